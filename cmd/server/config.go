@@ -45,6 +45,47 @@ type config struct {
 		NLoginAttempts           int           `yaml:"allowedLoginAttempts"`
 		PasswordResetTimeMinutes time.Duration `yaml:"passwordResetTimeout"`
 	} `yaml:"userService"`
+	LiveSession struct {
+		LockGracePeriod time.Duration `yaml:"lockGracePeriod"`
+		LockIdleTimeout time.Duration `yaml:"lockIdleTimeout"`
+	} `yaml:"liveSession"`
+	Delivery struct {
+		ReplayBufferSize int           `yaml:"replayBufferSize"`
+		ResumeWindow     time.Duration `yaml:"resumeWindow"`
+	} `yaml:"delivery"`
+	Retention struct {
+		DefaultRetentionDays int           `yaml:"defaultRetentionDays"`
+		CheckInterval        time.Duration `yaml:"checkInterval"`
+	} `yaml:"retention"`
+	Export struct {
+		WorkFolder           string        `yaml:"workFolder"`
+		ExpiresAfter         time.Duration `yaml:"expiresAfter"`
+		MaxConcurrentExports int           `yaml:"maxConcurrentExports"`
+	} `yaml:"export"`
+	Import struct {
+		Admins          []string `yaml:"admins"`
+		DefaultLanguage string   `yaml:"defaultLanguage"`
+	} `yaml:"import"`
+	Execution struct {
+		MaxConcurrentExecutions int           `yaml:"maxConcurrentExecutions"`
+		WorkFolder              string        `yaml:"workFolder"`
+		Timeout                 time.Duration `yaml:"timeout"`
+		CPUSeconds              uint64        `yaml:"cpuSeconds"`
+		MemoryBytes             uint64        `yaml:"memoryBytes"`
+		MaxFileBytes            uint64        `yaml:"maxFileBytes"`
+		MaxProcesses            uint64        `yaml:"maxProcesses"`
+		MaxOutputBytes          int           `yaml:"maxOutputBytes"`
+		WorkDirBytes            uint64        `yaml:"workDirBytes"`
+		SandboxUID              int           `yaml:"sandboxUid"`
+		SandboxGID              int           `yaml:"sandboxGid"`
+		ReadOnlyPaths           []string      `yaml:"readOnlyPaths"`
+		Runners                 []struct {
+			Language string   `yaml:"language"`
+			FileName string   `yaml:"fileName"`
+			Compile  []string `yaml:"compile"`
+			Run      []string `yaml:"run"`
+		} `yaml:"runners"`
+	} `yaml:"execution"`
 }
 
 func readConfigFile(configPath string, cfg *config) error {
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+
+	core "github.com/miphilipp/devchat-server/internal"
+	"github.com/miphilipp/devchat-server/internal/importing"
+	"github.com/miphilipp/devchat-server/internal/messaging"
+)
+
+// runImport implements the import subcommand, which imports an export on behalf of
+// a user and prints the result. Unlike the REST API it is not limited to the admins
+// of the config file. It returns the exit code.
+func runImport(
+	args []string,
+	cfg importing.Config,
+	userRepo core.UserRepo,
+	conversationRepo core.ConversationRepo,
+	messageRepo core.MessageRepo,
+	messagingService messaging.Service,
+	logger log.Logger) int {
+
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "", "The format of the export, either slack or mattermost.")
+	userName := flags.String("user", "", "The user that becomes the admin of the imported conversations.")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 || *userName == "" {
+		level.Error(logger).Log("System", "Import", "err", "usage: import -format slack|mattermost -user <name> <file>")
+		return 2
+	}
+
+	user, err := userRepo.GetUserForName(*userName)
+	if err != nil {
+		level.Error(logger).Log("System", "Import", "user", *userName, "err", err)
+		return 1
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		level.Error(logger).Log("System", "Import", "err", err)
+		return 1
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		level.Error(logger).Log("System", "Import", "err", err)
+		return 1
+	}
+
+	cfg.Admins = []string{user.Name}
+	var importService importing.Service
+	importService = importing.NewService(userRepo, conversationRepo, messageRepo, messagingService, cfg)
+	importService = importing.NewLoggingService(logger, importService, true)
+
+	result, err := importService.Import(user.ID, importing.Format(*format), file, info.Size())
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(result)
+	if err != nil {
+		return 1
+	}
+	return 0
+}
@@ -18,12 +18,18 @@ import (
 	"github.com/miphilipp/devchat-server/internal/communication/websocket"
 	"github.com/miphilipp/devchat-server/internal/conversations"
 	"github.com/miphilipp/devchat-server/internal/database"
+	"github.com/miphilipp/devchat-server/internal/execution"
+	"github.com/miphilipp/devchat-server/internal/export"
+	"github.com/miphilipp/devchat-server/internal/importing"
 	"github.com/miphilipp/devchat-server/internal/mailing"
 	"github.com/miphilipp/devchat-server/internal/messaging"
+	"github.com/miphilipp/devchat-server/internal/scheduling"
 	"github.com/miphilipp/devchat-server/internal/user"
 )
 
 func main() {
+	execution.SandboxInit()
+
 	var verbose bool
 	var configPath string
 	var showVersion bool
@@ -81,6 +87,7 @@ func main() {
 	messageRepo := database.NewMessageRepository(db)
 	conversationRepo := database.NewConversationRepository(db)
 	userRepo := database.NewUserRepository(db)
+	schedulingRepo := database.NewSchedulingRepository(db)
 
 	var mailingService = mailing.NewService(
 		cfg.Mailing.Server,
@@ -107,6 +114,65 @@ func main() {
 	messagingService = messaging.NewService(messageRepo, conversationRepo)
 	messagingService = messaging.NewLoggingService(logger, messagingService, verbose)
 
+	importConfig := importing.Config{
+		MediaFolder:     cfg.Server.MediaFolder,
+		Admins:          cfg.Import.Admins,
+		DefaultLanguage: cfg.Import.DefaultLanguage,
+	}
+	if flag.Arg(0) == "import" {
+		os.Exit(runImport(flag.Args()[1:], importConfig, userRepo, conversationRepo, messageRepo, messagingService, logger))
+	}
+
+	runners := make([]execution.Runner, len(cfg.Execution.Runners))
+	for i, r := range cfg.Execution.Runners {
+		runners[i] = execution.Runner{
+			Language: r.Language,
+			FileName: r.FileName,
+			Compile:  r.Compile,
+			Run:      r.Run,
+		}
+	}
+
+	var executionService execution.Service
+	executionService = execution.NewService(messageRepo, conversationRepo, execution.Config{
+		Runners:                 runners,
+		MaxConcurrentExecutions: cfg.Execution.MaxConcurrentExecutions,
+		WorkFolder:              cfg.Execution.WorkFolder,
+		Sandbox: execution.Sandbox{
+			UID:           cfg.Execution.SandboxUID,
+			GID:           cfg.Execution.SandboxGID,
+			ReadOnlyPaths: cfg.Execution.ReadOnlyPaths,
+		},
+		Limits: execution.Limits{
+			Timeout:        cfg.Execution.Timeout,
+			CPUSeconds:     cfg.Execution.CPUSeconds,
+			MemoryBytes:    cfg.Execution.MemoryBytes,
+			MaxFileBytes:   cfg.Execution.MaxFileBytes,
+			MaxProcesses:   cfg.Execution.MaxProcesses,
+			MaxOutputBytes: cfg.Execution.MaxOutputBytes,
+			WorkDirBytes:   cfg.Execution.WorkDirBytes,
+		},
+	})
+	executionService = execution.NewLoggingService(logger, executionService, verbose)
+
+	var schedulingService scheduling.Service
+	schedulingService = scheduling.NewService(schedulingRepo, messageRepo, conversationRepo, messagingService)
+	schedulingService = scheduling.NewLoggingService(logger, schedulingService, verbose)
+
+	var exportService export.Service
+	exportService = export.NewService(messageRepo, conversationRepo, export.Config{
+		MediaFolder:          cfg.Server.MediaFolder,
+		WorkFolder:           cfg.Export.WorkFolder,
+		DownloadPath:         "/media/export",
+		ExpiresAfter:         cfg.Export.ExpiresAfter,
+		MaxConcurrentExports: cfg.Export.MaxConcurrentExports,
+	})
+	exportService = export.NewLoggingService(logger, exportService, verbose)
+
+	var importService importing.Service
+	importService = importing.NewService(userRepo, conversationRepo, messageRepo, messagingService, importConfig)
+	importService = importing.NewLoggingService(logger, importService, verbose)
+
 	sessionPersistance, err := session.NewInMemorySessionPersistance(
 		cfg.InMemoryDB.Addr,
 		cfg.InMemoryDB.Password,
@@ -129,7 +195,18 @@ func main() {
 		messagingService,
 		conversationService,
 		userService,
+		executionService,
+		schedulingService,
+		websocket.Config{
+			LockGracePeriod:        cfg.LiveSession.LockGracePeriod,
+			LockIdleTimeout:        cfg.LiveSession.LockIdleTimeout,
+			DefaultRetentionDays:   cfg.Retention.DefaultRetentionDays,
+			RetentionCheckInterval: cfg.Retention.CheckInterval,
+			MediaFolder:            cfg.Server.MediaFolder,
+			ResumeWindow:           cfg.Delivery.ResumeWindow,
+		},
 		limiterStore,
+		websocket.NewRedisReplayBuffer(sessionPersistance.RedisClient, cfg.Delivery.ReplayBufferSize),
 		log.WithPrefix(logger, "Interface", "websocket"))
 	if socket == nil {
 		os.Exit(1)
@@ -149,6 +226,10 @@ func main() {
 		userService,
 		conversationService,
 		messagingService,
+		executionService,
+		schedulingService,
+		exportService,
+		importService,
 		socket,
 		session,
 		limiterStore,
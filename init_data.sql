@@ -29,4 +29,5 @@ insert into programming_language (name, runnable) values ('PL/SQL', false);
 insert into programming_language (name, runnable) values ('GraphQL', false);
 insert into programming_language (name, runnable) values ('Groovy', false);
 insert into programming_language (name, runnable) values ('Kotlin', false);
-insert into programming_language (name, runnable) values ('WebAssembly', false);
\ No newline at end of file
+insert into programming_language (name, runnable) values ('WebAssembly', false);
+insert into programming_language (name, runnable) values ('Plain Text', false);
\ No newline at end of file
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/gorilla/mux"
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+func (s *Webserver) getBookmarks(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+
+	beforeID := core.MaxInt
+	beforeIDStr := request.FormValue("before")
+	if beforeIDStr != "" {
+		b, err := strconv.Atoi(beforeIDStr)
+		if err != nil {
+			level.Error(s.logger).Log("Handler", "getBookmarks", "err", err)
+			return core.NewPathFormatError("Could not parse before")
+		}
+		beforeID = b
+	}
+
+	limit := 20
+	limitStr := request.FormValue("limit")
+	if limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil {
+			level.Error(s.logger).Log("Handler", "getBookmarks", "err", err)
+			return core.NewPathFormatError("Could not parse limit")
+		}
+		limit = l
+	}
+
+	bookmarks, err := s.messageService.ListBookmarks(userID, beforeID, limit, request.FormValue("tag"))
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(bookmarks)
+	return nil
+}
+
+func (s *Webserver) putBookmark(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "putBookmark", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	messageID, err := strconv.Atoi(vars["messageID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "putBookmark", "err", err)
+		return core.NewPathFormatError("Could not pares path component messageID")
+	}
+
+	var bookmark core.Bookmark
+	err = json.NewDecoder(request.Body).Decode(&bookmark)
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "putBookmark", "err", err)
+		return core.NewJSONFormatError(err.Error())
+	}
+	bookmark.ConversationID = conversationID
+	bookmark.MessageID = messageID
+
+	bookmark, err = s.messageService.SaveBookmark(userID, bookmark)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(bookmark)
+	return nil
+}
+
+func (s *Webserver) deleteBookmark(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "deleteBookmark", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	messageID, err := strconv.Atoi(vars["messageID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "deleteBookmark", "err", err)
+		return core.NewPathFormatError("Could not pares path component messageID")
+	}
+
+	err = s.messageService.DeleteBookmark(userID, conversationID, messageID)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusOK)
+	return nil
+}
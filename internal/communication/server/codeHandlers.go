@@ -0,0 +1,74 @@
+package server
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/gorilla/mux"
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+func (s *Webserver) getCodeFile(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getCodeFile", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	messageID, err := strconv.Atoi(vars["messageID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getCodeFile", "err", err)
+		return core.NewPathFormatError("Could not pares path component messageID")
+	}
+
+	index, err := strconv.Atoi(vars["file"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getCodeFile", "err", err)
+		return core.NewPathFormatError("Could not pares path component file")
+	}
+
+	file, err := s.messageService.GetCodeFile(userID, conversationID, messageID, index)
+	if err != nil {
+		return err
+	}
+
+	// File names may contain characters that cannot be used in a quoted string.
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": file.Name})
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	writer.Header().Set("Content-Disposition", disposition)
+	writer.WriteHeader(http.StatusOK)
+	io.WriteString(writer, file.Code)
+	return nil
+}
+
+func (s *Webserver) getCodeArchive(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getCodeArchive", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	messageID, err := strconv.Atoi(vars["messageID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getCodeArchive", "err", err)
+		return core.NewPathFormatError("Could not pares path component messageID")
+	}
+
+	archive, fileName, err := s.messageService.GetCodeArchive(userID, conversationID, messageID)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/zip")
+	writer.Header().Set("Content-Disposition", "attachment; filename=\""+fileName+"\"")
+	writer.WriteHeader(http.StatusOK)
+	writer.Write(archive)
+	return nil
+}
@@ -142,6 +142,46 @@ func (s *Webserver) patchConversation(writer http.ResponseWriter, request *http.
 	return nil
 }
 
+func (s *Webserver) putRetentionPeriod(writer http.ResponseWriter, request *http.Request) error {
+	userContext := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "putRetentionPeriod", "err", err)
+		return core.NewPathFormatError("Could not parse path component conversationID")
+	}
+
+	body := struct {
+		Days *int `json:"days"`
+	}{}
+
+	err = json.NewDecoder(request.Body).Decode(&body)
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "putRetentionPeriod", "err", err)
+		return core.NewJSONFormatError(err.Error())
+	}
+
+	err = s.conversationService.SetRetentionPeriod(userContext, conversationID, body.Days)
+	if err != nil {
+		return err
+	}
+
+	reply := struct {
+		RetentionDays *int `json:"retentionDays"`
+	}{body.Days}
+
+	ctx := websocket.NewRequestContext(websocket.RESTCommand{
+		Ressource: "conversation/retention",
+		Method:    websocket.PatchCommandMethod,
+	}, -1, conversationID)
+	s.socket.BroadcastToRoom(conversationID, reply, ctx)
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(reply)
+	return nil
+}
+
 func (s *Webserver) getConversation(writer http.ResponseWriter, request *http.Request) error {
 	userID := request.Context().Value("UserID").(int)
 	conversations, err := s.conversationService.ListConversationsForUser(userID)
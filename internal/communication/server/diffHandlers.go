@@ -0,0 +1,65 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/gorilla/mux"
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+func (s *Webserver) getDiffOfMessage(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getDiffOfMessage", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	messageID, err := strconv.Atoi(vars["messageID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getDiffOfMessage", "err", err)
+		return core.NewPathFormatError("Could not pares path component messageID")
+	}
+
+	files, err := s.messageService.GetDiffOfMessage(userID, conversationID, messageID)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(files)
+	return nil
+}
+
+func (s *Webserver) getPatchOfMessage(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getPatchOfMessage", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	messageID, err := strconv.Atoi(vars["messageID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getPatchOfMessage", "err", err)
+		return core.NewPathFormatError("Could not pares path component messageID")
+	}
+
+	patch, fileName, err := s.messageService.GetPatchOfMessage(userID, conversationID, messageID)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "text/x-patch; charset=utf-8")
+	writer.Header().Set("Content-Disposition", "attachment; filename=\""+fileName+"\"")
+	writer.WriteHeader(http.StatusOK)
+	io.WriteString(writer, patch)
+	return nil
+}
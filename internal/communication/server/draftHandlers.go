@@ -0,0 +1,100 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/gorilla/mux"
+	core "github.com/miphilipp/devchat-server/internal"
+	"github.com/miphilipp/devchat-server/internal/communication/websocket"
+)
+
+func (s *Webserver) getDrafts(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+
+	drafts, err := s.messageService.ListDrafts(userID)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(drafts)
+	return nil
+}
+
+func (s *Webserver) getDraft(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getDraft", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	draft, err := s.messageService.GetDraft(userID, conversationID)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(draft)
+	return nil
+}
+
+func (s *Webserver) putDraft(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "putDraft", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	var draft core.Draft
+	err = json.NewDecoder(request.Body).Decode(&draft)
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "putDraft", "err", err)
+		return core.NewJSONFormatError(err.Error())
+	}
+	draft.ConversationID = conversationID
+
+	ctx := websocket.NewRequestContext(websocket.RESTCommand{
+		Ressource: "draft",
+		Method:    websocket.PatchCommandMethod,
+	}, 0, conversationID)
+	draft, err = s.messageService.SaveDraft(userID, draft, s.socket, ctx)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(draft)
+	return nil
+}
+
+func (s *Webserver) deleteDraft(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "deleteDraft", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	ctx := websocket.NewRequestContext(websocket.RESTCommand{
+		Ressource: "draft",
+		Method:    websocket.DeleteCommandMethod,
+	}, 0, conversationID)
+	err = s.messageService.ClearDraft(userID, conversationID, s.socket, ctx)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusOK)
+	return nil
+}
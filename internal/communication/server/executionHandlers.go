@@ -0,0 +1,37 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/gorilla/mux"
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+func (s *Webserver) getExecutionResult(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getExecutionResult", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	messageID, err := strconv.Atoi(vars["messageID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getExecutionResult", "err", err)
+		return core.NewPathFormatError("Could not pares path component messageID")
+	}
+
+	result, err := s.executionService.GetExecutionResult(userID, conversationID, messageID)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(result)
+	return nil
+}
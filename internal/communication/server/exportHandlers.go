@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/gorilla/mux"
+	core "github.com/miphilipp/devchat-server/internal"
+	"github.com/miphilipp/devchat-server/internal/communication/websocket"
+)
+
+func (s *Webserver) postExport(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "postExport", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	ctx := websocket.NewRequestContext(websocket.RESTCommand{
+		Ressource: "export",
+		Method:    websocket.PatchCommandMethod,
+	}, 0, conversationID)
+	job, err := s.exportService.StartExport(userID, conversationID, s.socket, ctx)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(job)
+	return nil
+}
+
+func (s *Webserver) getExports(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+
+	jobs, err := s.exportService.ListExportJobs(userID)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(jobs)
+	return nil
+}
+
+func (s *Webserver) serveExport(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	jobID, err := strconv.Atoi(vars["jobID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "serveExport", "err", err)
+		return core.NewPathFormatError("Could not parse path component jobID")
+	}
+
+	job, file, err := s.exportService.OpenExport(userID, jobID)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer.Header().Set("Content-Type", "application/zip")
+	writer.Header().Set("Content-Disposition", "attachment; filename=\""+path.Base(job.DownloadURL)+"\"")
+	http.ServeContent(writer, request, "", *job.CompletionDate, file)
+	return nil
+}
@@ -31,6 +31,10 @@ var apiErrorToStatusCodeMap = map[int]int{
 	1021: http.StatusBadRequest,
 	1022: http.StatusUnauthorized,
 	1023: http.StatusBadRequest,
+	1025: http.StatusBadRequest,
+	1026: http.StatusConflict,
+	1027: http.StatusConflict,
+	1028: http.StatusConflict,
 }
 
 // SetupRestHandlers registers all the  REST routes
@@ -93,6 +97,14 @@ func (s *Webserver) SetupRestHandlers() {
 			}
 		}).Methods(http.MethodGet)
 
+	media.HandleFunc("/export/{jobID:[0-9]+}/{fileName}",
+		func(writer http.ResponseWriter, request *http.Request) {
+			err := s.serveExport(writer, request)
+			if err != nil {
+				sendAPIError(err, writer)
+			}
+		}).Methods(http.MethodGet)
+
 	api.HandleFunc("/conversation", func(writer http.ResponseWriter, request *http.Request) {
 		err := s.getConversation(writer, request)
 		if err != nil {
@@ -121,6 +133,34 @@ func (s *Webserver) SetupRestHandlers() {
 		}
 	}).Methods(http.MethodPatch)
 
+	api.HandleFunc("/conversation/{id:[0-9]+}/retention", func(writer http.ResponseWriter, request *http.Request) {
+		err := s.putRetentionPeriod(writer, request)
+		if err != nil {
+			sendAPIError(err, writer)
+		}
+	}).Methods(http.MethodPut)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/export", func(writer http.ResponseWriter, request *http.Request) {
+		err := s.postExport(writer, request)
+		if err != nil {
+			sendAPIError(err, writer)
+		}
+	}).Methods(http.MethodPost)
+
+	api.HandleFunc("/exports", func(writer http.ResponseWriter, request *http.Request) {
+		err := s.getExports(writer, request)
+		if err != nil {
+			sendAPIError(err, writer)
+		}
+	}).Methods(http.MethodGet)
+
+	api.HandleFunc("/import", func(writer http.ResponseWriter, request *http.Request) {
+		err := s.postImport(writer, request)
+		if err != nil {
+			sendAPIError(err, writer)
+		}
+	}).Methods(http.MethodPost)
+
 	api.HandleFunc("/conversation/{id:[0-9]+}/users", func(writer http.ResponseWriter, request *http.Request) {
 		err := s.getMembersOfConversation(writer, request)
 		if err != nil {
@@ -237,6 +277,195 @@ func (s *Webserver) SetupRestHandlers() {
 			}
 		}).Methods(http.MethodGet)
 
+	api.HandleFunc("/conversation/{id:[0-9]+}/messages/{messageID:[0-9]+}/code/files/{file:[0-9]+}",
+		func(writer http.ResponseWriter, request *http.Request) {
+			err := s.getCodeFile(writer, request)
+			if err != nil {
+				sendAPIError(err, writer)
+			}
+		}).Methods(http.MethodGet)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/messages/{messageID:[0-9]+}/code/archive",
+		func(writer http.ResponseWriter, request *http.Request) {
+			err := s.getCodeArchive(writer, request)
+			if err != nil {
+				sendAPIError(err, writer)
+			}
+		}).Methods(http.MethodGet)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/messages/{messageID:[0-9]+}/diff",
+		func(writer http.ResponseWriter, request *http.Request) {
+			err := s.getDiffOfMessage(writer, request)
+			if err != nil {
+				sendAPIError(err, writer)
+			}
+		}).Methods(http.MethodGet)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/messages/{messageID:[0-9]+}/patch",
+		func(writer http.ResponseWriter, request *http.Request) {
+			err := s.getPatchOfMessage(writer, request)
+			if err != nil {
+				sendAPIError(err, writer)
+			}
+		}).Methods(http.MethodGet)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/messages/{messageID:[0-9]+}/reminder",
+		func(writer http.ResponseWriter, request *http.Request) {
+			err := s.postReminder(writer, request)
+			if err != nil {
+				sendAPIError(err, writer)
+			}
+		}).Methods(http.MethodPost)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/scheduled", func(writer http.ResponseWriter, request *http.Request) {
+		err := s.postScheduledMessage(writer, request)
+		if err != nil {
+			sendAPIError(err, writer)
+		}
+	}).Methods(http.MethodPost)
+
+	api.HandleFunc("/drafts", func(writer http.ResponseWriter, request *http.Request) {
+		err := s.getDrafts(writer, request)
+		if err != nil {
+			sendAPIError(err, writer)
+		}
+	}).Methods(http.MethodGet)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/draft", func(writer http.ResponseWriter, request *http.Request) {
+		err := s.getDraft(writer, request)
+		if err != nil {
+			sendAPIError(err, writer)
+		}
+	}).Methods(http.MethodGet)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/draft", func(writer http.ResponseWriter, request *http.Request) {
+		err := s.putDraft(writer, request)
+		if err != nil {
+			sendAPIError(err, writer)
+		}
+	}).Methods(http.MethodPut)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/draft", func(writer http.ResponseWriter, request *http.Request) {
+		err := s.deleteDraft(writer, request)
+		if err != nil {
+			sendAPIError(err, writer)
+		}
+	}).Methods(http.MethodDelete)
+
+	api.HandleFunc("/bookmarks", func(writer http.ResponseWriter, request *http.Request) {
+		err := s.getBookmarks(writer, request)
+		if err != nil {
+			sendAPIError(err, writer)
+		}
+	}).Methods(http.MethodGet)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/messages/{messageID:[0-9]+}/bookmark", func(writer http.ResponseWriter, request *http.Request) {
+		err := s.putBookmark(writer, request)
+		if err != nil {
+			sendAPIError(err, writer)
+		}
+	}).Methods(http.MethodPut)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/messages/{messageID:[0-9]+}/bookmark", func(writer http.ResponseWriter, request *http.Request) {
+		err := s.deleteBookmark(writer, request)
+		if err != nil {
+			sendAPIError(err, writer)
+		}
+	}).Methods(http.MethodDelete)
+
+	api.HandleFunc("/scheduled", func(writer http.ResponseWriter, request *http.Request) {
+		err := s.getScheduledItems(writer, request)
+		if err != nil {
+			sendAPIError(err, writer)
+		}
+	}).Methods(http.MethodGet)
+
+	api.HandleFunc("/scheduled/{itemID:[0-9]+}", func(writer http.ResponseWriter, request *http.Request) {
+		err := s.patchScheduledItem(writer, request)
+		if err != nil {
+			sendAPIError(err, writer)
+		}
+	}).Methods(http.MethodPatch)
+
+	api.HandleFunc("/scheduled/{itemID:[0-9]+}", func(writer http.ResponseWriter, request *http.Request) {
+		err := s.deleteScheduledItem(writer, request)
+		if err != nil {
+			sendAPIError(err, writer)
+		}
+	}).Methods(http.MethodDelete)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/messages/{messageID:[0-9]+}/thread",
+		func(writer http.ResponseWriter, request *http.Request) {
+			err := s.getThread(writer, request)
+			if err != nil {
+				sendAPIError(err, writer)
+			}
+		}).Methods(http.MethodGet)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/messages/{messageID:[0-9]+}/history",
+		func(writer http.ResponseWriter, request *http.Request) {
+			err := s.getEditHistory(writer, request)
+			if err != nil {
+				sendAPIError(err, writer)
+			}
+		}).Methods(http.MethodGet)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/messages/{messageID:[0-9]+}/forward",
+		func(writer http.ResponseWriter, request *http.Request) {
+			err := s.forwardMessage(writer, request)
+			if err != nil {
+				sendAPIError(err, writer)
+			}
+		}).Methods(http.MethodPost)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/messages/{messageID:[0-9]+}/readers",
+		func(writer http.ResponseWriter, request *http.Request) {
+			err := s.getReaders(writer, request)
+			if err != nil {
+				sendAPIError(err, writer)
+			}
+		}).Methods(http.MethodGet)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/messages/{messageID:[0-9]+}/lock",
+		func(writer http.ResponseWriter, request *http.Request) {
+			err := s.forceReleaseLock(writer, request)
+			if err != nil {
+				sendAPIError(err, writer)
+			}
+		}).Methods(http.MethodDelete)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/messages/{messageID:[0-9]+}/execution",
+		func(writer http.ResponseWriter, request *http.Request) {
+			err := s.getExecutionResult(writer, request)
+			if err != nil {
+				sendAPIError(err, writer)
+			}
+		}).Methods(http.MethodGet)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/messages/{messageID:[0-9]+}/revisions",
+		func(writer http.ResponseWriter, request *http.Request) {
+			err := s.getCodeRevisions(writer, request)
+			if err != nil {
+				sendAPIError(err, writer)
+			}
+		}).Methods(http.MethodGet)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/messages/{messageID:[0-9]+}/revisions/diff",
+		func(writer http.ResponseWriter, request *http.Request) {
+			err := s.getCodeRevisionDiff(writer, request)
+			if err != nil {
+				sendAPIError(err, writer)
+			}
+		}).Queries("from", "{from:[0-9]+}", "to", "{to:[0-9]+}").Methods(http.MethodGet)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/messages/{messageID:[0-9]+}/revisions/{revision:[0-9]+}/restore",
+		func(writer http.ResponseWriter, request *http.Request) {
+			err := s.restoreCodeRevision(writer, request)
+			if err != nil {
+				sendAPIError(err, writer)
+			}
+		}).Methods(http.MethodPost)
+
 	api.HandleFunc("/conversation/{id:[0-9]+}/messages/{messageID:[0-9]+}",
 		func(writer http.ResponseWriter, request *http.Request) {
 			err := s.getMessage(writer, request)
@@ -245,6 +474,51 @@ func (s *Webserver) SetupRestHandlers() {
 			}
 		}).Methods(http.MethodGet)
 
+	api.HandleFunc("/conversation/{id:[0-9]+}/messages/{messageID:[0-9]+}",
+		func(writer http.ResponseWriter, request *http.Request) {
+			err := s.deleteMessage(writer, request)
+			if err != nil {
+				sendAPIError(err, writer)
+			}
+		}).Methods(http.MethodDelete)
+
+	api.HandleFunc("/mentions", func(writer http.ResponseWriter, request *http.Request) {
+		err := s.getMentions(writer, request)
+		if err != nil {
+			sendAPIError(err, writer)
+		}
+	}).Methods(http.MethodGet)
+
+	api.HandleFunc("/search", func(writer http.ResponseWriter, request *http.Request) {
+		err := s.searchMessages(writer, request)
+		if err != nil {
+			sendAPIError(err, writer)
+		}
+	}).Methods(http.MethodGet)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/pins", func(writer http.ResponseWriter, request *http.Request) {
+		err := s.getPins(writer, request)
+		if err != nil {
+			sendAPIError(err, writer)
+		}
+	}).Methods(http.MethodGet)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/pins/{messageID:[0-9]+}",
+		func(writer http.ResponseWriter, request *http.Request) {
+			err := s.togglePin(writer, request, true)
+			if err != nil {
+				sendAPIError(err, writer)
+			}
+		}).Methods(http.MethodPut)
+
+	api.HandleFunc("/conversation/{id:[0-9]+}/pins/{messageID:[0-9]+}",
+		func(writer http.ResponseWriter, request *http.Request) {
+			err := s.togglePin(writer, request, false)
+			if err != nil {
+				sendAPIError(err, writer)
+			}
+		}).Methods(http.MethodDelete)
+
 	api.HandleFunc("/programmingLanguages", func(writer http.ResponseWriter, request *http.Request) {
 		err := s.getProgrammingLanguages(writer, request)
 		if err != nil {
@@ -252,6 +526,13 @@ func (s *Webserver) SetupRestHandlers() {
 		}
 	}).Methods(http.MethodGet)
 
+	api.HandleFunc("/programmingLanguages/detect", func(writer http.ResponseWriter, request *http.Request) {
+		err := s.detectProgrammingLanguage(writer, request)
+		if err != nil {
+			sendAPIError(err, writer)
+		}
+	}).Methods(http.MethodPost)
+
 	api.HandleFunc("/websocket", func(writer http.ResponseWriter, request *http.Request) {
 		userContext := request.Context().Value("UserID").(int)
 		err := s.socket.StartWebsocket(writer, request, userContext)
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/go-kit/kit/log/level"
+	core "github.com/miphilipp/devchat-server/internal"
+	"github.com/miphilipp/devchat-server/internal/communication/websocket"
+	"github.com/miphilipp/devchat-server/internal/importing"
+)
+
+func (s *Webserver) postImport(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	format := importing.Format(request.URL.Query().Get("format"))
+	if format != importing.SlackFormat && format != importing.MattermostFormat {
+		return core.NewInvalidValueError("format")
+	}
+
+	// Checked before anything is written to disk.
+	err := s.importService.AuthorizeImport(userID)
+	if err != nil {
+		return err
+	}
+	request.Body = http.MaxBytesReader(writer, request.Body, importing.MaxExportSize)
+
+	// The archive is read randomly, so it has to be buffered on disk.
+	file, err := ioutil.TempFile("", "devchat-import-*")
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "postImport", "err", err)
+		return core.ErrUnknownError
+	}
+
+	_, err = io.Copy(file, request.Body)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		level.Error(s.logger).Log("Handler", "postImport", "err", err)
+		return core.NewInvalidValueError("export")
+	}
+
+	ctx := websocket.NewRequestContext(websocket.RESTCommand{
+		Ressource: "import",
+		Method:    websocket.PostCommandMethod,
+	}, 0, -1)
+	err = s.importService.StartImport(userID, format, file, s.socket, ctx)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusOK)
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/gorilla/mux"
 	core "github.com/miphilipp/devchat-server/internal"
 	"github.com/miphilipp/devchat-server/internal/communication/websocket"
+	"github.com/miphilipp/devchat-server/internal/messaging"
 )
 
 func (s *Webserver) getMessages(writer http.ResponseWriter, request *http.Request) error {
@@ -23,19 +24,52 @@ func (s *Webserver) getMessages(writer http.ResponseWriter, request *http.Reques
 		return core.NewPathFormatError("Could not pares path component conversationID")
 	}
 
-	beforeInSequence := core.MaxInt
-	beforeInSequenceStr := request.FormValue("before")
-	if beforeInSequenceStr != "" {
-		o, err := strconv.Atoi(beforeInSequenceStr)
+	query := core.HistoryQuery{
+		Direction: core.HistoryBefore,
+		AnchorID:  core.MaxInt,
+		Cursor:    request.FormValue("cursor"),
+	}
+
+	// Only requests using one of the anchors introduced with pagination receive a
+	// HistoryPage. All others get the plain list of messages as before.
+	isPaged := false
+	nAnchors := 0
+	if query.Cursor != "" {
+		isPaged = true
+		nAnchors++
+	}
+
+	anchors := []struct {
+		name      string
+		direction core.HistoryDirection
+	}{
+		{"before", core.HistoryBefore},
+		{"after", core.HistoryAfter},
+		{"around", core.HistoryAround},
+	}
+	for _, anchor := range anchors {
+		anchorStr := request.FormValue(anchor.name)
+		if anchorStr == "" {
+			continue
+		}
+
+		id, err := strconv.Atoi(anchorStr)
 		if err != nil {
 			level.Error(s.logger).Log("Handler", "getMessages", "err", err)
-			return core.NewPathFormatError("Could not parse before")
+			return core.NewPathFormatError("Could not parse " + anchor.name)
 		}
 
-		beforeInSequence = o
+		query.Direction = anchor.direction
+		query.AnchorID = id
+		isPaged = isPaged || anchor.direction != core.HistoryBefore
+		nAnchors++
+	}
+
+	if nAnchors > 1 {
+		return core.NewInvalidValueError("anchor")
 	}
 
-	messageType := core.UndefinedMesssageType
+	query.Type = core.UndefinedMesssageType
 	messageTypeStr := request.FormValue("type")
 	if messageTypeStr != "" {
 		t, err := strconv.Atoi(messageTypeStr)
@@ -43,10 +77,10 @@ func (s *Webserver) getMessages(writer http.ResponseWriter, request *http.Reques
 			level.Error(s.logger).Log("Handler", "getMessages", "err", err)
 			return core.NewPathFormatError("Could not parse type")
 		}
-		messageType = core.MessageType(t)
+		query.Type = core.MessageType(t)
 	}
 
-	limit := 20
+	query.Limit = 20
 	limitStr := request.FormValue("limit")
 	if limitStr != "" {
 		l, err := strconv.Atoi(limitStr)
@@ -54,20 +88,43 @@ func (s *Webserver) getMessages(writer http.ResponseWriter, request *http.Reques
 			level.Error(s.logger).Log("Handler", "getMessages", "err", err)
 			return core.NewPathFormatError("Could not parse limit")
 		}
-		limit = l
+		query.Limit = l
+	}
+
+	// The limit of unpaged requests used to be unrestricted.
+	if !isPaged && query.Limit > messaging.MaxHistoryLimit {
+		query.Limit = messaging.MaxHistoryLimit
 	}
 
-	messages, err := s.messageService.ListAllMessages(userID, conversationID, beforeInSequence, limit, messageType)
+	page, err := s.messageService.ListAllMessages(userID, conversationID, query)
 	if err != nil {
 		return err
 	}
 
 	writer.Header().Set("Content-Type", "application/json")
 	writer.WriteHeader(http.StatusOK)
-	json.NewEncoder(writer).Encode(messages)
+	if isPaged {
+		json.NewEncoder(writer).Encode(page)
+	} else {
+		json.NewEncoder(writer).Encode(legacyMessageList(page, query.Type))
+	}
 	return nil
 }
 
+// legacyMessageList returns the messages in the order of the unpaged history. Messages
+// filtered by type used to be listed newest first.
+func legacyMessageList(page core.HistoryPage, messageType core.MessageType) []interface{} {
+	if messageType == core.UndefinedMesssageType {
+		return page.Messages
+	}
+
+	messages := make([]interface{}, len(page.Messages))
+	for i, message := range page.Messages {
+		messages[len(messages)-1-i] = message
+	}
+	return messages
+}
+
 func (s *Webserver) getCodeOfMessage(writer http.ResponseWriter, request *http.Request) error {
 	userID := request.Context().Value("UserID").(int)
 	vars := mux.Vars(request)
@@ -110,6 +167,25 @@ func (s *Webserver) getProgrammingLanguages(writer http.ResponseWriter, request
 	return nil
 }
 
+func (s *Webserver) detectProgrammingLanguage(writer http.ResponseWriter, request *http.Request) error {
+	var code core.CodeFile
+	err := json.NewDecoder(request.Body).Decode(&code)
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "detectProgrammingLanguage", "err", err)
+		return core.NewJSONFormatError(err.Error())
+	}
+
+	guess, err := s.messageService.DetectLanguage(code.Name, code.Code)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(guess)
+	return nil
+}
+
 func (s *Webserver) getMessage(writer http.ResponseWriter, request *http.Request) error {
 	userID := request.Context().Value("UserID").(int)
 	vars := mux.Vars(request)
@@ -136,6 +212,277 @@ func (s *Webserver) getMessage(writer http.ResponseWriter, request *http.Request
 	return nil
 }
 
+func (s *Webserver) getThread(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getThread", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	messageID, err := strconv.Atoi(vars["messageID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getThread", "err", err)
+		return core.NewPathFormatError("Could not pares path component messageID")
+	}
+
+	thread, err := s.messageService.ListThread(userID, conversationID, messageID)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(thread)
+	return nil
+}
+
+func (s *Webserver) getMentions(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+
+	beforeInSequence := core.MaxInt
+	beforeInSequenceStr := request.FormValue("before")
+	if beforeInSequenceStr != "" {
+		o, err := strconv.Atoi(beforeInSequenceStr)
+		if err != nil {
+			level.Error(s.logger).Log("Handler", "getMentions", "err", err)
+			return core.NewPathFormatError("Could not parse before")
+		}
+
+		beforeInSequence = o
+	}
+
+	limit := 20
+	limitStr := request.FormValue("limit")
+	if limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil {
+			level.Error(s.logger).Log("Handler", "getMentions", "err", err)
+			return core.NewPathFormatError("Could not parse limit")
+		}
+		limit = l
+	}
+
+	mentions, err := s.messageService.ListMentions(userID, beforeInSequence, limit)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(mentions)
+	return nil
+}
+
+func (s *Webserver) getEditHistory(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getEditHistory", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	messageID, err := strconv.Atoi(vars["messageID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getEditHistory", "err", err)
+		return core.NewPathFormatError("Could not pares path component messageID")
+	}
+
+	edits, err := s.messageService.ListEditHistory(userID, conversationID, messageID)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(edits)
+	return nil
+}
+
+func (s *Webserver) forwardMessage(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "forwardMessage", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	messageID, err := strconv.Atoi(vars["messageID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "forwardMessage", "err", err)
+		return core.NewPathFormatError("Could not pares path component messageID")
+	}
+
+	requestBody := struct {
+		ConversationID int `json:"conversationId"`
+	}{}
+	err = json.NewDecoder(request.Body).Decode(&requestBody)
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "forwardMessage", "err", err)
+		return core.NewJSONFormatError(err.Error())
+	}
+
+	ctx := websocket.NewRequestContext(websocket.RESTCommand{
+		Ressource: "message",
+		Method:    websocket.PostCommandMethod,
+	}, -1, requestBody.ConversationID)
+	message, err := s.messageService.ForwardMessage(
+		userID, conversationID, messageID, requestBody.ConversationID, s.socket, ctx)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(message)
+	return nil
+}
+
+func (s *Webserver) getReaders(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getReaders", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	messageID, err := strconv.Atoi(vars["messageID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getReaders", "err", err)
+		return core.NewPathFormatError("Could not pares path component messageID")
+	}
+
+	readers, err := s.messageService.ListReaders(userID, conversationID, messageID)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(readers)
+	return nil
+}
+
+func (s *Webserver) deleteMessage(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "deleteMessage", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	messageID, err := strconv.Atoi(vars["messageID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "deleteMessage", "err", err)
+		return core.NewPathFormatError("Could not pares path component messageID")
+	}
+
+	ctx := websocket.NewRequestContext(websocket.RESTCommand{
+		Ressource: "message",
+		Method:    websocket.DeleteCommandMethod,
+	}, -1, conversationID)
+	tombstone, err := s.messageService.DeleteMessage(
+		userID,
+		conversationID,
+		messageID,
+		s.config.MediaFolder,
+		s.socket,
+		ctx,
+	)
+	if err != nil {
+		return err
+	}
+	s.socket.BroadcastThreadSummary(userID, conversationID, tombstone)
+
+	writer.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *Webserver) getPins(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getPins", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	pins, err := s.messageService.ListPins(userID, conversationID)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(pins)
+	return nil
+}
+
+func (s *Webserver) togglePin(writer http.ResponseWriter, request *http.Request, state bool) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "togglePin", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	messageID, err := strconv.Atoi(vars["messageID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "togglePin", "err", err)
+		return core.NewPathFormatError("Could not pares path component messageID")
+	}
+
+	method := websocket.PostCommandMethod
+	if !state {
+		method = websocket.DeleteCommandMethod
+	}
+	ctx := websocket.NewRequestContext(websocket.RESTCommand{
+		Ressource: "message/pin",
+		Method:    method,
+	}, -1, conversationID)
+	err = s.messageService.TogglePin(userID, conversationID, messageID, state, s.socket, ctx)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *Webserver) forceReleaseLock(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "forceReleaseLock", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	messageID, err := strconv.Atoi(vars["messageID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "forceReleaseLock", "err", err)
+		return core.NewPathFormatError("Could not pares path component messageID")
+	}
+
+	ctx := websocket.NewRequestContext(websocket.RESTCommand{
+		Ressource: "livesession/code",
+		Method:    websocket.DeleteCommandMethod,
+	}, -1, conversationID)
+	err = s.messageService.ForceReleaseLock(userID, conversationID, messageID, s.socket, ctx)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusOK)
+	return nil
+}
+
 func (s *Webserver) uploadMedia(writer http.ResponseWriter, request *http.Request) error {
 
 	userID := request.Context().Value("UserID").(int)
@@ -214,6 +561,7 @@ func (s *Webserver) uploadMedia(writer http.ResponseWriter, request *http.Reques
 		Method:    websocket.PostCommandMethod,
 	}, -1, conversationID)
 	s.socket.BroadcastToRoom(conversationID, mediaMessage, ctx)
+	s.socket.BroadcastThreadSummary(userID, conversationID, mediaMessage)
 	return nil
 }
 
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/gorilla/mux"
+	core "github.com/miphilipp/devchat-server/internal"
+	"github.com/miphilipp/devchat-server/internal/communication/websocket"
+)
+
+func (s *Webserver) getCodeRevisions(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getCodeRevisions", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	messageID, err := strconv.Atoi(vars["messageID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getCodeRevisions", "err", err)
+		return core.NewPathFormatError("Could not pares path component messageID")
+	}
+
+	revisions, err := s.messageService.ListCodeRevisions(userID, conversationID, messageID)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(revisions)
+	return nil
+}
+
+func (s *Webserver) getCodeRevisionDiff(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getCodeRevisionDiff", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	messageID, err := strconv.Atoi(vars["messageID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getCodeRevisionDiff", "err", err)
+		return core.NewPathFormatError("Could not pares path component messageID")
+	}
+
+	from, err := strconv.Atoi(request.FormValue("from"))
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getCodeRevisionDiff", "err", err)
+		return core.NewPathFormatError("Could not parse from")
+	}
+
+	to, err := strconv.Atoi(request.FormValue("to"))
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "getCodeRevisionDiff", "err", err)
+		return core.NewPathFormatError("Could not parse to")
+	}
+
+	diff, err := s.messageService.DiffCodeRevisions(userID, conversationID, messageID, from, to)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+	writer.WriteHeader(http.StatusOK)
+	io.WriteString(writer, diff)
+	return nil
+}
+
+func (s *Webserver) restoreCodeRevision(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "restoreCodeRevision", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	messageID, err := strconv.Atoi(vars["messageID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "restoreCodeRevision", "err", err)
+		return core.NewPathFormatError("Could not pares path component messageID")
+	}
+
+	revision, err := strconv.Atoi(vars["revision"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "restoreCodeRevision", "err", err)
+		return core.NewPathFormatError("Could not pares path component revision")
+	}
+
+	ctx := websocket.NewRequestContext(websocket.RESTCommand{
+		Ressource: "message",
+		Method:    websocket.PatchCommandMethod,
+	}, -1, conversationID)
+	newRevision, err := s.messageService.RestoreCodeRevision(userID, conversationID, messageID, revision, s.socket, ctx)
+	if err != nil {
+		return err
+	}
+
+	reply := struct {
+		Revision int `json:"revision"`
+	}{newRevision}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(reply)
+	return nil
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/gorilla/mux"
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+func (s *Webserver) getScheduledItems(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+
+	items, err := s.schedulingService.ListScheduledItems(userID)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(items)
+	return nil
+}
+
+func (s *Webserver) postScheduledMessage(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "postScheduledMessage", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	requestBody := struct {
+		DueDate time.Time       `json:"dueDate"`
+		Message json.RawMessage `json:"message"`
+	}{}
+	err = json.NewDecoder(request.Body).Decode(&requestBody)
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "postScheduledMessage", "err", err)
+		return core.NewJSONFormatError(err.Error())
+	}
+
+	if requestBody.Message == nil {
+		return core.NewJSONFormatError("message missing")
+	}
+
+	item, err := s.schedulingService.ScheduleMessage(userID, conversationID, requestBody.DueDate, requestBody.Message)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(item)
+	return nil
+}
+
+func (s *Webserver) postReminder(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "postReminder", "err", err)
+		return core.NewPathFormatError("Could not pares path component conversationID")
+	}
+
+	messageID, err := strconv.Atoi(vars["messageID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "postReminder", "err", err)
+		return core.NewPathFormatError("Could not pares path component messageID")
+	}
+
+	requestBody := struct {
+		DueDate time.Time `json:"dueDate"`
+		Note    string    `json:"note"`
+	}{}
+	err = json.NewDecoder(request.Body).Decode(&requestBody)
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "postReminder", "err", err)
+		return core.NewJSONFormatError(err.Error())
+	}
+
+	item, err := s.schedulingService.CreateReminder(userID, conversationID, messageID, requestBody.DueDate, requestBody.Note)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(item)
+	return nil
+}
+
+func (s *Webserver) patchScheduledItem(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	itemID, err := strconv.Atoi(vars["itemID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "patchScheduledItem", "err", err)
+		return core.NewPathFormatError("Could not pares path component itemID")
+	}
+
+	requestBody := struct {
+		DueDate *time.Time      `json:"dueDate"`
+		Message json.RawMessage `json:"message"`
+		Note    *string         `json:"note"`
+	}{}
+	err = json.NewDecoder(request.Body).Decode(&requestBody)
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "patchScheduledItem", "err", err)
+		return core.NewJSONFormatError(err.Error())
+	}
+
+	item, err := s.schedulingService.EditScheduledItem(
+		userID,
+		itemID,
+		requestBody.DueDate,
+		requestBody.Message,
+		requestBody.Note,
+	)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(item)
+	return nil
+}
+
+func (s *Webserver) deleteScheduledItem(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+	vars := mux.Vars(request)
+	itemID, err := strconv.Atoi(vars["itemID"])
+	if err != nil {
+		level.Error(s.logger).Log("Handler", "deleteScheduledItem", "err", err)
+		return core.NewPathFormatError("Could not pares path component itemID")
+	}
+
+	err = s.schedulingService.CancelScheduledItem(userID, itemID)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusOK)
+	return nil
+}
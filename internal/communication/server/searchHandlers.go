@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+func (s *Webserver) searchMessages(writer http.ResponseWriter, request *http.Request) error {
+	userID := request.Context().Value("UserID").(int)
+
+	query := core.SearchQuery{
+		Term:     request.FormValue("q"),
+		Author:   request.FormValue("author"),
+		Language: request.FormValue("language"),
+		Type:     core.UndefinedMesssageType,
+	}
+
+	intParameters := []struct {
+		name  string
+		value *int
+	}{
+		{"conversation", &query.ConversationID},
+		{"limit", &query.Limit},
+		{"offset", &query.Offset},
+	}
+	for _, p := range intParameters {
+		str := request.FormValue(p.name)
+		if str == "" {
+			continue
+		}
+
+		v, err := strconv.Atoi(str)
+		if err != nil {
+			level.Error(s.logger).Log("Handler", "searchMessages", "err", err)
+			return core.NewPathFormatError("Could not parse " + p.name)
+		}
+		*p.value = v
+	}
+
+	messageTypeStr := request.FormValue("type")
+	if messageTypeStr != "" {
+		t, err := strconv.Atoi(messageTypeStr)
+		if err != nil {
+			level.Error(s.logger).Log("Handler", "searchMessages", "err", err)
+			return core.NewPathFormatError("Could not parse type")
+		}
+		query.Type = core.MessageType(t)
+	}
+
+	dateParameters := []struct {
+		name  string
+		value *time.Time
+	}{
+		{"from", &query.From},
+		{"to", &query.To},
+	}
+	for _, p := range dateParameters {
+		str := request.FormValue(p.name)
+		if str == "" {
+			continue
+		}
+
+		d, err := time.Parse(time.RFC3339, str)
+		if err != nil {
+			level.Error(s.logger).Log("Handler", "searchMessages", "err", err)
+			return core.NewPathFormatError("Could not parse " + p.name)
+		}
+		*p.value = d.UTC()
+	}
+
+	results, err := s.messageService.Search(userID, query)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	json.NewEncoder(writer).Encode(results)
+	return nil
+}
@@ -15,7 +15,11 @@ import (
 	"github.com/miphilipp/devchat-server/internal/communication/session"
 	"github.com/miphilipp/devchat-server/internal/communication/websocket"
 	"github.com/miphilipp/devchat-server/internal/conversations"
+	"github.com/miphilipp/devchat-server/internal/execution"
+	"github.com/miphilipp/devchat-server/internal/export"
+	"github.com/miphilipp/devchat-server/internal/importing"
 	"github.com/miphilipp/devchat-server/internal/messaging"
+	"github.com/miphilipp/devchat-server/internal/scheduling"
 	"github.com/miphilipp/devchat-server/internal/user"
 	"github.com/throttled/throttled"
 	"golang.org/x/text/language"
@@ -50,6 +54,10 @@ type Webserver struct {
 	userService         user.Service
 	conversationService conversations.Service
 	messageService      messaging.Service
+	executionService    execution.Service
+	schedulingService   scheduling.Service
+	exportService       export.Service
+	importService       importing.Service
 }
 
 func (s *Webserver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -137,6 +145,10 @@ func New(
 	userService user.Service,
 	cService conversations.Service,
 	mService messaging.Service,
+	eService execution.Service,
+	sService scheduling.Service,
+	xService export.Service,
+	iService importing.Service,
 	socket *websocket.Server,
 	session *session.Manager,
 	limiterStore throttled.GCRAStore,
@@ -157,6 +169,10 @@ func New(
 		userService:         userService,
 		conversationService: cService,
 		messageService:      mService,
+		executionService:    eService,
+		schedulingService:   sService,
+		exportService:       xService,
+		importService:       iService,
 		logger:              logger,
 		socket:              socket,
 		session:             session,
@@ -4,14 +4,29 @@ import (
 	"context"
 	"math/rand"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+// connection is a websocket connection of one device of the user.
+type connection struct {
+	*websocket.Conn
+	device string
+}
+
 type client struct {
-	id         int
-	conns      []*websocket.Conn
-	connsLock  sync.Mutex
+	id    int
+	conns []*connection
+
+	// devices holds every device that frames are still sequenced for, mapped to the time
+	// it lost its last connection. Connected devices map to the zero time.
+	devices   map[string]time.Time
+	connsLock sync.Mutex
+
+	// deliveryLock keeps frames from being sequenced while missed frames are replayed.
+	deliveryLock sync.Mutex
+
 	Send       chan messageFrame
 	ReadClose  chan struct{}
 	Disconnect chan int
@@ -29,7 +44,8 @@ func (s *Server) DisconnectClient(clientID int) {
 func newClient(id int) *client {
 	return &client{
 		id:         id,
-		conns:      make([]*websocket.Conn, 0, 2),
+		conns:      make([]*connection, 0, 2),
+		devices:    make(map[string]time.Time),
 		Send:       make(chan messageFrame),
 		Disconnect: make(chan int),
 		ReadClose:  make(chan struct{}),
@@ -38,6 +54,12 @@ func newClient(id int) *client {
 
 // Unicast sends a message over all the connections a client has established.
 func (s *Server) Unicast(ctx context.Context, userID int, payload interface{}) {
+	s.deliver(ctx, userID, payload)
+}
+
+// deliver works like Unicast, but reports whether the user is connected. Frames for a
+// user within the resume window are replayed after the reconnect and count as delivered.
+func (s *Server) deliver(ctx context.Context, userID int, payload interface{}) bool {
 	id := ctx.Value(RequestContextIDKey).(int)
 	command := ctx.Value(RequestContextCommandKey).(RESTCommand)
 	source := ctx.Value(RequestContextSourceKey).(int)
@@ -51,16 +73,17 @@ func (s *Server) Unicast(ctx context.Context, userID int, payload interface{}) {
 		}
 		client.Send <- newFrame(source, id, command, payload)
 	}
+	return ok
 }
 
-func (c *client) breakConnection(connection *websocket.Conn) bool {
+func (c *client) breakConnection(brokenConn *websocket.Conn) bool {
 	connectionIndex := -1
 
 	c.connsLock.Lock()
 	defer c.connsLock.Unlock()
 
 	for i, conn := range c.conns {
-		if conn == connection {
+		if conn.Conn == brokenConn {
 			connectionIndex = i
 			break
 		}
@@ -70,7 +93,53 @@ func (c *client) breakConnection(connection *websocket.Conn) bool {
 		return len(c.conns) == 0
 	}
 
+	device := c.conns[connectionIndex].device
 	c.conns[connectionIndex] = c.conns[len(c.conns)-1]
 	c.conns = c.conns[:len(c.conns)-1]
+
+	if !c.isDeviceConnected(device) {
+		c.devices[device] = time.Now()
+	}
 	return len(c.conns) == 0
 }
+
+// isDeviceConnected must be called while holding the connections lock.
+func (c *client) isDeviceConnected(device string) bool {
+	for _, conn := range c.conns {
+		if conn.device == device {
+			return true
+		}
+	}
+	return false
+}
+
+// deliveryDevices returns the devices a frame has to be sequenced for. Devices that
+// have been disconnected for longer than the resume window are dropped.
+func (c *client) deliveryDevices(resumeWindow time.Duration) []string {
+	c.connsLock.Lock()
+	defer c.connsLock.Unlock()
+
+	devices := make([]string, 0, len(c.devices))
+	for device, disconnectedAt := range c.devices {
+		if !disconnectedAt.IsZero() && time.Since(disconnectedAt) > resumeWindow {
+			delete(c.devices, device)
+			continue
+		}
+		devices = append(devices, device)
+	}
+	return devices
+}
+
+// connectionsOf returns the connections the device has currently established.
+func (c *client) connectionsOf(device string) []*connection {
+	c.connsLock.Lock()
+	defer c.connsLock.Unlock()
+
+	conns := make([]*connection, 0, 1)
+	for _, conn := range c.conns {
+		if conn.device == device {
+			conns = append(conns, conn)
+		}
+	}
+	return conns
+}
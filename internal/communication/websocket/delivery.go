@@ -0,0 +1,211 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/gorilla/websocket"
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+var resyncCommand = RESTCommand{
+	Ressource: "delivery/resync",
+	Method:    NotifyCommandMethod,
+}
+
+var deviceCommand = RESTCommand{
+	Ressource: "delivery/device",
+	Method:    NotifyCommandMethod,
+}
+
+const maxDeviceLength = 64
+
+// deliverFrame sequences the frame for every device of the client and sends it over the
+// connections of the device. The connections lock is not held while the replay buffer is accessed.
+func (s *Server) deliverFrame(c *client, frame messageFrame) {
+	c.deliveryLock.Lock()
+	defer c.deliveryLock.Unlock()
+
+	for _, device := range c.deliveryDevices(s.cfg.ResumeWindow) {
+		data, err := s.sequenceFrame(c.id, device, frame)
+		if err != nil {
+			level.Error(s.logger).Log("Function", "deliverFrame", "client", c.id, "err", err)
+			continue
+		}
+
+		for _, conn := range c.connectionsOf(device) {
+			err = conn.WriteMessage(websocket.TextMessage, data)
+			if err != nil {
+				level.Error(s.logger).Log("Function", "deliverFrame", "client", c.id, "err", err)
+			}
+		}
+	}
+}
+
+// sequenceFrame numbers the frame and keeps it in the replay buffer of the device. If the buffer is
+// not available, the frame is delivered without a sequence number.
+func (s *Server) sequenceFrame(userID int, device string, frame messageFrame) ([]byte, error) {
+	sequence, err := s.replayBuffer.NextSequence(userID, device)
+	if err != nil {
+		level.Error(s.logger).Log("Function", "sequenceFrame", "client", userID, "err", err)
+		return json.Marshal(frame)
+	}
+
+	frame.Sequence = sequence
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.replayBuffer.Store(userID, device, sequence, data)
+	if err != nil {
+		level.Error(s.logger).Log("Function", "sequenceFrame", "client", userID, "err", err)
+	}
+	return data, nil
+}
+
+// attachConnection adds the connection of a device to the client. A connection without a valid
+// device is assigned a new one, which is sent to it first. If the device presents the sequence number
+// of the last frame it has acknowledged, all frames it has missed since are sent next.
+// No frame can be sequenced in the meantime, so nothing is lost or duplicated.
+func (s *Server) attachConnection(c *client, conn *websocket.Conn, device, resume string) *connection {
+	c.deliveryLock.Lock()
+	defer c.deliveryLock.Unlock()
+
+	if !isValidDevice(device) {
+		device = strconv.FormatInt(rand.Int63(), 36)
+		payload := struct {
+			Device string `json:"device"`
+		}{device}
+		err := conn.WriteJSON(newFrame(-1, rand.Int(), deviceCommand, payload))
+		if err != nil {
+			level.Error(s.logger).Log("Function", "attachConnection", "client", c.id, "err", err)
+		}
+	}
+
+	if resume != "" {
+		// Frames are only kept for devices which are known to the client.
+		c.connsLock.Lock()
+		_, isBuffered := c.devices[device]
+		c.connsLock.Unlock()
+
+		err := s.replay(c.id, device, isBuffered, conn, resume)
+		if err != nil {
+			level.Error(s.logger).Log("Function", "attachConnection", "client", c.id, "err", err)
+		}
+	}
+
+	attached := &connection{Conn: conn, device: device}
+	c.connsLock.Lock()
+	c.conns = append(c.conns, attached)
+	c.devices[device] = time.Time{}
+	c.connsLock.Unlock()
+	return attached
+}
+
+func (s *Server) replay(userID int, device string, isBuffered bool, conn *websocket.Conn, resume string) error {
+	lastAcknowledged, err := strconv.ParseInt(resume, 10, 64)
+	if err != nil || lastAcknowledged < 0 || !isBuffered {
+		return conn.WriteJSON(newFrame(-1, rand.Int(), resyncCommand, nil))
+	}
+
+	frames, isComplete, err := s.replayBuffer.FramesAfter(userID, device, lastAcknowledged)
+	if err != nil || !isComplete {
+		// The client has to fetch its state again.
+		writeErr := conn.WriteJSON(newFrame(-1, rand.Int(), resyncCommand, nil))
+		if err != nil {
+			return err
+		}
+		return writeErr
+	}
+
+	for _, frame := range frames {
+		err = conn.WriteMessage(websocket.TextMessage, frame)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isValidDevice(device string) bool {
+	if device == "" || len(device) > maxDeviceLength {
+		return false
+	}
+
+	for _, r := range device {
+		isAlphaNumeric := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+		if !isAlphaNumeric && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// scheduleDetachment removes a client that has lost all of its connections after the resume window.
+// Until then, all frames for the client are kept in the replay buffer.
+func (s *Server) scheduleDetachment(c *client) {
+	s.detachments.Lock()
+	defer s.detachments.Unlock()
+
+	if timer, ok := s.detachments.m[c.id]; ok {
+		timer.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(s.cfg.ResumeWindow, func() {
+		clients.Lock()
+		s.detachments.Lock()
+		if s.detachments.m[c.id] != timer {
+			s.detachments.Unlock()
+			clients.Unlock()
+			return
+		}
+		delete(s.detachments.m, c.id)
+		s.detachments.Unlock()
+
+		// The client may have reconnected before this detachment was scheduled.
+		c.connsLock.Lock()
+		isConnected := len(c.conns) > 0
+		c.connsLock.Unlock()
+		if isConnected {
+			clients.Unlock()
+			return
+		}
+
+		delete(clients.m, c.id)
+		clients.Unlock()
+
+		s.removeClientFromRooms(c)
+		c.Disconnect <- 1000 // Code is not relevant here
+	})
+	s.detachments.m[c.id] = timer
+}
+
+// cancelDetachment must be called while holding the clients lock.
+func (s *Server) cancelDetachment(userID int) {
+	s.detachments.Lock()
+	defer s.detachments.Unlock()
+
+	if timer, ok := s.detachments.m[userID]; ok {
+		timer.Stop()
+		delete(s.detachments.m, userID)
+	}
+}
+
+func (s *Server) acknowledgeFrames(ctx context.Context, clientID int, frame messageFrame) error {
+	payload := struct {
+		Sequence int64 `json:"seq"`
+	}{}
+	err := json.Unmarshal(*frame.Payload.(*json.RawMessage), &payload)
+	if err != nil {
+		return core.NewJSONFormatError(err.Error())
+	}
+
+	device, _ := ctx.Value(RequestContextDeviceKey).(string)
+	return s.replayBuffer.Acknowledge(clientID, device, payload.Sequence)
+}
@@ -0,0 +1,232 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/websocket"
+)
+
+func newDetachmentTestServer() *Server {
+	s := &Server{cfg: Config{ResumeWindow: 10 * time.Millisecond}}
+	s.detachments.m = make(map[int]*time.Timer)
+	s.rooms.m = make(map[int]*room)
+	return s
+}
+
+func TestDetachmentRemovesDisconnectedClient(t *testing.T) {
+	s := newDetachmentTestServer()
+	c := newClient(-101)
+	clients.Lock()
+	clients.m[c.id] = c
+	clients.Unlock()
+
+	s.scheduleDetachment(c)
+	select {
+	case <-c.Disconnect:
+	case <-time.After(time.Second):
+		t.Fatal("client was not detached")
+	}
+
+	clients.RLock()
+	_, ok := clients.m[c.id]
+	clients.RUnlock()
+	if ok {
+		t.Error("client is still registered")
+	}
+}
+
+func TestDetachmentKeepsReconnectedClient(t *testing.T) {
+	s := newDetachmentTestServer()
+	c := newClient(-102)
+	clients.Lock()
+	clients.m[c.id] = c
+	clients.Unlock()
+	defer func() {
+		clients.Lock()
+		delete(clients.m, c.id)
+		clients.Unlock()
+	}()
+
+	// The new connection is attached before the closing one schedules the detachment.
+	c.conns = append(c.conns, &connection{Conn: &websocket.Conn{}})
+	s.scheduleDetachment(c)
+
+	select {
+	case <-c.Disconnect:
+		t.Fatal("reconnected client was detached")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	clients.RLock()
+	_, ok := clients.m[c.id]
+	clients.RUnlock()
+	if !ok {
+		t.Error("reconnected client has been removed")
+	}
+
+	s.detachments.Lock()
+	pending := len(s.detachments.m)
+	s.detachments.Unlock()
+	if pending != 0 {
+		t.Errorf("%d detachments are still pending", pending)
+	}
+}
+
+type memoryReplayBuffer struct {
+	sync.Mutex
+	sequences map[string]int64
+	frames    map[string]map[int64][]byte
+	onAccess  func()
+}
+
+func newMemoryReplayBuffer() *memoryReplayBuffer {
+	return &memoryReplayBuffer{
+		sequences: make(map[string]int64),
+		frames:    make(map[string]map[int64][]byte),
+		onAccess:  func() {},
+	}
+}
+
+func (b *memoryReplayBuffer) NextSequence(userID int, device string) (int64, error) {
+	b.onAccess()
+	b.Lock()
+	defer b.Unlock()
+	b.sequences[device]++
+	return b.sequences[device], nil
+}
+
+func (b *memoryReplayBuffer) Store(userID int, device string, sequence int64, frame []byte) error {
+	b.onAccess()
+	b.Lock()
+	defer b.Unlock()
+	if b.frames[device] == nil {
+		b.frames[device] = make(map[int64][]byte)
+	}
+	b.frames[device][sequence] = frame
+	return nil
+}
+
+func (b *memoryReplayBuffer) Acknowledge(userID int, device string, sequence int64) error {
+	b.Lock()
+	defer b.Unlock()
+	for s := range b.frames[device] {
+		if s <= sequence {
+			delete(b.frames[device], s)
+		}
+	}
+	return nil
+}
+
+func (b *memoryReplayBuffer) FramesAfter(userID int, device string, sequence int64) ([][]byte, bool, error) {
+	b.Lock()
+	defer b.Unlock()
+	members := make([]string, 0)
+	for s := sequence + 1; s <= b.sequences[device]; s++ {
+		if frame, ok := b.frames[device][s]; ok {
+			members = append(members, string(frame))
+		}
+	}
+	frames, isComplete := collectFrames(members, sequence, b.sequences[device])
+	return frames, isComplete, nil
+}
+
+func readSequence(t *testing.T, conn *websocket.Conn) int64 {
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	frame := messageFrame{}
+	err := conn.ReadJSON(&frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return frame.Sequence
+}
+
+func TestDeliveryIsSequencedPerDevice(t *testing.T) {
+	buffer := newMemoryReplayBuffer()
+	s := newDetachmentTestServer()
+	s.cfg.ResumeWindow = time.Minute
+	s.replayBuffer = buffer
+	s.logger = log.NewNopLogger()
+	c := newClient(-103)
+
+	serverConns := make(chan *connection, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		serverConns <- s.attachConnection(c, conn, r.URL.Query().Get("device"), r.URL.Query().Get("resume"))
+	}))
+	defer server.Close()
+
+	dial := func(query string) (*websocket.Conn, *connection) {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn, <-serverConns
+	}
+
+	first, _ := dial("device=first")
+	defer first.Close()
+	second, attachedSecond := dial("device=second")
+
+	// The connections must stay usable while the replay buffer is accessed.
+	buffer.onAccess = func() {
+		locked := make(chan struct{})
+		go func() {
+			c.connsLock.Lock()
+			c.connsLock.Unlock()
+			close(locked)
+		}()
+		select {
+		case <-locked:
+		case <-time.After(time.Second):
+			t.Error("connections are locked while accessing the replay buffer")
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		s.deliverFrame(c, newFrame(-1, i+1, resyncCommand, nil))
+	}
+	for i := int64(1); i <= 2; i++ {
+		if seq := readSequence(t, first); seq != i {
+			t.Errorf("first device received sequence %d, want %d", seq, i)
+		}
+		if seq := readSequence(t, second); seq != i {
+			t.Errorf("second device received sequence %d, want %d", seq, i)
+		}
+	}
+
+	// The first device acknowledges both frames, the second one none.
+	payload := json.RawMessage(`{"seq": 2}`)
+	ctx := context.WithValue(context.Background(), RequestContextDeviceKey, "first")
+	err := s.acknowledgeFrames(ctx, c.id, messageFrame{Payload: &payload})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second device misses a frame and resumes without having acknowledged anything.
+	second.Close()
+	c.breakConnection(attachedSecond.Conn)
+	s.deliverFrame(c, newFrame(-1, 3, resyncCommand, nil))
+	if seq := readSequence(t, first); seq != 3 {
+		t.Errorf("first device received sequence %d, want 3", seq)
+	}
+
+	second, _ = dial("device=second&resume=0")
+	defer second.Close()
+	for i := int64(1); i <= 3; i++ {
+		if seq := readSequence(t, second); seq != i {
+			t.Errorf("resumed device received sequence %d, want %d", seq, i)
+		}
+	}
+}
@@ -16,6 +16,7 @@ const (
 	RequestContextCommandKey = "command"
 	RequestContextSourceKey  = "Source"
 	RequestContextIDKey      = "id"
+	RequestContextDeviceKey  = "device"
 )
 
 type RESTCommand struct {
@@ -28,6 +29,9 @@ type messageFrame struct {
 	Source  int         `json:"source"`
 	ID      int         `json:"id"`
 	Payload interface{} `json:"payload"`
+
+	// Sequence is the per-device delivery sequence number of frames sent by the server.
+	Sequence int64 `json:"seq,omitempty"`
 }
 
 func NewRequestContext(command RESTCommand, id, sourceCtx int) context.Context {
@@ -0,0 +1,63 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+var lockReleaseCommand = RESTCommand{
+	Ressource: "livesession/code",
+	Method:    DeleteCommandMethod,
+}
+
+// scheduleLockRelease releases all locks of a user after the grace period, unless the
+// user reconnects in the meantime.
+func (s *Server) scheduleLockRelease(userID int) {
+	s.lockReleases.Lock()
+	defer s.lockReleases.Unlock()
+
+	if timer, ok := s.lockReleases.m[userID]; ok {
+		timer.Stop()
+	}
+
+	s.lockReleases.m[userID] = time.AfterFunc(s.cfg.LockGracePeriod, func() {
+		s.lockReleases.Lock()
+		delete(s.lockReleases.m, userID)
+		s.lockReleases.Unlock()
+
+		ctx := NewRequestContext(lockReleaseCommand, -1, 0)
+		err := s.Messaging.ReleaseLocksOfUser(userID, s, ctx)
+		if err != nil {
+			level.Error(s.logger).Log("Function", "scheduleLockRelease", "userID", userID, "err", err)
+		}
+	})
+}
+
+func (s *Server) cancelLockRelease(userID int) {
+	s.lockReleases.Lock()
+	defer s.lockReleases.Unlock()
+
+	if timer, ok := s.lockReleases.m[userID]; ok {
+		timer.Stop()
+		delete(s.lockReleases.m, userID)
+	}
+}
+
+// releaseIdleLocks periodically releases the locks of inactive live sessions.
+func (s *Server) releaseIdleLocks() {
+	interval := s.cfg.LockIdleTimeout / 4
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx := NewRequestContext(lockReleaseCommand, -1, 0)
+		err := s.Messaging.ReleaseIdleLocks(s.cfg.LockIdleTimeout, s, ctx)
+		if err != nil {
+			level.Error(s.logger).Log("Function", "releaseIdleLocks", "err", err)
+		}
+	}
+}
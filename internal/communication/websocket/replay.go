@@ -0,0 +1,115 @@
+package websocket
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+const (
+	defaultReplayBufferSize = 500
+
+	// replayBufferTTL bounds how long the frames and the sequence counter of an inactive device are kept.
+	replayBufferTTL = 24 * time.Hour
+)
+
+// ReplayBuffer keeps the frames sent to a device of a user until the device acknowledges them,
+// so they can be delivered again after a reconnect. Every device has its own sequence numbers.
+type ReplayBuffer interface {
+	// NextSequence returns the next delivery sequence number of a device. Sequence numbers start at 1.
+	NextSequence(userID int, device string) (int64, error)
+
+	// Store keeps a serialized frame. Only the newest frames up to the size of the buffer are kept.
+	Store(userID int, device string, sequence int64, frame []byte) error
+
+	// Acknowledge drops every frame up to and including the passed sequence number.
+	Acknowledge(userID int, device string, sequence int64) error
+
+	// FramesAfter returns every stored frame with a higher sequence number in order.
+	// The second return value is false if some of these frames are not available anymore.
+	FramesAfter(userID int, device string, sequence int64) ([][]byte, bool, error)
+}
+
+type redisReplayBuffer struct {
+	client *redis.Client
+	size   int64
+}
+
+// NewRedisReplayBuffer creates a ReplayBuffer that keeps up to size frames per device in Redis.
+func NewRedisReplayBuffer(client *redis.Client, size int) ReplayBuffer {
+	if size <= 0 {
+		size = defaultReplayBufferSize
+	}
+	return &redisReplayBuffer{client: client, size: int64(size)}
+}
+
+func sequenceKey(userID int, device string) string {
+	return "delivery_seq_" + strconv.Itoa(userID) + "_" + device
+}
+
+func bufferKey(userID int, device string) string {
+	return "delivery_buf_" + strconv.Itoa(userID) + "_" + device
+}
+
+func (b *redisReplayBuffer) NextSequence(userID int, device string) (int64, error) {
+	key := sequenceKey(userID, device)
+	pipe := b.client.TxPipeline()
+	next := pipe.Incr(key)
+	pipe.Expire(key, replayBufferTTL)
+	_, err := pipe.Exec()
+	if err != nil {
+		return 0, err
+	}
+	return next.Val(), nil
+}
+
+func (b *redisReplayBuffer) Store(userID int, device string, sequence int64, frame []byte) error {
+	key := bufferKey(userID, device)
+	pipe := b.client.TxPipeline()
+	pipe.ZAdd(key, redis.Z{Score: float64(sequence), Member: frame})
+	pipe.ZRemRangeByRank(key, 0, -b.size-1)
+	pipe.Expire(key, replayBufferTTL)
+	_, err := pipe.Exec()
+	return err
+}
+
+func (b *redisReplayBuffer) Acknowledge(userID int, device string, sequence int64) error {
+	return b.client.ZRemRangeByScore(bufferKey(userID, device), "-inf", strconv.FormatInt(sequence, 10)).Err()
+}
+
+func (b *redisReplayBuffer) FramesAfter(userID int, device string, sequence int64) ([][]byte, bool, error) {
+	latest, err := b.client.Get(sequenceKey(userID, device)).Int64()
+	if err == redis.Nil {
+		latest = 0
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	members, err := b.client.ZRangeByScore(bufferKey(userID, device), redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(sequence, 10),
+		Max: strconv.FormatInt(latest, 10),
+	}).Result()
+	if err != nil {
+		return nil, false, err
+	}
+
+	frames, isComplete := collectFrames(members, sequence, latest)
+	return frames, isComplete, nil
+}
+
+// collectFrames converts the buffered frames after sequence up to latest. They are only
+// complete if none of them has been evicted from the buffer in the meantime.
+func collectFrames(members []string, sequence, latest int64) ([][]byte, bool) {
+	// The counter has expired or the client presents a sequence number it cannot know.
+	if sequence > latest {
+		return nil, false
+	}
+
+	frames := make([][]byte, len(members))
+	for i, member := range members {
+		frames[i] = []byte(member)
+	}
+
+	return frames, int64(len(frames)) == latest-sequence
+}
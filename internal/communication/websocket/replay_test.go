@@ -0,0 +1,37 @@
+package websocket
+
+import "testing"
+
+func TestCollectFrames(t *testing.T) {
+	tests := []struct {
+		members    []string
+		sequence   int64
+		latest     int64
+		nFrames    int
+		isComplete bool
+	}{
+		{[]string{"4", "5", "6"}, 3, 6, 3, true},
+		{[]string{}, 6, 6, 0, true},
+		{[]string{}, 0, 0, 0, true},
+		// Frame 4 has been evicted from the buffer.
+		{[]string{"5", "6"}, 3, 6, 2, false},
+		// The buffer has expired.
+		{[]string{}, 3, 6, 0, false},
+		// The client claims to know frames that were never sent, e.g. after the counter expired.
+		{[]string{}, 7, 6, 0, false},
+		{[]string{}, 7, 0, 0, false},
+	}
+
+	for _, test := range tests {
+		frames, isComplete := collectFrames(test.members, test.sequence, test.latest)
+		if len(frames) != test.nFrames || isComplete != test.isComplete {
+			t.Errorf("collectFrames(%v, %d, %d) = %d frames, %v", test.members, test.sequence, test.latest, len(frames), isComplete)
+		}
+
+		for i, frame := range frames {
+			if string(frame) != test.members[i] {
+				t.Errorf("frame %d is %q, want %q", i, frame, test.members[i])
+			}
+		}
+	}
+}
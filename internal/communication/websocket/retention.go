@@ -0,0 +1,44 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+var expiredMessageCommand = RESTCommand{
+	Ressource: "message",
+	Method:    DeleteCommandMethod,
+}
+
+// deleteExpiredMessages periodically deletes the messages that are older than
+// the retention period of their conversation.
+func (s *Server) deleteExpiredMessages() {
+	ticker := time.NewTicker(s.cfg.RetentionCheckInterval)
+	defer ticker.Stop()
+	for ; true; <-ticker.C {
+		conversations, err := s.Conversations.ListConversations()
+		if err != nil {
+			level.Error(s.logger).Log("Function", "deleteExpiredMessages", "err", err)
+			continue
+		}
+
+		now := time.Now().UTC()
+		for _, c := range conversations {
+			days := s.cfg.DefaultRetentionDays
+			if c.RetentionDays != nil {
+				days = *c.RetentionDays
+			}
+
+			if days <= 0 {
+				continue
+			}
+
+			ctx := NewRequestContext(expiredMessageCommand, -1, c.ID)
+			_, err := s.Messaging.DeleteExpiredMessages(c.ID, now.AddDate(0, 0, -days), s.cfg.MediaFolder, s, ctx)
+			if err != nil {
+				level.Error(s.logger).Log("Function", "deleteExpiredMessages", "conversationID", c.ID, "err", err)
+			}
+		}
+	}
+}
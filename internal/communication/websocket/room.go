@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/go-kit/kit/log/level"
+	core "github.com/miphilipp/devchat-server/internal"
 )
 
 type room struct {
@@ -131,3 +132,40 @@ func (s *Server) BroadcastToRoom(roomNumber int, payload interface{}, ctx contex
 			"err", "No such room")
 	}
 }
+
+// BroadcastThreadSummary sends the current reply count of the thread the passed message
+// belongs to, to every member of the conversation. Messages that are not replies are ignored.
+func (s *Server) BroadcastThreadSummary(userCtx, conversationID int, message interface{}) {
+	reply, ok := message.(core.Threadable)
+	if !ok || reply.GetParentID() == 0 {
+		return
+	}
+
+	summary, err := s.Messaging.GetThreadSummary(userCtx, conversationID, reply.GetParentID())
+	if err != nil {
+		level.Error(s.logger).Log("Function", "BroadcastThreadSummary", "err", err)
+		return
+	}
+
+	ctx := NewRequestContext(RESTCommand{
+		Ressource: "message/thread",
+		Method:    PatchCommandMethod,
+	}, -1, conversationID)
+	s.BroadcastToRoom(conversationID, summary, ctx)
+}
+
+// NotifyMentionedUsers sends the passed message to every user that is mentioned in it.
+func (s *Server) NotifyMentionedUsers(conversationID int, message interface{}) {
+	mentionable, ok := message.(core.Mentionable)
+	if !ok {
+		return
+	}
+
+	ctx := NewRequestContext(RESTCommand{
+		Ressource: "mention",
+		Method:    NotifyCommandMethod,
+	}, 0, conversationID)
+	for _, userID := range mentionable.GetMentions() {
+		s.Unicast(ctx, userID, message)
+	}
+}
@@ -0,0 +1,134 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+const maxSchedulerWait = time.Minute
+
+var (
+	scheduledMessageCommand = RESTCommand{
+		Ressource: "message",
+		Method:    PostCommandMethod,
+	}
+
+	reminderCommand = RESTCommand{
+		Ressource: "reminder",
+		Method:    NotifyCommandMethod,
+	}
+
+	scheduledItemFailedCommand = RESTCommand{
+		Ressource: "scheduled/failed",
+		Method:    NotifyCommandMethod,
+	}
+)
+
+// dispatchScheduledItems sends scheduled messages and reminders as soon as they are due.
+// It sleeps until the next item is due or the schedule changes.
+func (s *Server) dispatchScheduledItems() {
+	for {
+		items, err := s.Scheduling.ClaimDueItems()
+		if err != nil {
+			level.Error(s.logger).Log("Function", "dispatchScheduledItems", "err", err)
+		}
+
+		for _, item := range items {
+			s.dispatchScheduledItem(item)
+		}
+
+		if len(items) > 0 {
+			continue
+		}
+
+		wait := maxSchedulerWait
+		next, err := s.Scheduling.NextDueDate()
+		if err != nil {
+			level.Error(s.logger).Log("Function", "dispatchScheduledItems", "err", err)
+		} else if !next.IsZero() && time.Until(next) < wait {
+			wait = time.Until(next)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-s.Scheduling.Changes():
+			timer.Stop()
+		}
+	}
+}
+
+func (s *Server) dispatchScheduledItem(item core.ScheduledItem) {
+	switch item.Kind {
+	case core.ScheduledMessage:
+		ctx := NewRequestContext(scheduledMessageCommand, -1, item.ConversationID)
+		answer, err := s.Messaging.SendMessage(item.ConversationID, item.UserID, item.Message, s, ctx)
+		if err != nil {
+			level.Error(s.logger).Log("Function", "dispatchScheduledItem", "itemID", item.ID, "err", err)
+			s.retryScheduledItem(item, err)
+			return
+		}
+
+		s.completeScheduledItem(item)
+		s.BroadcastThreadSummary(item.UserID, item.ConversationID, answer)
+		s.NotifyMentionedUsers(item.ConversationID, answer)
+	case core.ScheduledReminder:
+		ctx := NewRequestContext(reminderCommand, 0, item.ConversationID)
+		if s.deliver(ctx, item.UserID, item) {
+			s.completeScheduledItem(item)
+			return
+		}
+
+		// The reminder is delivered as soon as the user connects again.
+		err := s.Scheduling.DeferItem(item.ID)
+		if err != nil {
+			level.Error(s.logger).Log("Function", "dispatchScheduledItem", "itemID", item.ID, "err", err)
+		}
+	}
+}
+
+// dispatchDeferredItems delivers the reminders a user has missed while being offline.
+func (s *Server) dispatchDeferredItems(userID int) {
+	items, err := s.Scheduling.ClaimDeferredItems(userID)
+	if err != nil {
+		level.Error(s.logger).Log("Function", "dispatchDeferredItems", "client", userID, "err", err)
+		return
+	}
+
+	for _, item := range items {
+		s.dispatchScheduledItem(item)
+	}
+}
+
+// completeScheduledItem removes a dispatched item. If that fails, the item is dispatched
+// again once its claim has timed out.
+func (s *Server) completeScheduledItem(item core.ScheduledItem) {
+	err := s.Scheduling.CompleteItem(item.ID)
+	if err != nil {
+		level.Error(s.logger).Log(
+			"Function", "completeScheduledItem",
+			"itemID", item.ID,
+			"message", "item might be dispatched again",
+			"err", err)
+	}
+}
+
+// retryScheduledItem reschedules a message that could not be sent. If the message is
+// dropped instead, its author is notified about the failure.
+func (s *Server) retryScheduledItem(item core.ScheduledItem, err error) {
+	retried, retryErr := s.Scheduling.RetryItem(item, err)
+	if retried || retryErr != nil {
+		return
+	}
+
+	apiErr, ok := core.UnwrapDatabaseError(err).(core.ApiError)
+	if !ok {
+		apiErr = core.ErrUnknownError
+	}
+
+	item.Error = &apiErr
+	ctx := NewRequestContext(scheduledItemFailedCommand, 0, item.ConversationID)
+	s.Unicast(ctx, item.UserID, item)
+}
@@ -0,0 +1,56 @@
+package websocket
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	core "github.com/miphilipp/devchat-server/internal"
+	"github.com/miphilipp/devchat-server/internal/scheduling"
+)
+
+type fakeSchedulingService struct {
+	scheduling.Service
+	completed []int
+	deferred  []int
+}
+
+func (s *fakeSchedulingService) CompleteItem(itemID int) error {
+	s.completed = append(s.completed, itemID)
+	return errors.New("connection lost")
+}
+
+func (s *fakeSchedulingService) DeferItem(itemID int) error {
+	s.deferred = append(s.deferred, itemID)
+	return nil
+}
+
+func TestReminderIsDeferredWhileOffline(t *testing.T) {
+	schedulingService := &fakeSchedulingService{}
+	s := &Server{Scheduling: schedulingService, logger: log.NewNopLogger()}
+	reminder := core.ScheduledItem{ID: 5, Kind: core.ScheduledReminder, UserID: -201, ConversationID: 1}
+
+	s.dispatchScheduledItem(reminder)
+	if len(schedulingService.deferred) != 1 || len(schedulingService.completed) != 0 {
+		t.Fatalf("reminder for an offline user was not deferred: %#v", schedulingService)
+	}
+
+	c := newClient(reminder.UserID)
+	clients.Lock()
+	clients.m[c.id] = c
+	clients.Unlock()
+	defer func() {
+		clients.Lock()
+		delete(clients.m, c.id)
+		clients.Unlock()
+	}()
+
+	received := make(chan messageFrame, 1)
+	go func() { received <- <-c.Send }()
+
+	s.dispatchScheduledItem(reminder)
+	frame := <-received
+	if frame.Command != reminderCommand || len(schedulingService.completed) != 1 || len(schedulingService.deferred) != 1 {
+		t.Errorf("reminder was not delivered: %#v %#v", frame, schedulingService)
+	}
+}
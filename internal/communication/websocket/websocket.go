@@ -7,6 +7,7 @@ import (
 
 	//"fmt"
 	"sync"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
@@ -15,7 +16,9 @@ import (
 	"github.com/gorilla/websocket"
 	core "github.com/miphilipp/devchat-server/internal"
 	"github.com/miphilipp/devchat-server/internal/conversations"
+	"github.com/miphilipp/devchat-server/internal/execution"
 	"github.com/miphilipp/devchat-server/internal/messaging"
+	"github.com/miphilipp/devchat-server/internal/scheduling"
 	"github.com/miphilipp/devchat-server/internal/user"
 )
 
@@ -37,13 +40,39 @@ type endpoint struct {
 	handler   func(ctx context.Context, clientID int, frame messageFrame) error
 }
 
+// Config contains the settings of the background jobs of the server. Zero values are replaced by defaults.
+type Config struct {
+	// LockGracePeriod is the time after which the locks of a disconnected user are released.
+	LockGracePeriod time.Duration
+
+	// LockIdleTimeout is the time after which locks of inactive live sessions are released.
+	LockIdleTimeout time.Duration
+
+	// DefaultRetentionDays applies to conversations without own retention period.
+	// 0 keeps messages forever.
+	DefaultRetentionDays int
+
+	// RetentionCheckInterval is the time between two runs of the deletion of expired messages.
+	RetentionCheckInterval time.Duration
+
+	// MediaFolder is the folder the files of media messages are stored in.
+	MediaFolder string
+
+	// ResumeWindow is the time a disconnected user can reconnect and receive the frames that were missed.
+	ResumeWindow time.Duration
+}
+
 type Server struct {
 	Messaging     messaging.Service
 	Conversations conversations.Service
 	User          user.Service
+	Execution     execution.Service
+	Scheduling    scheduling.Service
 
-	logger  log.Logger
-	limiter *WebsocketRateLimiter
+	logger       log.Logger
+	limiter      *WebsocketRateLimiter
+	replayBuffer ReplayBuffer
+	cfg          Config
 
 	endpoints []endpoint
 
@@ -51,15 +80,45 @@ type Server struct {
 		sync.RWMutex
 		m map[int]*room
 	}
+
+	lockReleases struct {
+		sync.Mutex
+		m map[int]*time.Timer
+	}
+
+	detachments struct {
+		sync.Mutex
+		m map[int]*time.Timer
+	}
 }
 
 func New(
 	messagingService messaging.Service,
 	conversationService conversations.Service,
 	userService user.Service,
+	executionService execution.Service,
+	schedulingService scheduling.Service,
+	cfg Config,
 	limiterStore throttled.GCRAStore,
+	replayBuffer ReplayBuffer,
 	logger log.Logger) *Server {
 
+	if cfg.LockGracePeriod == 0 {
+		cfg.LockGracePeriod = 30 * time.Second
+	}
+
+	if cfg.LockIdleTimeout == 0 {
+		cfg.LockIdleTimeout = 15 * time.Minute
+	}
+
+	if cfg.RetentionCheckInterval == 0 {
+		cfg.RetentionCheckInterval = time.Hour
+	}
+
+	if cfg.ResumeWindow == 0 {
+		cfg.ResumeWindow = 2 * time.Minute
+	}
+
 	vary := &WebsocketVaryBy{RemoteAddr: true, Method: false, Ressource: true}
 	limiter, err := newWebsocketRateLimiter(limiterStore, vary, 20, 3)
 	if err != nil {
@@ -70,8 +129,12 @@ func New(
 		Messaging:     messagingService,
 		Conversations: conversationService,
 		User:          userService,
+		Execution:     executionService,
+		Scheduling:    schedulingService,
 		logger:        logger,
 		limiter:       limiter,
+		replayBuffer:  replayBuffer,
+		cfg:           cfg,
 		endpoints:     make([]endpoint, 0, 10),
 		rooms: struct {
 			sync.RWMutex
@@ -89,8 +152,15 @@ func New(
 		server.rooms.m[c.ID] = newRoom(c.ID)
 	}
 
+	server.lockReleases.m = make(map[int]*time.Timer)
+	server.detachments.m = make(map[int]*time.Timer)
+
 	registerEndpoints(server)
 
+	go server.releaseIdleLocks()
+	go server.dispatchScheduledItems()
+	go server.deleteExpiredMessages()
+
 	return server
 }
 
@@ -105,6 +175,7 @@ func (s *Server) removeClientFromRooms(client *client) {
 }
 
 // StartWebsocket upgrades the connection to a websocket connection.
+// A client that passes the query parameter resume receives the frames it has missed since then.
 func (s *Server) StartWebsocket(w http.ResponseWriter, r *http.Request, user int) error {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -112,16 +183,17 @@ func (s *Server) StartWebsocket(w http.ResponseWriter, r *http.Request, user int
 		return err
 	}
 
-	clients.RLock()
+	clients.Lock()
 	c, ok := clients.m[user]
-	clients.RUnlock()
 	if !ok {
 		c = newClient(user)
-
-		clients.Lock()
 		clients.m[user] = c
-		clients.Unlock()
+	}
+	s.cancelDetachment(user)
+	clients.Unlock()
 
+	s.cancelLockRelease(user)
+	if !ok {
 		conversationWithUser, err := s.Conversations.ListConversationsForUser(user)
 		if err != nil {
 			level.Error(s.logger).Log("err", err)
@@ -146,22 +218,20 @@ func (s *Server) StartWebsocket(w http.ResponseWriter, r *http.Request, user int
 		return nil
 	})
 
-	c.connsLock.Lock()
-	c.conns = append(c.conns, conn)
-	c.connsLock.Unlock()
+	attached := s.attachConnection(c, conn, r.URL.Query().Get("device"), r.URL.Query().Get("resume"))
+	if !ok {
+		go s.dispatchDeferredItems(c.id)
+	}
 
-	go s.receiveLoop(conn, c)
+	go s.receiveLoop(attached, c)
 	return nil
 }
 
 func (s *Server) cleanupAfterClient(conn *websocket.Conn, client *client) {
 	isCompletlyDisconnected := client.breakConnection(conn)
 	if isCompletlyDisconnected {
-		clients.Lock()
-		delete(clients.m, client.id)
-		clients.Unlock()
-		s.removeClientFromRooms(client)
-		client.Disconnect <- 1000 // Code is not relevant here
+		s.scheduleLockRelease(client.id)
+		s.scheduleDetachment(client)
 	}
 }
 
@@ -169,14 +239,13 @@ func (s *Server) sendLoop(client *client) {
 	for {
 		select {
 		case msg := <-client.Send:
-			for _, conn := range client.conns {
-				err := conn.WriteJSON(msg)
-				if err != nil {
-					level.Error(s.logger).Log("Function", "sendLoop", "client", client.id, "err", err)
-				}
-			}
+			s.deliverFrame(client, msg)
 		case code := <-client.Disconnect:
-			for _, conn := range client.conns {
+			client.connsLock.Lock()
+			conns := client.conns
+			client.connsLock.Unlock()
+
+			for _, conn := range conns {
 				err := conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, ""))
 				if err != nil {
 					level.Error(s.logger).Log("Function", "sendLoop", "client", client.id, "err", err)
@@ -187,7 +256,7 @@ func (s *Server) sendLoop(client *client) {
 	}
 }
 
-func (s *Server) receiveLoop(conn *websocket.Conn, c *client) {
+func (s *Server) receiveLoop(conn *connection, c *client) {
 	defer conn.Close()
 
 	for {
@@ -200,12 +269,12 @@ func (s *Server) receiveLoop(conn *websocket.Conn, c *client) {
 		if err != nil {
 			if websocket.IsCloseError(err, websocket.CloseAbnormalClosure) {
 				level.Debug(s.logger).Log("err", err)
-				s.cleanupAfterClient(conn, c)
+				s.cleanupAfterClient(conn.Conn, c)
 			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseAbnormalClosure) {
 				level.Info(s.logger).Log("message", "Connection has been closed by peer")
 			} else {
 				level.Error(s.logger).Log("err", err)
-				s.cleanupAfterClient(conn, c)
+				s.cleanupAfterClient(conn.Conn, c)
 			}
 			return
 		}
@@ -231,6 +300,7 @@ func (s *Server) receiveLoop(conn *websocket.Conn, c *client) {
 				}
 
 				ctx := NewRequestContext(wrapper.Command, wrapper.ID, wrapper.Source)
+				ctx = context.WithValue(ctx, RequestContextDeviceKey, conn.device)
 				err = endpoint.handler(ctx, c.id, wrapper)
 				if err != nil {
 					c.Send <- makeErrorMessage(core.UnwrapDatabaseError(err), wrapper.ID, wrapper.Command.Ressource)
@@ -274,17 +344,86 @@ func registerEndpoints(server *Server) {
 		return err
 	})
 
+	server.addEndpoint(RESTCommand{"livesession/handover", PostCommandMethod}, true, func(ctx context.Context, clientID int, frame messageFrame) error {
+		_, err := server.Messaging.RequestLockHandOver(clientID, frame.Source, *frame.Payload.(*json.RawMessage), server, ctx)
+		return err
+	})
+
+	server.addEndpoint(RESTCommand{"livesession/handover", PatchCommandMethod}, true, func(ctx context.Context, clientID int, frame messageFrame) error {
+		_, err := server.Messaging.AnswerLockHandOver(clientID, frame.Source, *frame.Payload.(*json.RawMessage), server, ctx)
+		return err
+	})
+
 	server.addEndpoint(RESTCommand{"message", PatchCommandMethod}, true, func(ctx context.Context, clientID int, frame messageFrame) error {
 		_, err := server.Messaging.EditMessage(clientID, frame.Source, *frame.Payload.(*json.RawMessage), server, ctx)
 		return err
 	})
 
 	server.addEndpoint(RESTCommand{"message", PostCommandMethod}, true, func(ctx context.Context, clientID int, frame messageFrame) error {
-		_, err := server.Messaging.SendMessage(frame.Source, clientID, *frame.Payload.(*json.RawMessage), server, ctx)
+		answer, err := server.Messaging.SendMessage(frame.Source, clientID, *frame.Payload.(*json.RawMessage), server, ctx)
+		if err != nil {
+			return err
+		}
+
+		// Media messages are announced to the room as soon as their upload is complete.
+		if _, isMediaMessage := answer.(core.MediaMessage); !isMediaMessage {
+			server.BroadcastThreadSummary(clientID, frame.Source, answer)
+			server.NotifyMentionedUsers(frame.Source, answer)
+		}
+		return nil
+	})
+
+	server.addEndpoint(RESTCommand{"message/reaction", PostCommandMethod}, true, func(ctx context.Context, clientID int, frame messageFrame) error {
+		_, err := server.Messaging.ToggleReaction(clientID, frame.Source, true, *frame.Payload.(*json.RawMessage), server, ctx)
+		return err
+	})
+
+	server.addEndpoint(RESTCommand{"message/reaction", DeleteCommandMethod}, true, func(ctx context.Context, clientID int, frame messageFrame) error {
+		_, err := server.Messaging.ToggleReaction(clientID, frame.Source, false, *frame.Payload.(*json.RawMessage), server, ctx)
+		return err
+	})
+
+	server.addEndpoint(RESTCommand{"message/vote", PostCommandMethod}, true, func(ctx context.Context, clientID int, frame messageFrame) error {
+		_, err := server.Messaging.TogglePollVote(clientID, frame.Source, true, *frame.Payload.(*json.RawMessage), server, ctx)
 		return err
 	})
 
+	server.addEndpoint(RESTCommand{"message/vote", DeleteCommandMethod}, true, func(ctx context.Context, clientID int, frame messageFrame) error {
+		_, err := server.Messaging.TogglePollVote(clientID, frame.Source, false, *frame.Payload.(*json.RawMessage), server, ctx)
+		return err
+	})
+
+	server.addEndpoint(RESTCommand{"message/execution", PostCommandMethod}, true, func(ctx context.Context, clientID int, frame messageFrame) error {
+		payload := struct {
+			MessageID int `json:"messageId"`
+		}{}
+		err := json.Unmarshal(*frame.Payload.(*json.RawMessage), &payload)
+		if err != nil {
+			return core.NewJSONFormatError(err.Error())
+		}
+
+		return server.Execution.Execute(clientID, frame.Source, payload.MessageID, server, ctx)
+	})
+
+	server.addEndpoint(RESTCommand{"draft", PatchCommandMethod}, true, func(ctx context.Context, clientID int, frame messageFrame) error {
+		var draft core.Draft
+		err := json.Unmarshal(*frame.Payload.(*json.RawMessage), &draft)
+		if err != nil {
+			return core.NewJSONFormatError(err.Error())
+		}
+
+		draft.ConversationID = frame.Source
+		_, err = server.Messaging.SaveDraft(clientID, draft, server, ctx)
+		return err
+	})
+
+	server.addEndpoint(RESTCommand{"draft", DeleteCommandMethod}, true, func(ctx context.Context, clientID int, frame messageFrame) error {
+		return server.Messaging.ClearDraft(clientID, frame.Source, server, ctx)
+	})
+
+	server.addEndpoint(RESTCommand{"delivery/ack", NotifyCommandMethod}, false, server.acknowledgeFrames)
+
 	server.addEndpoint(RESTCommand{"message/read", NotifyCommandMethod}, true, func(ctx context.Context, clientID int, frame messageFrame) error {
-		return server.Messaging.ReadMessages(clientID, *frame.Payload.(*json.RawMessage))
+		return server.Messaging.ReadMessages(clientID, *frame.Payload.(*json.RawMessage), server, ctx)
 	})
 }
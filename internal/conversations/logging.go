@@ -86,6 +86,20 @@ func (s *loggingService) EditConversation(userCtx int, conversation core.Convers
 	return s.next.EditConversation(userCtx, conversation)
 }
 
+func (s *loggingService) SetRetentionPeriod(userCtx, conversationID int, days *int) (err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "SetRetentionPeriod",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.SetRetentionPeriod(userCtx, conversationID, days)
+}
+
 func (s *loggingService) ListConversationsForUser(user int) (conversations []core.Conversation, err error) {
 	defer func(begin time.Time) {
 		if err != nil || s.verbose {
@@ -4,6 +4,8 @@ import (
 	core "github.com/miphilipp/devchat-server/internal"
 )
 
+const maxRetentionDays = 36500
+
 // Service defines all use cases related to conversations.
 // All users that are passed via an argument labeled userCtx are expected to be logged in.
 type Service interface {
@@ -21,6 +23,7 @@ type Service interface {
 	DeleteConversation(userCtx, conversationID int) error
 	SetAdminStatus(userCtx, newAdmin, conversationID int, status bool) error
 	EditConversation(userCtx int, conversation core.Conversation) (core.Conversation, error)
+	SetRetentionPeriod(userCtx, conversationID int, days *int) error
 
 	// Restricted access
 	ListConversationsForUser(userCtx int) ([]core.Conversation, error)
@@ -229,6 +232,25 @@ func (s *service) EditConversation(userCtx int, conversation core.Conversation)
 	}, nil
 }
 
+// SetRetentionPeriod sets the number of days after which messages of the conversation are deleted.
+// nil restores the server default, 0 disables the deletion.
+func (s *service) SetRetentionPeriod(userCtx, conversationID int, days *int) error {
+	isAdmin, err := s.conversationRepo.IsUserAdminOfConveration(userCtx, conversationID)
+	if err != nil {
+		return err
+	}
+
+	if !isAdmin {
+		return core.ErrAccessDenied
+	}
+
+	if days != nil && (*days < 0 || *days > maxRetentionDays) {
+		return core.NewInvalidValueError("days")
+	}
+
+	return s.conversationRepo.SetRetentionPeriod(conversationID, days)
+}
+
 func (s *service) ListUsersOfConversation(userCtx int, conversationID int) ([]core.UserInConversation, error) {
 	isMember, err := s.conversationRepo.IsUserInConversation(userCtx, conversationID)
 	if err != nil {
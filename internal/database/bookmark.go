@@ -0,0 +1,69 @@
+package database
+
+import (
+	"github.com/go-pg/pg/v9"
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+// StoreBookmark creates the bookmark or replaces note and tags of the existing one.
+func (r *messageRepository) StoreBookmark(userID int, bookmark core.Bookmark) error {
+	_, err := r.db.Exec(
+		`INSERT INTO bookmark (userid, conversationid, messageid, note, tags)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (userid, messageid) DO UPDATE
+		SET note = EXCLUDED.note, tags = EXCLUDED.tags;`,
+		userID, bookmark.ConversationID, bookmark.MessageID,
+		nullIfEmpty(bookmark.Note), pg.Array(bookmark.Tags))
+	return core.NewDataBaseError(err)
+}
+
+func (r *messageRepository) DeleteBookmark(userID, messageID int) error {
+	res, err := r.db.Exec(
+		`DELETE FROM bookmark WHERE userid = ? AND messageid = ?;`, userID, messageID)
+	if err != nil {
+		return core.NewDataBaseError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return core.ErrNothingChanged
+	}
+	return nil
+}
+
+func (r *messageRepository) FindBookmark(userID, messageID int) (core.Bookmark, error) {
+	var bookmark core.Bookmark
+	_, err := r.db.QueryOne(&bookmark,
+		`SELECT b.id, b.conversationid, b.messageid, coalesce(b.note, '') as note, b.tags, b.creationdate, p.preview
+		FROM bookmark b
+		LEFT JOIN v_message_preview p ON p.id = b.messageid
+		WHERE b.userid = ? AND b.messageid = ?;`, userID, messageID)
+	if err == pg.ErrNoRows {
+		return core.Bookmark{}, core.ErrRessourceDoesNotExist
+	}
+
+	if err != nil {
+		return core.Bookmark{}, core.NewDataBaseError(err)
+	}
+
+	return bookmark, nil
+}
+
+// FindBookmarksForUser returns the bookmarks of the user, newest first. Bookmarks in
+// conversations the user has left are omitted. An empty tag disables the tag filter.
+func (r *messageRepository) FindBookmarksForUser(userID, beforeID, limit int, tag string) ([]core.Bookmark, error) {
+	bookmarks := make([]core.Bookmark, 0, limit)
+	_, err := r.db.Query(&bookmarks,
+		`SELECT b.id, b.conversationid, b.messageid, coalesce(b.note, '') as note, b.tags, b.creationdate, p.preview
+		FROM bookmark b
+		JOIN group_association g ON g.userid = b.userid AND g.conversationid = b.conversationid
+		LEFT JOIN v_message_preview p ON p.id = b.messageid
+		WHERE b.userid = ? AND b.id < ? AND g.joined IS NOT NULL AND g.hasleft = false
+			AND (? = '' OR ? = ANY(b.tags))
+		ORDER BY b.id DESC
+		LIMIT ?;`, userID, beforeID, tag, tag, limit)
+	if err != nil {
+		return nil, core.NewDataBaseError(err)
+	}
+
+	return bookmarks, nil
+}
@@ -7,25 +7,30 @@ import (
 
 func (r *messageRepository) StoreCodeMessage(conversation int, user int, m core.CodeMessage) (int, error) {
 	var id = -1
-	_, err := callFunction(r.db, "createCodeMessage", &id, user, conversation, m.Code, m.Sentdate, m.Language, m.Title)
+	_, err := callFunction(r.db, "createCodeMessage", &id, user, conversation, m.Code, m.Sentdate, m.Language, m.Title, m.FileName, emptyIfNil(m.Files), nullIfZero(m.ParentID))
 	if err != nil {
 		return 0, core.NewDataBaseError(err)
 	}
+
+	err = r.storeReferences(id, m.Message)
+	if err != nil {
+		return 0, err
+	}
+
 	return id, nil
 }
 
 func (r *messageRepository) FindCodeMessagesForConversation(
 	conversationID int,
-	beforeInSequence int,
-	limit int) ([]interface{}, error) {
+	window core.MessageRange) ([]interface{}, error) {
 
 	codeMessages := make([]core.CodeMessage, 0, 10)
 	_, err := r.db.Query(&codeMessages,
-		`SELECT type, id, sentdate, author, code, language, title, lockedby
+		`SELECT type, id, sentdate, author, code, language, title, filename, files, lockedby, revision, parentid, replycount, reactions, isedited, ispinned, origin, quotedid, quote
 		FROM v_code_message
-		WHERE conversationid = ? AND id < ?
-		ORDER BY id desc
-		LIMIT ?;`, conversationID, beforeInSequence, limit)
+		WHERE conversationid = ? AND id > ? AND id < ? AND parentid IS NULL
+		ORDER BY id ?
+		LIMIT ?;`, conversationID, window.AfterID, window.BeforeID, sortOrder(window), window.Limit)
 	if err != nil {
 		return make([]interface{}, 0), core.NewDataBaseError(err)
 	}
@@ -41,7 +46,7 @@ func (r *messageRepository) FindCodeMessagesForConversation(
 func (r *messageRepository) FindCodeMessageForID(messageID, conversationID int) (core.CodeMessage, error) {
 	var message core.CodeMessage
 	_, err := r.db.QueryOne(&message,
-		`SELECT type, id, sentdate, author, code, language, title, lockedby
+		`SELECT type, id, sentdate, author, code, language, title, filename, files, lockedby, revision, parentid, replycount, reactions, isedited, ispinned, origin, quotedid, quote
 		FROM v_code_message
 		WHERE id = ? AND conversationid = ?;`, messageID, conversationID)
 	if err != nil && err == pg.ErrNoRows {
@@ -55,19 +60,58 @@ func (r *messageRepository) FindCodeMessageForID(messageID, conversationID int)
 	return message, nil
 }
 
-func (r *messageRepository) UpdateCode(messageID int, newCode, title, language string) error {
-	res, err := r.db.Exec(
-		`UPDATE public.code_message
-		 SET code = ?, language = ?, title = ?
-		 WHERE id = ?;`, newCode, language, title, messageID)
+// UpdateCode stores the code, title, language and files of the message as a new revision
+// and returns its number.
+func (r *messageRepository) UpdateCode(messageID, userID int, m core.CodeMessage) (int, error) {
+	var revision int
+	_, err := callFunction(r.db, "updateCode", &revision, messageID, nullIfZero(userID), m.Code, m.Title, m.Language, m.FileName, emptyIfNil(m.Files))
+	if err != nil {
+		return 0, core.NewDataBaseError(err)
+	}
+
+	if revision == 0 {
+		return 0, core.ErrRessourceDoesNotExist
+	}
+	return revision, nil
+}
+
+func (r *messageRepository) FindRevisionsForCodeMessage(messageID int) ([]core.CodeRevision, error) {
+	revisions := make([]core.CodeRevision, 0, 10)
+	_, err := r.db.Query(&revisions,
+		`SELECT r.revision, coalesce(u.name, '') as author, r.revisiondate, r.title, r.language, r.filename
+		FROM code_revision r
+		LEFT JOIN public.user u ON u.id = r.userid
+		WHERE r.messageid = ?
+		ORDER BY r.revision;`, messageID)
+	if err != nil {
+		return nil, core.NewDataBaseError(err)
+	}
+	return revisions, nil
+}
+
+func (r *messageRepository) FindCodeRevision(messageID, revision int) (core.CodeRevision, error) {
+	var codeRevision core.CodeRevision
+	_, err := r.db.QueryOne(&codeRevision,
+		`SELECT r.revision, coalesce(u.name, '') as author, r.revisiondate, r.title, r.language, r.filename, r.code, r.files
+		FROM code_revision r
+		LEFT JOIN public.user u ON u.id = r.userid
+		WHERE r.messageid = ? AND r.revision = ?;`, messageID, revision)
+	if err != nil && err == pg.ErrNoRows {
+		return core.CodeRevision{}, core.ErrRessourceDoesNotExist
+	}
+
 	if err != nil {
-		return core.NewDataBaseError(err)
+		return core.CodeRevision{}, core.NewDataBaseError(err)
 	}
+	return codeRevision, nil
+}
 
-	if res.RowsAffected() == 0 {
-		return core.ErrRessourceDoesNotExist
+// emptyIfNil keeps messages without further files from being stored as JSON null.
+func emptyIfNil(files []core.CodeFile) []core.CodeFile {
+	if files == nil {
+		return make([]core.CodeFile, 0)
 	}
-	return nil
+	return files
 }
 
 func (r *messageRepository) FindAllProgrammingLanguages() ([]core.ProgrammingLanguage, error) {
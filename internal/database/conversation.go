@@ -14,7 +14,7 @@ type conversationRepository struct {
 func (r *conversationRepository) FindConversationsForUser(user int) ([]core.Conversation, error) {
 	conversations := make([]core.Conversation, 0, 2)
 	_, err := r.db.Query(&conversations, `
-			SELECT id, title, repourl, calculateunreadmessages(id, ?) as unreadMessagesCount
+			SELECT id, title, repourl, retentiondays, calculateunreadmessages(id, ?) as unreadMessagesCount
 			FROM conversation c
 			JOIN group_association g on c.id = g.conversationid
 			WHERE userid = ? AND g.joined IS NOT NULL AND g.hasLeft = false;`, user, user)
@@ -38,7 +38,7 @@ func (r *conversationRepository) SetAdminState(userID, conversationID int, state
 func (r *conversationRepository) FindConversations() ([]core.Conversation, error) {
 	conversations := make([]core.Conversation, 0, 2)
 	_, err := r.db.Query(&conversations, `
-			SELECT id as ID, title as Title, repourl as Repourl FROM conversation;`)
+			SELECT id as ID, title as Title, repourl as Repourl, retentiondays FROM conversation;`)
 
 	return conversations, core.NewDataBaseError(err)
 }
@@ -126,12 +126,13 @@ func (r *conversationRepository) MarkAsJoined(userID int, conversationID int) (i
 
 func (r *conversationRepository) FindConversationForID(conversationID int) (core.Conversation, error) {
 	c := struct {
-		ID      int
-		Title   string
-		RepoURL string `pg:"repourl"`
+		ID            int
+		Title         string
+		RepoURL       string `pg:"repourl"`
+		RetentionDays *int   `pg:"retentiondays"`
 	}{}
 	_, err := r.db.QueryOne(&c,
-		`SELECT id, title, repourl FROM public.conversation WHERE id = ?;`,
+		`SELECT id, title, repourl, retentiondays FROM public.conversation WHERE id = ?;`,
 		conversationID)
 	if errors.Is(err, pg.ErrNoRows) {
 		return core.Conversation{}, core.ErrConversationDoesNotExist
@@ -142,12 +143,28 @@ func (r *conversationRepository) FindConversationForID(conversationID int) (core
 	}
 
 	return core.Conversation{
-		Title:   c.Title,
-		ID:      c.ID,
-		Repourl: c.RepoURL,
+		Title:         c.Title,
+		ID:            c.ID,
+		Repourl:       c.RepoURL,
+		RetentionDays: c.RetentionDays,
 	}, nil
 }
 
+// SetRetentionPeriod sets the number of days after which messages of the conversation are deleted.
+// Passing nil resets the conversation to the server default.
+func (r *conversationRepository) SetRetentionPeriod(conversationID int, days *int) error {
+	_, err := r.db.ExecOne(
+		`UPDATE public.conversation
+		SET retentiondays = ?
+		WHERE id = ?;`,
+		days, conversationID)
+	if err == pg.ErrNoRows {
+		return core.ErrConversationDoesNotExist
+	}
+
+	return core.NewDataBaseError(err)
+}
+
 func (r *conversationRepository) SetMetaDataOfConversation(conversation core.Conversation) error {
 	_, err := r.db.ExecOne(
 		`UPDATE public.conversation
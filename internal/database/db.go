@@ -15,6 +15,22 @@ func callFunction(db *pg.DB, name string, model interface{}, args ...interface{}
 	return db.QueryOne(model, constructQuery("SELECT", name, len(args)), args...)
 }
 
+// nullIfZero maps the zero value of an optional reference to NULL.
+func nullIfZero(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// nullIfEmpty maps an empty optional string to NULL.
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
 func constructQuery(verb string, callableName string, nArgs int) string {
 	var b strings.Builder
 	query := fmt.Sprintf("%s %s(", verb, callableName)
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"encoding/json"
+
+	"github.com/go-pg/pg/v9"
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+func (r *messageRepository) StoreDiffMessage(conversation, user int, m core.DiffMessage) (int, error) {
+	// Only the metadata of the files is stored, hunks are parsed from the patch on demand.
+	files := make([]core.DiffFile, len(m.Files))
+	for i, f := range m.Files {
+		f.Hunks = nil
+		files[i] = f
+	}
+
+	filesJSON, err := json.Marshal(files)
+	if err != nil {
+		return 0, err
+	}
+
+	var id = -1
+	_, err = callFunction(r.db, "createDiffMessage", &id,
+		user, conversation, m.Sentdate, m.Title, m.Patch, string(filesJSON), nullIfZero(m.ParentID))
+	if err != nil {
+		return 0, core.NewDataBaseError(err)
+	}
+
+	err = r.storeReferences(id, m.Message)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *messageRepository) FindDiffMessageForID(messageID, conversationID int) (core.DiffMessage, error) {
+	var message core.DiffMessage
+	_, err := r.db.QueryOne(&message,
+		`SELECT type, id, sentdate, author, title, files, additions, deletions,
+			parentid, replycount, reactions, isedited, ispinned, origin, quotedid, quote
+		FROM v_diff_message
+		WHERE id = ? AND conversationid = ?;`, messageID, conversationID)
+	if err != nil && err == pg.ErrNoRows {
+		return core.DiffMessage{}, core.ErrRessourceDoesNotExist
+	}
+
+	if err != nil {
+		return core.DiffMessage{}, core.NewDataBaseError(err)
+	}
+
+	return message, nil
+}
+
+func (r *messageRepository) FindDiffMessagesForConversation(
+	conversationID int,
+	window core.MessageRange) ([]interface{}, error) {
+
+	diffMessages := make([]core.DiffMessage, 0, 10)
+	_, err := r.db.Query(&diffMessages,
+		`SELECT type, id, sentdate, author, title, files, additions, deletions,
+			parentid, replycount, reactions, isedited, ispinned, origin, quotedid, quote
+		FROM v_diff_message
+		WHERE conversationid = ? AND id > ? AND id < ? AND parentid IS NULL
+		ORDER BY id ?
+		LIMIT ?;`, conversationID, window.AfterID, window.BeforeID, sortOrder(window), window.Limit)
+	if err != nil {
+		return make([]interface{}, 0), core.NewDataBaseError(err)
+	}
+
+	messagesI := make([]interface{}, len(diffMessages))
+	for i := range diffMessages {
+		messagesI[i] = diffMessages[i]
+	}
+
+	return messagesI, nil
+}
+
+func (r *messageRepository) FindPatchOfDiffMessage(messageID, conversationID int) (string, error) {
+	var patch string
+	_, err := r.db.QueryOne(&patch,
+		`SELECT d.patch
+		FROM diff_message d
+		JOIN message m ON m.id = d.id
+		WHERE d.id = ? AND m.conversationid = ?;`, messageID, conversationID)
+	if err != nil && err == pg.ErrNoRows {
+		return "", core.ErrRessourceDoesNotExist
+	}
+
+	if err != nil {
+		return "", core.NewDataBaseError(err)
+	}
+
+	return patch, nil
+}
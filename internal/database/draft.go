@@ -0,0 +1,63 @@
+package database
+
+import (
+	"github.com/go-pg/pg/v9"
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+// StoreDraft creates the draft of a user or replaces the existing one.
+func (r *messageRepository) StoreDraft(userID int, draft core.Draft) error {
+	_, err := r.db.Exec(
+		`INSERT INTO draft (userid, conversationid, parentid, text, updatedate)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (userid, conversationid) DO UPDATE
+		SET parentid = EXCLUDED.parentid, text = EXCLUDED.text, updatedate = EXCLUDED.updatedate;`,
+		userID, draft.ConversationID, nullIfZero(draft.ParentID), draft.Text, draft.UpdateDate.UTC())
+	return core.NewDataBaseError(err)
+}
+
+func (r *messageRepository) DeleteDraft(userID, conversationID int) error {
+	res, err := r.db.Exec(
+		`DELETE FROM draft WHERE userid = ? AND conversationid = ?;`, userID, conversationID)
+	if err != nil {
+		return core.NewDataBaseError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return core.ErrNothingChanged
+	}
+	return nil
+}
+
+func (r *messageRepository) FindDraft(userID, conversationID int) (core.Draft, error) {
+	var draft core.Draft
+	_, err := r.db.QueryOne(&draft,
+		`SELECT conversationid, parentid, text, updatedate
+		FROM draft
+		WHERE userid = ? AND conversationid = ?;`, userID, conversationID)
+	if err == pg.ErrNoRows {
+		return core.Draft{}, core.ErrRessourceDoesNotExist
+	}
+
+	if err != nil {
+		return core.Draft{}, core.NewDataBaseError(err)
+	}
+
+	return draft, nil
+}
+
+// FindDraftsForUser returns the drafts of all conversations the user is still a member of.
+func (r *messageRepository) FindDraftsForUser(userID int) ([]core.Draft, error) {
+	drafts := make([]core.Draft, 0, 5)
+	_, err := r.db.Query(&drafts,
+		`SELECT d.conversationid, d.parentid, d.text, d.updatedate
+		FROM draft d
+		JOIN group_association g ON g.userid = d.userid AND g.conversationid = d.conversationid
+		WHERE d.userid = ? AND g.joined IS NOT NULL AND g.hasleft = false
+		ORDER BY d.updatedate DESC;`, userID)
+	if err != nil {
+		return nil, core.NewDataBaseError(err)
+	}
+
+	return drafts, nil
+}
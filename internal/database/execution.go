@@ -0,0 +1,58 @@
+package database
+
+import (
+	"github.com/go-pg/pg/v9"
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+// StoreExecutionResult replaces the result of the previous execution of the message.
+func (r *messageRepository) StoreExecutionResult(result core.ExecutionResult) error {
+	_, err := r.db.Exec(
+		`INSERT INTO code_execution 
+			(messageid, revision, userid, language, exitcode, stdout, stderr, timedout, truncated, duration)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (messageid) DO UPDATE SET
+			revision = excluded.revision,
+			userid = excluded.userid,
+			executiondate = excluded.executiondate,
+			language = excluded.language,
+			exitcode = excluded.exitcode,
+			stdout = excluded.stdout,
+			stderr = excluded.stderr,
+			timedout = excluded.timedout,
+			truncated = excluded.truncated,
+			duration = excluded.duration;`,
+		result.MessageID,
+		result.Revision,
+		nullIfZero(result.UserID),
+		result.Language,
+		result.ExitCode,
+		result.Stdout,
+		result.Stderr,
+		result.TimedOut,
+		result.Truncated,
+		result.Duration)
+	if err != nil {
+		return core.NewDataBaseError(err)
+	}
+	return nil
+}
+
+func (r *messageRepository) FindExecutionResult(messageID int) (core.ExecutionResult, error) {
+	var result core.ExecutionResult
+	_, err := r.db.QueryOne(&result,
+		`SELECT 
+			e.messageid, e.revision, e.userid, coalesce(u.name, '') as executedby, e.executiondate, e.language, 
+			e.exitcode, e.stdout, e.stderr, e.timedout, e.truncated, e.duration
+		FROM code_execution e
+		LEFT JOIN public.user u ON u.id = e.userid
+		WHERE e.messageid = ?;`, messageID)
+	if err != nil && err == pg.ErrNoRows {
+		return core.ExecutionResult{}, core.ErrRessourceDoesNotExist
+	}
+
+	if err != nil {
+		return core.ExecutionResult{}, core.NewDataBaseError(err)
+	}
+	return result, nil
+}
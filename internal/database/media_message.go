@@ -9,11 +9,16 @@ import (
 
 func (r *messageRepository) StoreMediaMessage(conversation, user int, m core.MediaMessage) (int, error) {
 	var id = -1
-	_, err := callFunction(r.db, "createMediaMessage", &id, user, conversation, m.Sentdate, m.Text)
+	_, err := callFunction(r.db, "createMediaMessage", &id, user, conversation, m.Sentdate, m.Text, nullIfZero(m.ParentID))
 	if err != nil {
 		return 0, core.NewDataBaseError(err)
 	}
 
+	err = r.storeReferences(id, m.Message)
+	if err != nil {
+		return 0, err
+	}
+
 	return id, nil
 }
 
@@ -31,30 +36,64 @@ func (r *messageRepository) CreateMediaObject(messageID int, name, fileType stri
 	return mediaID, nil
 }
 
+// CopyMediaObject adds a media object to a message that shares the file of an existing media object.
+func (r *messageRepository) CopyMediaObject(messageID int, obj core.MediaObject) (int, error) {
+	var mediaID int
+	_, err := r.db.QueryOne(&mediaID,
+		`INSERT INTO media_object(message, name, filetype, meta, fileid)
+		VALUES(?, ?, ?, ?, ?)
+		RETURNING id;`, messageID, obj.Name, obj.MIMEType, nullIfEmpty(string(obj.Meta)), obj.FileID)
+
+	if err != nil {
+		return 0, core.NewDataBaseError(err)
+	}
+
+	return mediaID, nil
+}
+
+// FindUnreferencedFiles returns the passed file ids that are not used by any media object anymore.
+func (r *messageRepository) FindUnreferencedFiles(fileIDs []int) ([]int, error) {
+	unreferenced := make([]int, 0, len(fileIDs))
+	if len(fileIDs) == 0 {
+		return unreferenced, nil
+	}
+
+	_, err := r.db.Query(&unreferenced,
+		`SELECT f.id
+		FROM unnest(?::integer[]) AS f(id)
+		WHERE NOT EXISTS (
+			SELECT 1 FROM media_object mo WHERE coalesce(mo.fileid, mo.id) = f.id
+		);`, pg.Array(fileIDs))
+	if err != nil {
+		return nil, core.NewDataBaseError(err)
+	}
+
+	return unreferenced, nil
+}
+
 func (r *messageRepository) FindMediaMessagesForConversation(
 	conversationID int,
-	beforeInSequence int,
-	limit int) ([]interface{}, error) {
+	window core.MessageRange) ([]interface{}, error) {
 
 	mediaMessages := make([]core.MediaMessage, 0, 10)
 	_, err := r.db.Query(&mediaMessages,
-		`SELECT type, m.id, sentdate, author, Text
+		`SELECT type, m.id, sentdate, author, Text, parentid, replycount, reactions, isedited, ispinned, origin, quotedid, quote
 		FROM v_media_message m
-		WHERE conversationid = ? AND id < ? AND m.iscomplete = true
-		ORDER BY id desc
-		LIMIT ?;`, conversationID, beforeInSequence, limit)
+		WHERE conversationid = ? AND id > ? AND id < ? AND m.iscomplete = true AND parentid IS NULL
+		ORDER BY id ?
+		LIMIT ?;`, conversationID, window.AfterID, window.BeforeID, sortOrder(window), window.Limit)
 	if err != nil {
 		return make([]interface{}, 0), core.NewDataBaseError(err)
 	}
 
-	for _, message := range mediaMessages {
+	for i := range mediaMessages {
 		mediaObjects := make([]core.MediaObject, 0)
 		_, err = r.db.Query(&mediaObjects,
-			`SELECT name, id, filetype, meta FROM media_object WHERE message = ?;`, message.ID)
+			`SELECT name, id, filetype, meta FROM media_object WHERE message = ?;`, mediaMessages[i].ID)
 		if err != nil {
 			return make([]interface{}, 0), core.NewDataBaseError(err)
 		}
-		message.Files = mediaObjects
+		mediaMessages[i].Files = mediaObjects
 	}
 
 	messagesI := make([]interface{}, len(mediaMessages))
@@ -68,7 +107,7 @@ func (r *messageRepository) FindMediaMessagesForConversation(
 func (r *messageRepository) FindMediaMessageForID(messageID, conversationID int) (core.MediaMessage, error) {
 	var message core.MediaMessage
 	_, err := r.db.QueryOne(&message,
-		`SELECT type, id, sentdate, author, text
+		`SELECT type, id, sentdate, author, text, parentid, replycount, reactions, isedited, ispinned, origin, quotedid, quote
 		FROM v_media_message
 		WHERE id = ? AND conversationid = ?;`, messageID, conversationID)
 	if err != nil && err == pg.ErrNoRows {
@@ -105,7 +144,7 @@ func (r *messageRepository) SetMetaOfMediaMessage(id int, meta interface{}) erro
 func (r *messageRepository) FindMediaObjectForID(id, conversationID int) (core.MediaObject, error) {
 	var obj core.MediaObject
 	_, err := r.db.QueryOne(&obj,
-		`SELECT mo.filetype, mo.name, mo.id, mo.meta
+		`SELECT mo.filetype, mo.name, mo.id, mo.meta, coalesce(mo.fileid, mo.id) as fileid
 		FROM media_object mo
 		JOIN v_media_message m ON m.id = mo.message
 		WHERE mo.id = ? AND m.conversationid = ?;`, id, conversationID)
@@ -115,3 +154,13 @@ func (r *messageRepository) FindMediaObjectForID(id, conversationID int) (core.M
 
 	return obj, core.NewDataBaseError(err)
 }
+
+func (r *messageRepository) FindMediaObjectsForMessage(messageID int) ([]core.MediaObject, error) {
+	mediaObjects := make([]core.MediaObject, 0)
+	_, err := r.db.Query(&mediaObjects,
+		`SELECT name, id, filetype, meta, coalesce(fileid, id) as fileid FROM media_object WHERE message = ?;`, messageID)
+	if err != nil {
+		return nil, core.NewDataBaseError(err)
+	}
+	return mediaObjects, nil
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"github.com/go-pg/pg/v9"
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+func (r *messageRepository) StoreMentions(messageID int, userIDs []int) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	_, err := r.db.Exec(
+		`INSERT INTO mention (messageid, userid)
+		SELECT ?, unnest(?::integer[])
+		ON CONFLICT DO NOTHING;`, messageID, pg.Array(userIDs))
+	if err != nil {
+		return core.NewDataBaseError(err)
+	}
+	return nil
+}
+
+// FindMentionsForUser returns the messages the user was mentioned in. Mentions in
+// conversations the user has left are omitted.
+func (r *messageRepository) FindMentionsForUser(userID, beforeInSequence, limit int) ([]core.Mention, error) {
+	mentions := make([]core.Mention, 0, limit)
+	_, err := r.db.Query(&mentions,
+		`SELECT 
+			m.id as messageid, m.conversationid, m.type, u.name as author, m.sentdate,
+			coalesce(t.text, c.title, mm.text, '') as preview, 
+			coalesce(s.hasread, false) as hasread
+		FROM mention mn
+		JOIN message m ON m.id = mn.messageid
+		JOIN v_joined_member j ON j.conversationid = m.conversationid AND j.userid = mn.userid
+		LEFT JOIN public.user u ON u.id = m.userid
+		LEFT JOIN text_message t ON t.id = m.id
+		LEFT JOIN code_message c ON c.id = m.id
+		LEFT JOIN media_message mm ON mm.id = m.id
+		LEFT JOIN message_status s ON s.messageid = m.id AND s.userid = mn.userid
+		WHERE mn.userid = ? AND m.id < ?
+		ORDER BY m.id desc
+		LIMIT ?;`, userID, beforeInSequence, limit)
+	if err != nil {
+		return nil, core.NewDataBaseError(err)
+	}
+	return mentions, nil
+}
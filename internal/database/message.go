@@ -2,6 +2,7 @@ package database
 
 import (
 	"sort"
+	"time"
 
 	"github.com/go-pg/pg/v9"
 	core "github.com/miphilipp/devchat-server/internal"
@@ -11,14 +12,75 @@ type messageRepository struct {
 	db *pg.DB
 }
 
-func (r *messageRepository) SetReadFlags(userid int, conversationID int) error {
+// SetReadFlags marks the messages of a conversation up to the passed message as read.
+// If upToMessageID is 0, all messages are marked. It returns the id of the newest message
+// that has been marked or 0 if every message had been read already.
+func (r *messageRepository) SetReadFlags(userID, conversationID, upToMessageID int, readDate time.Time) (int, error) {
+	var lastReadID int
+	_, err := r.db.QueryOne(pg.Scan(&lastReadID),
+		`WITH updated AS (
+			UPDATE message_status
+			SET hasread = true, readdate = ?
+			WHERE userid = ? AND conversationid = ? AND hasread = false AND (? = 0 OR messageid <= ?)
+			RETURNING messageid
+		)
+		SELECT coalesce(max(messageid), 0) FROM updated;`,
+		readDate.UTC(), userID, conversationID, upToMessageID, upToMessageID)
+	if err != nil {
+		return 0, core.NewDataBaseError(err)
+	}
+
+	return lastReadID, nil
+}
+
+// FindReadersOfMessage returns every member except the author that has read the message.
+func (r *messageRepository) FindReadersOfMessage(messageID int) ([]core.MessageReader, error) {
+	readers := make([]core.MessageReader, 0, 5)
+	_, err := r.db.Query(&readers,
+		`SELECT s.userid, u.name, s.readdate
+		FROM message_status s
+		JOIN message m ON m.id = s.messageid
+		JOIN public.user u ON u.id = s.userid
+		WHERE s.messageid = ? AND s.hasread = true AND s.userid != m.userid
+		ORDER BY s.readdate NULLS FIRST, u.name;`, messageID)
+	if err != nil {
+		return nil, core.NewDataBaseError(err)
+	}
+
+	return readers, nil
+}
+
+// storeReferences records the message quoted by a new message and the origin of a forwarded message.
+func (r *messageRepository) storeReferences(messageID int, m core.Message) error {
+	if m.QuotedID == 0 && m.Origin == nil {
+		return nil
+	}
+
 	_, err := r.db.Exec(
-		`UPDATE message_status 
-		 SET hasRead = true 
-		 WHERE userid = ? AND conversationid = ?;`, userid, conversationID)
+		`UPDATE public.message SET quotedid = ?, origin = ? WHERE id = ?;`,
+		nullIfZero(m.QuotedID), m.Origin, messageID)
 	return core.NewDataBaseError(err)
 }
 
+// FindMessagePreview returns a short summary of a message of the conversation.
+func (r *messageRepository) FindMessagePreview(conversationID, messageID int) (core.MessagePreview, error) {
+	var preview core.MessagePreview
+	_, err := r.db.QueryOne(pg.Scan(&preview),
+		`SELECT p.preview
+		FROM v_message_preview p
+		JOIN message m ON m.id = p.id
+		WHERE m.conversationid = ? AND m.id = ?;`, conversationID, messageID)
+	if err == pg.ErrNoRows {
+		return core.MessagePreview{}, core.ErrRessourceDoesNotExist
+	}
+
+	if err != nil {
+		return core.MessagePreview{}, core.NewDataBaseError(err)
+	}
+
+	return preview, nil
+}
+
 func containsID(stubs []messageStub, id int) bool {
 	for _, s := range stubs {
 		if s.ID == id {
@@ -28,18 +90,30 @@ func containsID(stubs []messageStub, id int) bool {
 	return false
 }
 
-func getLargestID(arr []messageStub) int {
+func getIDBounds(arr []messageStub) (int, int) {
 	if len(arr) == 0 {
-		return 0
+		return 0, 0
 	}
 
-	largestID := arr[0].ID
+	smallestID, largestID := arr[0].ID, arr[0].ID
 	for _, s := range arr {
+		if s.ID < smallestID {
+			smallestID = s.ID
+		}
 		if s.ID > largestID {
 			largestID = s.ID
 		}
 	}
-	return largestID
+	return smallestID, largestID
+}
+
+// sortOrder returns the order in which the messages of the range have to be selected
+// so that the limit cuts off the messages farthest from the anchor.
+func sortOrder(window core.MessageRange) pg.Safe {
+	if window.Forward {
+		return pg.Safe("asc")
+	}
+	return pg.Safe("desc")
 }
 
 type messageStub struct {
@@ -49,54 +123,53 @@ type messageStub struct {
 
 func (r *messageRepository) FindForConversation(
 	conversationID int,
-	beforeInSequence int,
-	limit int) ([]interface{}, error) {
+	window core.MessageRange) ([]interface{}, int, error) {
 	stubs := make([]messageStub, 0, 10)
 	_, err := r.db.Query(&stubs,
 		`SELECT type, id
 		FROM message
-		WHERE conversationid = ? AND id < ? AND iscomplete = true
-		ORDER BY id desc
-		LIMIT ?;`, conversationID, beforeInSequence, limit)
+		WHERE conversationid = ? AND id > ? AND id < ? AND iscomplete = true AND parentid IS NULL
+		ORDER BY id ?
+		LIMIT ?;`, conversationID, window.AfterID, window.BeforeID, sortOrder(window), window.Limit)
 	if err != nil {
-		return make([]interface{}, 0), core.NewDataBaseError(err)
+		return make([]interface{}, 0), 0, core.NewDataBaseError(err)
 	}
 
-	var largestID = getLargestID(stubs)
+	smallestID, largestID := getIDBounds(stubs)
 	codeMessages := make([]core.CodeMessage, 0, 10)
 	_, err = r.db.Query(&codeMessages,
-		`SELECT type, id, sentdate, author, code, language, title, lockedby
+		`SELECT type, id, sentdate, author, code, language, title, filename, files, lockedby, revision, parentid, replycount, reactions, isedited, ispinned, origin, quotedid, quote
 		FROM v_code_message
-		WHERE conversationid = ? AND id <= ? AND id < ?
+		WHERE conversationid = ? AND id BETWEEN ? AND ? AND parentid IS NULL
 		ORDER BY id desc
-		LIMIT ?;`, conversationID, largestID, beforeInSequence, limit)
+		LIMIT ?;`, conversationID, smallestID, largestID, window.Limit)
 	if err != nil {
-		return make([]interface{}, 0), core.NewDataBaseError(err)
+		return make([]interface{}, 0), 0, core.NewDataBaseError(err)
 	}
 
 	textMessages := make([]core.TextMessage, 0, 10)
 	_, err = r.db.Query(&textMessages,
-		`SELECT type, id, sentdate, author, text
+		`SELECT type, id, sentdate, author, text, html, plaintext, parentid, replycount, reactions, isedited, ispinned, origin, quotedid, quote
 		FROM v_text_message
-		WHERE conversationid = ? AND id <= ? AND id < ?
+		WHERE conversationid = ? AND id BETWEEN ? AND ? AND parentid IS NULL
 		ORDER BY id desc
-		LIMIT ?;`, conversationID, largestID, beforeInSequence, limit)
+		LIMIT ?;`, conversationID, smallestID, largestID, window.Limit)
 	if err != nil {
-		return make([]interface{}, 0), core.NewDataBaseError(err)
+		return make([]interface{}, 0), 0, core.NewDataBaseError(err)
 	}
 
 	mediaMessages := make([]core.MediaMessage, 0, 10)
 	_, err = r.db.Query(&mediaMessages,
-		`SELECT m.type, m.id, m.sentdate, m.author, m.text
+		`SELECT m.type, m.id, m.sentdate, m.author, m.text, m.parentid, m.replycount, m.reactions, m.isedited, m.ispinned, m.origin, m.quotedid, m.quote
 		FROM v_media_message m
 		JOIN media_object mo ON mo.message = m.id
-		WHERE m.conversationid = ? AND m.id <= ? AND m.id < ? AND m.iscomplete = true
-		GROUP BY m.id, m.sentdate, m.author, m.text, m.type
+		WHERE m.conversationid = ? AND m.id BETWEEN ? AND ? AND m.iscomplete = true AND m.parentid IS NULL
+		GROUP BY m.id, m.sentdate, m.author, m.text, m.type, m.parentid, m.replycount, m.reactions, m.isedited, m.ispinned, m.origin, m.quotedid, m.quote
 		HAVING COUNT(mo.message) > 0
 		ORDER BY id desc
-		LIMIT ?;`, conversationID, largestID, beforeInSequence, limit)
+		LIMIT ?;`, conversationID, smallestID, largestID, window.Limit)
 	if err != nil {
-		return make([]interface{}, 0), core.NewDataBaseError(err)
+		return make([]interface{}, 0), 0, core.NewDataBaseError(err)
 	}
 
 	for i := range mediaMessages {
@@ -104,13 +177,38 @@ func (r *messageRepository) FindForConversation(
 		_, err = r.db.Query(&mediaObjects,
 			`SELECT name, id, filetype, meta FROM media_object WHERE message = ?;`, mediaMessages[i].ID)
 		if err != nil {
-			return make([]interface{}, 0), core.NewDataBaseError(err)
+			return make([]interface{}, 0), 0, core.NewDataBaseError(err)
 		}
 		mediaMessages[i].Files = make([]core.MediaObject, len(mediaObjects))
 		copy(mediaMessages[i].Files, mediaObjects)
 	}
 
-	messages := make([]interface{ core.Sequencable }, 0, len(codeMessages)+len(textMessages)+len(mediaMessages))
+	pollMessages := make([]core.PollMessage, 0, 10)
+	_, err = r.db.Query(&pollMessages,
+		`SELECT type, id, sentdate, author, question, options, ismultiplechoice, isanonymous, closedate, isclosed,
+			parentid, replycount, reactions, isedited, ispinned, origin, quotedid, quote
+		FROM v_poll_message
+		WHERE conversationid = ? AND id BETWEEN ? AND ? AND parentid IS NULL
+		ORDER BY id desc
+		LIMIT ?;`, conversationID, smallestID, largestID, window.Limit)
+	if err != nil {
+		return make([]interface{}, 0), 0, core.NewDataBaseError(err)
+	}
+
+	diffMessages := make([]core.DiffMessage, 0, 10)
+	_, err = r.db.Query(&diffMessages,
+		`SELECT type, id, sentdate, author, title, files, additions, deletions,
+			parentid, replycount, reactions, isedited, ispinned, origin, quotedid, quote
+		FROM v_diff_message
+		WHERE conversationid = ? AND id BETWEEN ? AND ? AND parentid IS NULL
+		ORDER BY id desc
+		LIMIT ?;`, conversationID, smallestID, largestID, window.Limit)
+	if err != nil {
+		return make([]interface{}, 0), 0, core.NewDataBaseError(err)
+	}
+
+	messages := make([]interface{ core.Sequencable }, 0,
+		len(codeMessages)+len(textMessages)+len(mediaMessages)+len(pollMessages)+len(diffMessages))
 	for _, m := range codeMessages {
 		if containsID(stubs, m.ID) {
 			messages = append(messages, m)
@@ -127,6 +225,16 @@ func (r *messageRepository) FindForConversation(
 			messages = append(messages, m)
 		}
 	}
+	for _, m := range pollMessages {
+		if containsID(stubs, m.ID) {
+			messages = append(messages, m)
+		}
+	}
+	for _, m := range diffMessages {
+		if containsID(stubs, m.ID) {
+			messages = append(messages, m)
+		}
+	}
 
 	sort.Slice(messages, func(i, j int) bool {
 		return messages[i].GetSequenceNumber() < messages[j].GetSequenceNumber()
@@ -137,13 +245,34 @@ func (r *messageRepository) FindForConversation(
 		messagesI[i] = messages[i]
 	}
 
-	return messagesI, nil
+	return messagesI, len(stubs), nil
+}
+
+// HasMessagesInRange reports whether the conversation contains top level messages of the
+// given type within the range. UndefinedMesssageType matches messages of any type.
+func (r *messageRepository) HasMessagesInRange(
+	conversationID int,
+	window core.MessageRange,
+	mType core.MessageType) (bool, error) {
+
+	var exists bool
+	_, err := r.db.QueryOne(pg.Scan(&exists),
+		`SELECT EXISTS (
+			SELECT 1 FROM message
+			WHERE conversationid = ? AND id > ? AND id < ? AND iscomplete = true AND parentid IS NULL
+				AND (? = -1 OR type = ?)
+		);`, conversationID, window.AfterID, window.BeforeID, mType, mType)
+	if err != nil {
+		return false, core.NewDataBaseError(err)
+	}
+
+	return exists, nil
 }
 
 func (r *messageRepository) FindMessageStubForConversation(conversationID int, messageID int) (core.Message, error) {
 	var message core.Message
 	_, err := r.db.QueryOne(&message,
-		`SELECT m.type, m.id, m.sentdate, u.name as Author
+		`SELECT m.type, m.id, m.sentdate, u.name as Author, m.parentid, m.userid
 		FROM message m
 		JOIN public.user u ON m.userid = u.id
 		WHERE m.conversationid = ? AND m.id = ?;`, conversationID, messageID)
@@ -172,8 +301,8 @@ func (r *messageRepository) SetLockedSateForCodeMessage(messageID, lockingUserID
 	}
 	res, err := r.db.Exec(
 		`UPDATE public.code_message
-		 SET lockedby = ? 
-		 WHERE id = ?;`, id, messageID)
+		 SET lockedby = ?, lockdate = CASE WHEN ?::bigint IS NULL THEN NULL ELSE current_timestamp at time zone 'utc' END
+		 WHERE id = ?;`, id, id, messageID)
 	if err != nil {
 		return core.NewDataBaseError(err)
 	}
@@ -185,14 +314,282 @@ func (r *messageRepository) SetLockedSateForCodeMessage(messageID, lockingUserID
 	return nil
 }
 
+// ReleaseLock removes the lock of a code message, but only if it is still held by the passed user.
+func (r *messageRepository) ReleaseLock(messageID, holderID int) error {
+	res, err := r.db.Exec(
+		`UPDATE public.code_message
+		 SET lockedby = NULL, lockdate = NULL
+		 WHERE id = ? AND lockedby = ?;`, messageID, holderID)
+	if err != nil {
+		return core.NewDataBaseError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return core.ErrNothingChanged
+	}
+	return nil
+}
+
+// TransferLock hands the lock of a code message over to another user, but only if it is
+// still held by holderID.
+func (r *messageRepository) TransferLock(messageID, holderID, newHolderID int) error {
+	res, err := r.db.Exec(
+		`UPDATE public.code_message
+		 SET lockedby = ?, lockdate = current_timestamp at time zone 'utc'
+		 WHERE id = ? AND lockedby = ?;`, newHolderID, messageID, holderID)
+	if err != nil {
+		return core.NewDataBaseError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return core.ErrEditConflict
+	}
+	return nil
+}
+
+// FindLocks returns all locked code messages. If userID is not 0, only the locks of that user
+// are returned. If idleSince is not the zero time, only locks without activity since then are returned.
+func (r *messageRepository) FindLocks(userID int, idleSince time.Time) ([]core.CodeLock, error) {
+	var idle interface{}
+	if !idleSince.IsZero() {
+		idle = idleSince
+	}
+
+	locks := make([]core.CodeLock, 0, 5)
+	_, err := r.db.Query(&locks,
+		`SELECT c.id, m.conversationid, c.lockedby, c.lockdate
+		FROM code_message c
+		JOIN message m ON m.id = c.id
+		WHERE c.lockedby IS NOT NULL AND
+			(? = 0 OR c.lockedby = ?) AND
+			(?::timestamp IS NULL OR c.lockdate IS NULL OR c.lockdate < ?);`,
+		userID, userID, idle, idle)
+	if err != nil {
+		return nil, core.NewDataBaseError(err)
+	}
+	return locks, nil
+}
+
 func (r *messageRepository) DeleteMessage(id int) error {
 	_, err := r.db.Exec(
 		`DELETE FROM public.message WHERE id = ?;`, id)
 	return core.NewDataBaseError(err)
 }
 
+func (r *messageRepository) DeleteMessages(ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := r.db.Exec(
+		`DELETE FROM public.message WHERE id IN (?);`, pg.In(ids))
+	return core.NewDataBaseError(err)
+}
+
+// FindMessagesSentBefore returns the oldest messages of a conversation that were sent before the passed date.
+func (r *messageRepository) FindMessagesSentBefore(conversationID int, sentBefore time.Time, limit int) ([]core.Message, error) {
+	messages := make([]core.Message, 0, 10)
+	_, err := r.db.Query(&messages,
+		`SELECT m.type, m.id, m.sentdate, u.name as Author, m.parentid, m.userid
+		FROM message m
+		JOIN public.user u ON m.userid = u.id
+		WHERE m.conversationid = ? AND m.sentdate < ?
+		ORDER BY m.sentdate
+		LIMIT ?;`, conversationID, sentBefore.UTC(), limit)
+	if err != nil {
+		return nil, core.NewDataBaseError(err)
+	}
+
+	return messages, nil
+}
+
+// FindReplyStubs returns the replies to all of the passed messages.
+func (r *messageRepository) FindReplyStubs(parentIDs []int) ([]core.Message, error) {
+	messages := make([]core.Message, 0, 10)
+	if len(parentIDs) == 0 {
+		return messages, nil
+	}
+
+	_, err := r.db.Query(&messages,
+		`SELECT m.type, m.id, m.sentdate, u.name as Author, m.parentid, m.userid
+		FROM message m
+		JOIN public.user u ON m.userid = u.id
+		WHERE m.parentid IN (?)
+		ORDER BY m.sentdate;`, pg.In(parentIDs))
+	if err != nil {
+		return nil, core.NewDataBaseError(err)
+	}
+
+	return messages, nil
+}
+
 func (r *messageRepository) UpdateCompleteFlag(id int) error {
 	_, err := r.db.Exec(
 		`UPDATE public.message SET iscomplete = true WHERE id = ?;`, id)
 	return core.NewDataBaseError(err)
 }
+
+func (r *messageRepository) FindRepliesForMessage(conversationID, messageID int) ([]interface{}, error) {
+	stubs := make([]messageStub, 0, 10)
+	_, err := r.db.Query(&stubs,
+		`SELECT type, id
+		FROM message
+		WHERE conversationid = ? AND parentid = ? AND iscomplete = true
+		ORDER BY id;`, conversationID, messageID)
+	if err != nil {
+		return make([]interface{}, 0), core.NewDataBaseError(err)
+	}
+
+	return r.findMessagesForStubs(conversationID, stubs)
+}
+
+// FindMessagesAfter returns the messages of a conversation including all replies in
+// ascending order, starting after the passed message.
+func (r *messageRepository) FindMessagesAfter(conversationID, afterID, limit int) ([]interface{}, error) {
+	stubs := make([]messageStub, 0, limit)
+	_, err := r.db.Query(&stubs,
+		`SELECT type, id
+		FROM message
+		WHERE conversationid = ? AND id > ? AND iscomplete = true
+		ORDER BY id
+		LIMIT ?;`, conversationID, afterID, limit)
+	if err != nil {
+		return make([]interface{}, 0), core.NewDataBaseError(err)
+	}
+
+	return r.findMessagesForStubs(conversationID, stubs)
+}
+
+func (r *messageRepository) CountReplies(messageID int) (int, error) {
+	var count int
+	_, err := r.db.QueryOne(&count,
+		`SELECT count(*) FROM message WHERE parentid = ? AND iscomplete = true;`, messageID)
+	if err != nil {
+		return 0, core.NewDataBaseError(err)
+	}
+	return count, nil
+}
+
+// findMessagesForStubs loads the complete messages described by the passed stubs
+// and returns them in ascending order.
+func (r *messageRepository) findMessagesForStubs(conversationID int, stubs []messageStub) ([]interface{}, error) {
+	ids := make(map[core.MessageType][]int)
+	for _, s := range stubs {
+		ids[s.Type] = append(ids[s.Type], s.ID)
+	}
+
+	messages := make([]interface{ core.Sequencable }, 0, len(stubs))
+	if codeIDs := ids[core.CodeMessageType]; len(codeIDs) > 0 {
+		codeMessages := make([]core.CodeMessage, 0, len(codeIDs))
+		_, err := r.db.Query(&codeMessages,
+			`SELECT type, id, sentdate, author, code, language, title, filename, files, lockedby, revision, parentid, replycount, reactions, isedited, ispinned, origin, quotedid, quote
+			FROM v_code_message
+			WHERE conversationid = ? AND id IN (?);`, conversationID, pg.In(codeIDs))
+		if err != nil {
+			return make([]interface{}, 0), core.NewDataBaseError(err)
+		}
+
+		for _, m := range codeMessages {
+			messages = append(messages, m)
+		}
+	}
+
+	if textIDs := ids[core.TextMessageType]; len(textIDs) > 0 {
+		textMessages := make([]core.TextMessage, 0, len(textIDs))
+		_, err := r.db.Query(&textMessages,
+			`SELECT type, id, sentdate, author, text, html, plaintext, parentid, replycount, reactions, isedited, ispinned, origin, quotedid, quote
+			FROM v_text_message
+			WHERE conversationid = ? AND id IN (?);`, conversationID, pg.In(textIDs))
+		if err != nil {
+			return make([]interface{}, 0), core.NewDataBaseError(err)
+		}
+
+		for _, m := range textMessages {
+			messages = append(messages, m)
+		}
+	}
+
+	if mediaIDs := ids[core.MediaMessageType]; len(mediaIDs) > 0 {
+		mediaMessages := make([]core.MediaMessage, 0, len(mediaIDs))
+		_, err := r.db.Query(&mediaMessages,
+			`SELECT type, id, sentdate, author, text, parentid, replycount, reactions, isedited, ispinned, origin, quotedid, quote
+			FROM v_media_message
+			WHERE conversationid = ? AND id IN (?) AND iscomplete = true;`, conversationID, pg.In(mediaIDs))
+		if err != nil {
+			return make([]interface{}, 0), core.NewDataBaseError(err)
+		}
+
+		for i := range mediaMessages {
+			mediaObjects := make([]core.MediaObject, 0)
+			_, err = r.db.Query(&mediaObjects,
+				`SELECT name, id, filetype, meta FROM media_object WHERE message = ?;`, mediaMessages[i].ID)
+			if err != nil {
+				return make([]interface{}, 0), core.NewDataBaseError(err)
+			}
+			mediaMessages[i].Files = mediaObjects
+			messages = append(messages, mediaMessages[i])
+		}
+	}
+
+	if pollIDs := ids[core.PollMessageType]; len(pollIDs) > 0 {
+		pollMessages := make([]core.PollMessage, 0, len(pollIDs))
+		_, err := r.db.Query(&pollMessages,
+			`SELECT type, id, sentdate, author, question, options, ismultiplechoice, isanonymous, closedate, isclosed,
+				parentid, replycount, reactions, isedited, ispinned, origin, quotedid, quote
+			FROM v_poll_message
+			WHERE conversationid = ? AND id IN (?);`, conversationID, pg.In(pollIDs))
+		if err != nil {
+			return make([]interface{}, 0), core.NewDataBaseError(err)
+		}
+
+		for _, m := range pollMessages {
+			messages = append(messages, m)
+		}
+	}
+
+	if diffIDs := ids[core.DiffMessageType]; len(diffIDs) > 0 {
+		diffMessages := make([]core.DiffMessage, 0, len(diffIDs))
+		_, err := r.db.Query(&diffMessages,
+			`SELECT type, id, sentdate, author, title, files, additions, deletions,
+				parentid, replycount, reactions, isedited, ispinned, origin, quotedid, quote
+			FROM v_diff_message
+			WHERE conversationid = ? AND id IN (?);`, conversationID, pg.In(diffIDs))
+		if err != nil {
+			return make([]interface{}, 0), core.NewDataBaseError(err)
+		}
+
+		for _, m := range diffMessages {
+			messages = append(messages, m)
+		}
+	}
+
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].GetSequenceNumber() < messages[j].GetSequenceNumber()
+	})
+
+	messagesI := make([]interface{}, len(messages))
+	for i := range messages {
+		messagesI[i] = messages[i]
+	}
+
+	return messagesI, nil
+}
+
+func (r *messageRepository) UpdateMessageText(messageID, userID int, text, html, plainText string) error {
+	_, err := callStoredProcedure(r.db, "editMessageText", messageID, userID, text, html, plainText)
+	return core.NewDataBaseError(err)
+}
+
+func (r *messageRepository) FindEditHistory(messageID int) ([]core.MessageEdit, error) {
+	edits := make([]core.MessageEdit, 0, 5)
+	_, err := r.db.Query(&edits,
+		`SELECT e.id, coalesce(u.name, '') as editor, e.editdate, e.text
+		FROM message_edit e
+		LEFT JOIN public.user u ON u.id = e.userid
+		WHERE e.messageid = ?
+		ORDER BY e.id;`, messageID)
+	if err != nil {
+		return nil, core.NewDataBaseError(err)
+	}
+	return edits, nil
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"github.com/go-pg/pg/v9"
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+func (r *messageRepository) StorePin(conversationID, messageID, userID int) error {
+	res, err := r.db.Exec(
+		`INSERT INTO pinned_message (messageid, conversationid, userid)
+		SELECT id, conversationid, ?
+		FROM message
+		WHERE id = ? AND conversationid = ?
+		ON CONFLICT DO NOTHING;`, userID, messageID, conversationID)
+	if err != nil {
+		return core.NewDataBaseError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return core.ErrAlreadyExists
+	}
+	return nil
+}
+
+func (r *messageRepository) DeletePin(conversationID, messageID int) error {
+	res, err := r.db.Exec(
+		`DELETE FROM pinned_message 
+		WHERE messageid = ? AND conversationid = ?;`, messageID, conversationID)
+	if err != nil {
+		return core.NewDataBaseError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return core.ErrNothingChanged
+	}
+	return nil
+}
+
+// FindPinsForConversation returns all pins of a conversation including the pinned messages.
+// The most recent pin comes first.
+func (r *messageRepository) FindPinsForConversation(conversationID int) ([]core.Pin, error) {
+	pins := make([]core.Pin, 0, 5)
+	_, err := r.db.Query(&pins,
+		`SELECT p.messageid, coalesce(u.name, '') as pinnedby, p.pindate
+		FROM pinned_message p
+		LEFT JOIN public.user u ON u.id = p.userid
+		WHERE p.conversationid = ?
+		ORDER BY p.pindate desc;`, conversationID)
+	if err != nil {
+		return nil, core.NewDataBaseError(err)
+	}
+
+	if len(pins) == 0 {
+		return pins, nil
+	}
+
+	ids := make([]int, len(pins))
+	for i, p := range pins {
+		ids[i] = p.MessageID
+	}
+
+	stubs := make([]messageStub, 0, len(pins))
+	_, err = r.db.Query(&stubs,
+		`SELECT type, id FROM message WHERE id IN (?);`, pg.In(ids))
+	if err != nil {
+		return nil, core.NewDataBaseError(err)
+	}
+
+	messages, err := r.findMessagesForStubs(conversationID, stubs)
+	if err != nil {
+		return nil, err
+	}
+
+	messagesByID := make(map[int]interface{}, len(messages))
+	for _, m := range messages {
+		messagesByID[m.(core.Sequencable).GetSequenceNumber()] = m
+	}
+
+	for i := range pins {
+		pins[i].Message = messagesByID[pins[i].MessageID]
+	}
+	return pins, nil
+}
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"github.com/go-pg/pg/v9"
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+func (r *messageRepository) StorePollMessage(conversation, user int, m core.PollMessage) (int, error) {
+	options := make([]string, len(m.Options))
+	for i, o := range m.Options {
+		options[i] = o.Text
+	}
+
+	var id = -1
+	_, err := callFunction(r.db, "createPollMessage", &id,
+		user, conversation, m.Sentdate, m.Question, pg.Array(options),
+		m.IsMultipleChoice, m.IsAnonymous, m.CloseDate, nullIfZero(m.ParentID))
+	if err != nil {
+		return 0, core.NewDataBaseError(err)
+	}
+
+	err = r.storeReferences(id, m.Message)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *messageRepository) FindPollMessageForID(messageID, conversationID int) (core.PollMessage, error) {
+	var message core.PollMessage
+	_, err := r.db.QueryOne(&message,
+		`SELECT type, id, sentdate, author, question, options, ismultiplechoice, isanonymous, closedate, isclosed,
+			parentid, replycount, reactions, isedited, ispinned, origin, quotedid, quote
+		FROM v_poll_message
+		WHERE id = ? AND conversationid = ?;`, messageID, conversationID)
+	if err != nil && err == pg.ErrNoRows {
+		return core.PollMessage{}, core.ErrRessourceDoesNotExist
+	}
+
+	if err != nil {
+		return core.PollMessage{}, core.NewDataBaseError(err)
+	}
+
+	return message, nil
+}
+
+func (r *messageRepository) FindPollMessagesForConversation(
+	conversationID int,
+	window core.MessageRange) ([]interface{}, error) {
+
+	pollMessages := make([]core.PollMessage, 0, 10)
+	_, err := r.db.Query(&pollMessages,
+		`SELECT type, id, sentdate, author, question, options, ismultiplechoice, isanonymous, closedate, isclosed,
+			parentid, replycount, reactions, isedited, ispinned, origin, quotedid, quote
+		FROM v_poll_message
+		WHERE conversationid = ? AND id > ? AND id < ? AND parentid IS NULL
+		ORDER BY id ?
+		LIMIT ?;`, conversationID, window.AfterID, window.BeforeID, sortOrder(window), window.Limit)
+	if err != nil {
+		return make([]interface{}, 0), core.NewDataBaseError(err)
+	}
+
+	messagesI := make([]interface{}, len(pollMessages))
+	for i := range pollMessages {
+		messagesI[i] = pollMessages[i]
+	}
+
+	return messagesI, nil
+}
+
+// StorePollVote adds a vote of a user. If exclusive is set, all other votes
+// of the user for this poll are removed.
+func (r *messageRepository) StorePollVote(messageID, optionID, userID int, exclusive bool) error {
+	res, err := r.db.Exec(
+		`WITH removed AS (
+			DELETE FROM poll_vote
+			WHERE ? AND messageid = ? AND userid = ? AND optionid != ?
+		)
+		INSERT INTO poll_vote (messageid, optionid, userid)
+		VALUES (?, ?, ?)
+		ON CONFLICT DO NOTHING;`,
+		exclusive, messageID, userID, optionID,
+		messageID, optionID, userID)
+	if err != nil {
+		return core.NewDataBaseError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return core.ErrAlreadyExists
+	}
+	return nil
+}
+
+func (r *messageRepository) DeletePollVote(messageID, optionID, userID int) error {
+	res, err := r.db.Exec(
+		`DELETE FROM poll_vote
+		WHERE messageid = ? AND optionid = ? AND userid = ?;`, messageID, optionID, userID)
+	if err != nil {
+		return core.NewDataBaseError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return core.ErrNothingChanged
+	}
+	return nil
+}
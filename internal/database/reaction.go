@@ -0,0 +1,48 @@
+package database
+
+import (
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+func (r *messageRepository) StoreReaction(messageID, userID int, emoji string) error {
+	res, err := r.db.Exec(
+		`INSERT INTO reaction (messageid, userid, emoji)
+		VALUES (?, ?, ?)
+		ON CONFLICT DO NOTHING;`, messageID, userID, emoji)
+	if err != nil {
+		return core.NewDataBaseError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return core.ErrAlreadyExists
+	}
+	return nil
+}
+
+func (r *messageRepository) DeleteReaction(messageID, userID int, emoji string) error {
+	res, err := r.db.Exec(
+		`DELETE FROM reaction 
+		WHERE messageid = ? AND userid = ? AND emoji = ?;`, messageID, userID, emoji)
+	if err != nil {
+		return core.NewDataBaseError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return core.ErrNothingChanged
+	}
+	return nil
+}
+
+func (r *messageRepository) FindReactionsForMessage(messageID int) ([]core.ReactionCount, error) {
+	reactions := make([]core.ReactionCount, 0, 5)
+	_, err := r.db.Query(&reactions,
+		`SELECT emoji, count(*) as count, array_agg(userid ORDER BY reactiondate) as users
+		FROM reaction
+		WHERE messageid = ?
+		GROUP BY emoji
+		ORDER BY min(reactiondate);`, messageID)
+	if err != nil {
+		return nil, core.NewDataBaseError(err)
+	}
+	return reactions, nil
+}
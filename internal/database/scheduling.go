@@ -0,0 +1,169 @@
+package database
+
+import (
+	"time"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/types"
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+type schedulingRepository struct {
+	db *pg.DB
+}
+
+func (r *schedulingRepository) StoreScheduledItem(item core.ScheduledItem) (int, error) {
+	var id int
+	_, err := r.db.QueryOne(&id,
+		`INSERT INTO scheduled_item (kind, userid, conversationid, messageid, duedate, payload, note)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id;`,
+		item.Kind, item.UserID, item.ConversationID, nullIfZero(item.MessageID),
+		item.DueDate.UTC(), nullIfEmpty(string(item.Message)), nullIfEmpty(item.Note))
+	if err != nil {
+		return 0, core.NewDataBaseError(err)
+	}
+
+	return id, nil
+}
+
+func (r *schedulingRepository) UpdateScheduledItem(item core.ScheduledItem) error {
+	res, err := r.db.Exec(
+		`UPDATE scheduled_item
+		SET duedate = ?, payload = ?, note = ?, isdeferred = false
+		WHERE id = ? AND userid = ? AND claimdate IS NULL;`,
+		item.DueDate.UTC(), nullIfEmpty(string(item.Message)), nullIfEmpty(item.Note), item.ID, item.UserID)
+	if err != nil {
+		return core.NewDataBaseError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return core.ErrRessourceDoesNotExist
+	}
+	return nil
+}
+
+func (r *schedulingRepository) DeleteScheduledItem(userID, itemID int) error {
+	res, err := r.db.Exec(
+		`DELETE FROM scheduled_item WHERE id = ? AND userid = ?;`, itemID, userID)
+	if err != nil {
+		return core.NewDataBaseError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return core.ErrRessourceDoesNotExist
+	}
+	return nil
+}
+
+// ClaimDueItems marks the due items as claimed and returns them. Rows locked by a
+// concurrent claim are skipped, so every item is handed out only once. Items stay in
+// the table until they are completed. Claims older than claimedBefore are considered
+// abandoned, e.g. after a crash, and are handed out again.
+func (r *schedulingRepository) ClaimDueItems(dueBefore, claimedBefore time.Time, limit int) ([]core.ScheduledItem, error) {
+	items := make([]core.ScheduledItem, 0, 5)
+	_, err := r.db.Query(&items,
+		`UPDATE scheduled_item
+		SET claimdate = ?, attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM scheduled_item
+			WHERE duedate <= ? AND NOT isdeferred AND (claimdate IS NULL OR claimdate < ?)
+			ORDER BY duedate
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, kind, userid, conversationid, messageid, duedate, creationdate, payload, coalesce(note, '') as note, attempts;`,
+		dueBefore.UTC(), dueBefore.UTC(), claimedBefore.UTC(), limit)
+	if err != nil {
+		return nil, core.NewDataBaseError(err)
+	}
+
+	return items, nil
+}
+
+// CompleteScheduledItem removes an item after it has been dispatched.
+func (r *schedulingRepository) CompleteScheduledItem(itemID int) error {
+	_, err := r.db.Exec(`DELETE FROM scheduled_item WHERE id = ?;`, itemID)
+	return core.NewDataBaseError(err)
+}
+
+// ReleaseScheduledItem removes the claim of an item, so that it is handed out again at dueDate.
+func (r *schedulingRepository) ReleaseScheduledItem(itemID int, dueDate time.Time) error {
+	_, err := r.db.Exec(
+		`UPDATE scheduled_item SET claimdate = NULL, duedate = ? WHERE id = ?;`, dueDate.UTC(), itemID)
+	return core.NewDataBaseError(err)
+}
+
+// DeferScheduledItem keeps an item that could not be delivered until ClaimDeferredItems is
+// called for its owner.
+func (r *schedulingRepository) DeferScheduledItem(itemID int) error {
+	_, err := r.db.Exec(
+		`UPDATE scheduled_item SET claimdate = NULL, isdeferred = true WHERE id = ?;`, itemID)
+	return core.NewDataBaseError(err)
+}
+
+// ClaimDeferredItems claims all deferred items of the user like ClaimDueItems does.
+func (r *schedulingRepository) ClaimDeferredItems(userID int, claimDate time.Time) ([]core.ScheduledItem, error) {
+	items := make([]core.ScheduledItem, 0)
+	_, err := r.db.Query(&items,
+		`UPDATE scheduled_item
+		SET claimdate = ?, isdeferred = false
+		WHERE userid = ? AND isdeferred
+		RETURNING id, kind, userid, conversationid, messageid, duedate, creationdate, payload, coalesce(note, '') as note, attempts;`,
+		claimDate.UTC(), userID)
+	if err != nil {
+		return nil, core.NewDataBaseError(err)
+	}
+
+	return items, nil
+}
+
+func (r *schedulingRepository) FindScheduledItem(userID, itemID int) (core.ScheduledItem, error) {
+	var item core.ScheduledItem
+	_, err := r.db.QueryOne(&item,
+		`SELECT id, kind, userid, conversationid, messageid, duedate, creationdate, payload, coalesce(note, '') as note
+		FROM scheduled_item
+		WHERE id = ? AND userid = ?;`, itemID, userID)
+	if err != nil && err == pg.ErrNoRows {
+		return core.ScheduledItem{}, core.ErrRessourceDoesNotExist
+	}
+
+	if err != nil {
+		return core.ScheduledItem{}, core.NewDataBaseError(err)
+	}
+
+	return item, nil
+}
+
+func (r *schedulingRepository) FindScheduledItemsForUser(userID int) ([]core.ScheduledItem, error) {
+	items := make([]core.ScheduledItem, 0, 10)
+	_, err := r.db.Query(&items,
+		`SELECT id, kind, userid, conversationid, messageid, duedate, creationdate, payload, coalesce(note, '') as note
+		FROM scheduled_item
+		WHERE userid = ?
+		ORDER BY duedate;`, userID)
+	if err != nil {
+		return nil, core.NewDataBaseError(err)
+	}
+
+	return items, nil
+}
+
+// FindNextDueDate returns the zero time if nothing is scheduled. Claimed items are due
+// again once their claim has timed out.
+func (r *schedulingRepository) FindNextDueDate(claimTimeout time.Duration) (time.Time, error) {
+	var next types.NullTime
+	_, err := r.db.QueryOne(pg.Scan(&next),
+		`SELECT min(greatest(duedate, claimdate + ? * interval '1 second')) FROM scheduled_item WHERE NOT isdeferred;`,
+		claimTimeout.Seconds())
+	if err != nil {
+		return time.Time{}, core.NewDataBaseError(err)
+	}
+
+	return next.Time, nil
+}
+
+// NewSchedulingRepository creates new instance of a type that implements core.SchedulingRepo
+func NewSchedulingRepository(dbSession *pg.DB) core.SchedulingRepo {
+	return &schedulingRepository{db: dbSession}
+}
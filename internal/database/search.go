@@ -0,0 +1,114 @@
+package database
+
+import (
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+// SearchMessages performs a full text search over all messages of the conversations
+// the user is a joined member of. Code messages are found by their title and every one of
+// their files, polls by their question and options, diffs by their title and the paths of
+// the changed files. The language filter matches code messages with a file of that language.
+func (r *messageRepository) SearchMessages(userID int, query core.SearchQuery) ([]core.SearchResult, error) {
+	var from, to interface{}
+	if !query.From.IsZero() {
+		from = query.From
+	}
+
+	if !query.To.IsZero() {
+		to = query.To
+	}
+
+	results := make([]core.SearchResult, 0, query.Limit)
+	_, err := r.db.Query(&results,
+		`WITH q AS (SELECT websearch_to_tsquery('simple', ?) AS query)
+		SELECT r.id, r.conversationid, r.type, r.author, r.sentdate, r.title, r.language, r.content, r.rank
+		FROM (
+			SELECT 
+				m.id, m.conversationid, m.type, u.name as author, m.sentdate, 
+				'' as title, '' as language, t.text as content, '{}'::text[] as languages,
+				ts_rank(to_tsvector('simple', t.text), q.query) as rank
+			FROM text_message t
+			JOIN message m ON m.id = t.id
+			JOIN public.user u ON u.id = m.userid
+			CROSS JOIN q
+			WHERE to_tsvector('simple', t.text) @@ q.query
+			UNION ALL
+			SELECT 
+				m.id, m.conversationid, m.type, u.name as author, m.sentdate, 
+				c.title, c.language, c.code as content, ARRAY[c.language::text] || f.languages as languages,
+				ts_rank(to_tsvector('simple', c.title || ' ' || c.code) || jsonb_to_tsvector('simple', c.files, '["string"]'), q.query) as rank
+			FROM code_message c
+			JOIN message m ON m.id = c.id
+			JOIN public.user u ON u.id = m.userid
+			CROSS JOIN LATERAL (
+				SELECT coalesce(array_agg(cf->>'language'), '{}') as languages
+				FROM jsonb_array_elements(c.files) cf
+			) f
+			CROSS JOIN q
+			WHERE (to_tsvector('simple', c.title || ' ' || c.code) || jsonb_to_tsvector('simple', c.files, '["string"]')) @@ q.query
+			UNION ALL
+			SELECT 
+				m.id, m.conversationid, m.type, u.name as author, m.sentdate, 
+				'' as title, '' as language, mm.text as content, '{}'::text[] as languages,
+				ts_rank(to_tsvector('simple', coalesce(mm.text, '')), q.query) as rank
+			FROM media_message mm
+			JOIN message m ON m.id = mm.id
+			JOIN public.user u ON u.id = m.userid
+			CROSS JOIN q
+			WHERE to_tsvector('simple', coalesce(mm.text, '')) @@ q.query AND m.iscomplete = true
+			UNION ALL
+			SELECT 
+				m.id, m.conversationid, m.type, u.name as author, m.sentdate, 
+				'' as title, '' as language, p.question || ' ' || o.options as content, '{}'::text[] as languages,
+				ts_rank(to_tsvector('simple', p.question || ' ' || o.options), q.query) as rank
+			FROM poll_message p
+			JOIN message m ON m.id = p.id
+			JOIN public.user u ON u.id = m.userid
+			CROSS JOIN LATERAL (
+				SELECT coalesce(string_agg(po.text, ' ' ORDER BY po.optionid), '') as options
+				FROM poll_option po
+				WHERE po.messageid = p.id
+			) o
+			CROSS JOIN q
+			WHERE to_tsvector('simple', p.question || ' ' || o.options) @@ q.query
+			UNION ALL
+			SELECT 
+				m.id, m.conversationid, m.type, u.name as author, m.sentdate, 
+				d.title, '' as language, d.title || ' ' || f.paths as content, '{}'::text[] as languages,
+				ts_rank(to_tsvector('simple', d.title || ' ' || f.paths), q.query) as rank
+			FROM diff_message d
+			JOIN message m ON m.id = d.id
+			JOIN public.user u ON u.id = m.userid
+			CROSS JOIN LATERAL (
+				SELECT coalesce(string_agg(df.newpath, ' ' ORDER BY df.position), '') as paths
+				FROM diff_file df
+				WHERE df.messageid = d.id
+			) f
+			CROSS JOIN q
+			WHERE to_tsvector('simple', d.title || ' ' || f.paths) @@ q.query
+		) r
+		JOIN v_joined_member j ON j.conversationid = r.conversationid AND j.userid = ?
+		WHERE 
+			(? = 0 OR r.conversationid = ?) AND
+			(? = '' OR r.author = ?) AND
+			(? < 0 OR r.type = ?) AND
+			(? = '' OR ? = ANY(r.languages)) AND
+			(?::timestamp IS NULL OR r.sentdate >= ?) AND
+			(?::timestamp IS NULL OR r.sentdate <= ?)
+		ORDER BY r.rank DESC, r.id DESC
+		LIMIT ? OFFSET ?;`,
+		query.Term,
+		userID,
+		query.ConversationID, query.ConversationID,
+		query.Author, query.Author,
+		query.Type, query.Type,
+		query.Language, query.Language,
+		from, from,
+		to, to,
+		query.Limit, query.Offset)
+	if err != nil {
+		return nil, core.NewDataBaseError(err)
+	}
+
+	return results, nil
+}
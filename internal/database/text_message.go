@@ -8,18 +8,23 @@ import (
 // CreateMessage adds a message to the database
 func (r *messageRepository) StoreTextMessage(conversation int, user int, m core.TextMessage) (int, error) {
 	var id = -1
-	_, err := callFunction(r.db, "createTextMessage", &id, user, conversation, m.Sentdate, m.Text)
+	_, err := callFunction(r.db, "createTextMessage", &id, user, conversation, m.Sentdate, m.Text, m.HTML, m.PlainText, nullIfZero(m.ParentID))
 	if err != nil {
 		return 0, core.NewDataBaseError(err)
 	}
 
+	err = r.storeReferences(id, m.Message)
+	if err != nil {
+		return 0, err
+	}
+
 	return id, nil
 }
 
 func (r *messageRepository) FindTextMessageForID(messageID, conversationID int) (core.TextMessage, error) {
 	var message core.TextMessage
 	_, err := r.db.QueryOne(&message,
-		`SELECT type, id, sentdate, author, text
+		`SELECT type, id, sentdate, author, text, html, plaintext, parentid, replycount, reactions, isedited, ispinned, origin, quotedid, quote
 		FROM v_text_message
 		WHERE id = ? AND conversationid = ?;`, messageID, conversationID)
 	if err != nil && err == pg.ErrNoRows {
@@ -35,16 +40,15 @@ func (r *messageRepository) FindTextMessageForID(messageID, conversationID int)
 
 func (r *messageRepository) FindTextMessagesForConversation(
 	conversationID int,
-	beforeInSequence int,
-	limit int) ([]interface{}, error) {
+	window core.MessageRange) ([]interface{}, error) {
 
 	textMessages := make([]core.TextMessage, 0, 10)
 	_, err := r.db.Query(&textMessages,
-		`SELECT type, id, sentdate, author, text
+		`SELECT type, id, sentdate, author, text, html, plaintext, parentid, replycount, reactions, isedited, ispinned, origin, quotedid, quote
 		FROM v_text_message m
-		WHERE conversationid = ? AND id < ?
-		ORDER BY id desc
-		LIMIT ?;`, conversationID, beforeInSequence, limit)
+		WHERE conversationid = ? AND id > ? AND id < ? AND parentid IS NULL
+		ORDER BY id ?
+		LIMIT ?;`, conversationID, window.AfterID, window.BeforeID, sortOrder(window), window.Limit)
 	if err != nil {
 		return make([]interface{}, 0), err
 	}
@@ -229,6 +229,21 @@ func (r *userRepository) GetUserForName(name string) (core.User, error) {
 	}, nil
 }
 
+func (r *userRepository) GetUserForEmail(email string) (core.User, error) {
+	var user core.User
+	_, err := r.db.QueryOne(&user,
+		`SELECT name, email, id, isdeleted
+		 FROM public.user WHERE lower(email) = lower(?);`, email)
+	if err != nil && err == pg.ErrNoRows {
+		return core.User{}, core.ErrUserDoesNotExist
+	}
+
+	if err != nil {
+		return core.User{}, core.NewDataBaseError(err)
+	}
+	return user, nil
+}
+
 func (r *userRepository) GetUsersForPrefix(prefix string, limit int) ([]core.User, error) {
 	users := make([]core.User, 0, 10)
 	_, err := r.db.Query(&users,
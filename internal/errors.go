@@ -1,6 +1,10 @@
 package core
 
 var (
+	ErrExportNotFinished              = ApiError{1028, "The export has not been finished yet"}
+	ErrEditConflict                   = ApiError{1027, "The edit conflicts with the current state of the message"}
+	ErrExecutionInProgress            = ApiError{1026, "The code of this message is already being executed"}
+	ErrLanguageNotRunnable            = ApiError{1025, "Code of this language cannot be executed"}
 	ErrFeatureDeactivated             = ApiError{1023, "This feature is currently not availiable"}
 	ErrAccountNotConfirmed            = ApiError{1022, "Account hasn't been confirmed yet"}
 	ErrAuthFailed                     = ApiError{1020, "User cannot be authenticated"}
@@ -0,0 +1,49 @@
+package execution
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+type loggingService struct {
+	logger  log.Logger
+	next    Service
+	verbose bool
+}
+
+func NewLoggingService(logger log.Logger, s Service, verbose bool) Service {
+	return &loggingService{logger, s, verbose}
+}
+
+func (s *loggingService) Execute(userCtx, conversationID, messageID int, pusher core.Pusher, ctx context.Context) (err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "Execute",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"messageID", messageID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.Execute(userCtx, conversationID, messageID, pusher, ctx)
+}
+
+func (s *loggingService) GetExecutionResult(userCtx, conversationID, messageID int) (result core.ExecutionResult, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "GetExecutionResult",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"messageID", messageID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.GetExecutionResult(userCtx, conversationID, messageID)
+}
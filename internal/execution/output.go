@@ -0,0 +1,90 @@
+package execution
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"unicode/utf8"
+)
+
+// outputCollector records stdout and stderr of an execution up to a shared limit and
+// passes every chunk on as soon as it arrives.
+type outputCollector struct {
+	mutex           sync.Mutex
+	remaining       int
+	truncated       bool
+	stdout          bytes.Buffer
+	stderr          bytes.Buffer
+	onOutput        func(stream, data string)
+	onLimitExceeded func()
+}
+
+type outputStream struct {
+	collector *outputCollector
+	name      string
+	buffer    *bytes.Buffer
+	pending   []byte
+}
+
+func newOutputCollector(limit int, onOutput func(stream, data string)) *outputCollector {
+	return &outputCollector{
+		remaining: limit,
+		onOutput:  onOutput,
+	}
+}
+
+func (c *outputCollector) stream(name string) io.Writer {
+	buffer := &c.stdout
+	if name == "stderr" {
+		buffer = &c.stderr
+	}
+	return &outputStream{collector: c, name: name, buffer: buffer}
+}
+
+func (c *outputCollector) isTruncated() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.truncated
+}
+
+// Write never fails so that the process is not disturbed. Output beyond the limit
+// is dropped and the execution is stopped.
+func (s *outputStream) Write(p []byte) (int, error) {
+	c := s.collector
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.truncated {
+		return len(p), nil
+	}
+
+	data := p
+	if len(data) > c.remaining {
+		data = data[:c.remaining]
+		c.truncated = true
+	}
+	c.remaining -= len(data)
+	s.buffer.Write(data)
+
+	// Runes that are split between two writes are held back until they are complete.
+	chunk := append(s.pending, data...)
+	complete := len(chunk)
+	for i := len(chunk) - 1; i >= 0 && i >= len(chunk)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(chunk[i]) {
+			if !utf8.FullRune(chunk[i:]) {
+				complete = i
+			}
+			break
+		}
+	}
+
+	s.pending = append([]byte(nil), chunk[complete:]...)
+	if complete > 0 && c.onOutput != nil {
+		c.onOutput(s.name, string(chunk[:complete]))
+	}
+
+	if c.truncated && c.onLimitExceeded != nil {
+		c.onLimitExceeded()
+	}
+	return len(p), nil
+}
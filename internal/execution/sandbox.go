@@ -0,0 +1,79 @@
+package execution
+
+import "time"
+
+// Limits restricts the resources of a single sandboxed process. Zero values
+// are replaced by defaults.
+type Limits struct {
+	Timeout        time.Duration
+	CPUSeconds     uint64
+	MemoryBytes    uint64
+	MaxFileBytes   uint64
+	MaxProcesses   uint64
+	MaxOutputBytes int
+	WorkDirBytes   uint64
+}
+
+func (l Limits) withDefaults() Limits {
+	if l.Timeout == 0 {
+		l.Timeout = 10 * time.Second
+	}
+
+	if l.CPUSeconds == 0 {
+		l.CPUSeconds = 5
+	}
+
+	if l.MemoryBytes == 0 {
+		l.MemoryBytes = 512 << 20
+	}
+
+	if l.MaxFileBytes == 0 {
+		l.MaxFileBytes = 16 << 20
+	}
+
+	if l.MaxProcesses == 0 {
+		l.MaxProcesses = 64
+	}
+
+	if l.MaxOutputBytes == 0 {
+		l.MaxOutputBytes = 64 << 10
+	}
+
+	if l.WorkDirBytes == 0 {
+		l.WorkDirBytes = 64 << 20
+	}
+	return l
+}
+
+// Sandbox describes the environment the code is executed in. The code runs as UID and GID,
+// which have to belong to a dedicated user that owns no files and runs no other processes
+// on the host. ReadOnlyPaths are the only parts of the host file system that are visible
+// within the sandbox. Everything else is replaced by a fresh /proc, a minimal /dev and a
+// tmpfs work directory.
+type Sandbox struct {
+	UID           int
+	GID           int
+	ReadOnlyPaths []string
+}
+
+var defaultReadOnlyPaths = []string{
+	"/bin",
+	"/sbin",
+	"/lib",
+	"/lib32",
+	"/lib64",
+	"/libx32",
+	"/usr",
+	"/etc/alternatives",
+	"/etc/ld.so.cache",
+	"/etc/ld.so.conf",
+	"/etc/ld.so.conf.d",
+	"/etc/ssl/certs",
+}
+
+func (s Sandbox) withDefaults() Sandbox {
+	if s.ReadOnlyPaths == nil {
+		s.ReadOnlyPaths = defaultReadOnlyPaths
+	}
+	return s
+}
@@ -0,0 +1,423 @@
+//go:build linux
+// +build linux
+
+package execution
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	sandboxArg = "__devchat-sandbox__"
+
+	// The sandboxed process is root within its user namespace, which maps to the
+	// uid and gid of the sandbox user on the host.
+	sandboxRootID = 0
+
+	// The work directory within the sandbox, it is the only writable location.
+	workDir = "/work"
+
+	sandboxMountFlags = syscall.MS_NOSUID | syscall.MS_NODEV
+
+	// Not defined by the syscall package.
+	rlimitNproc      = 0x6
+	prSetNoNewPrivs  = 0x26
+	linuxCapVersion3 = 0x20080522
+	statfsRelatime   = 0x1000
+
+	// SECBIT_NOROOT, SECBIT_NO_SETUID_FIXUP and SECBIT_NO_CAP_AMBIENT_RAISE along with
+	// the lock bits of all securebits.
+	lockedSecureBits = 0xef
+
+	sandboxFailureExitCode = 127
+)
+
+// sandboxSpec is passed from the server to the init process of a sandbox.
+type sandboxSpec struct {
+	Limits        Limits
+	ReadOnlyPaths []string
+}
+
+// SandboxInit has to be called at the very beginning of main. If the process was started
+// as the init process of a sandbox, it sets up the sandbox and replaces itself with
+// the command to execute. In that case SandboxInit never returns.
+func SandboxInit() {
+	if len(os.Args) < 4 || os.Args[1] != sandboxArg {
+		return
+	}
+
+	err := execSandboxedCommand(os.Args[2], os.Args[3:])
+	fmt.Fprintln(os.Stderr, "sandbox:", err)
+	os.Exit(sandboxFailureExitCode)
+}
+
+// isSandboxSupported reports whether code can be executed as the sandbox user. Mapping
+// another user into a user namespace requires the server to run as root.
+func isSandboxSupported(sandbox Sandbox) bool {
+	if os.Geteuid() != 0 || sandbox.UID <= 0 || sandbox.GID <= 0 || sandbox.UID == os.Getuid() {
+		return false
+	}
+
+	_, err := os.Stat("/proc/self/ns/user")
+	return err == nil
+}
+
+// runSandboxed executes the command in new user, mount, pid, network, ipc and uts namespaces.
+// The new network namespace has no interfaces except for an unconfigured loopback device.
+// The files in dir/src are copied into the work directory of the sandbox, dir/root serves as
+// the mount point of its root. dir has to be owned by the sandbox user.
+func runSandboxed(
+	ctx context.Context,
+	sandbox Sandbox,
+	limits Limits,
+	dir string,
+	command []string,
+	stdout, stderr io.Writer) (int, error) {
+
+	encodedSpec, err := json.Marshal(sandboxSpec{Limits: limits, ReadOnlyPaths: sandbox.ReadOnlyPaths})
+	if err != nil {
+		return -1, err
+	}
+
+	args := append([]string{sandboxArg, string(encodedSpec)}, command...)
+	cmd := exec.CommandContext(ctx, "/proc/self/exe", args...)
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Env = []string{
+		"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+		"HOME=" + workDir,
+		"TMPDIR=" + workDir,
+		"LANG=C.UTF-8",
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER |
+			syscall.CLONE_NEWNS |
+			syscall.CLONE_NEWPID |
+			syscall.CLONE_NEWNET |
+			syscall.CLONE_NEWIPC |
+			syscall.CLONE_NEWUTS,
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: sandboxRootID, HostID: sandbox.UID, Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: sandboxRootID, HostID: sandbox.GID, Size: 1}},
+		GidMappingsEnableSetgroups: false,
+		Credential:                 &syscall.Credential{Uid: sandboxRootID, Gid: sandboxRootID, NoSetGroups: true},
+		Pdeathsig:                  syscall.SIGKILL,
+	}
+
+	err = cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode(), nil
+	}
+
+	if err != nil {
+		return -1, err
+	}
+	return 0, nil
+}
+
+// execSandboxedCommand runs inside of the sandbox. It pivots into a new root, drops all
+// capabilities and executes the command.
+func execSandboxedCommand(encodedSpec string, command []string) error {
+	// Capabilities and securebits belong to a thread, so they have to be dropped by
+	// the thread that calls exec.
+	runtime.LockOSThread()
+
+	var spec sandboxSpec
+	err := json.Unmarshal([]byte(encodedSpec), &spec)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	err = pivotIntoNewRoot(dir, spec)
+	if err != nil {
+		return err
+	}
+
+	rlimits := []struct {
+		resource int
+		value    uint64
+	}{
+		{syscall.RLIMIT_CPU, spec.Limits.CPUSeconds},
+		{syscall.RLIMIT_AS, spec.Limits.MemoryBytes},
+		{syscall.RLIMIT_FSIZE, spec.Limits.MaxFileBytes},
+		{rlimitNproc, spec.Limits.MaxProcesses},
+		{syscall.RLIMIT_CORE, 0},
+	}
+
+	for _, l := range rlimits {
+		err = syscall.Setrlimit(l.resource, &syscall.Rlimit{Cur: l.value, Max: l.value})
+		if err != nil {
+			return err
+		}
+	}
+
+	path, err := exec.LookPath(command[0])
+	if err != nil {
+		return err
+	}
+
+	err = dropCapabilities()
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(path, command, os.Environ())
+}
+
+// pivotIntoNewRoot builds a tmpfs root in dir/root and makes it the root of the sandbox.
+// The old root is detached afterwards, so nothing else of the host remains reachable.
+func pivotIntoNewRoot(dir string, spec sandboxSpec) error {
+	root := filepath.Join(dir, "root")
+
+	err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, "")
+	if err != nil {
+		return fmt.Errorf("make mounts private: %v", err)
+	}
+
+	err = syscall.Mount("tmpfs", root, "tmpfs", sandboxMountFlags, "mode=0755,size=1m")
+	if err != nil {
+		return fmt.Errorf("mount root: %v", err)
+	}
+
+	for _, path := range spec.ReadOnlyPaths {
+		err = bindReadOnly(path, root)
+		if err != nil {
+			return fmt.Errorf("bind %s: %v", path, err)
+		}
+	}
+
+	err = mountProc(filepath.Join(root, "proc"))
+	if err != nil {
+		return fmt.Errorf("mount proc: %v", err)
+	}
+
+	err = mountDev(filepath.Join(root, "dev"))
+	if err != nil {
+		return fmt.Errorf("mount dev: %v", err)
+	}
+
+	err = mountWorkDir(filepath.Join(dir, "src"), filepath.Join(root, workDir), spec.Limits.WorkDirBytes)
+	if err != nil {
+		return fmt.Errorf("mount work directory: %v", err)
+	}
+
+	err = syscall.Chdir(root)
+	if err != nil {
+		return err
+	}
+
+	// Stacks the new root on top of the old one, which is then detached.
+	err = syscall.PivotRoot(".", ".")
+	if err != nil {
+		return fmt.Errorf("pivot root: %v", err)
+	}
+
+	err = syscall.Unmount(".", syscall.MNT_DETACH)
+	if err != nil {
+		return fmt.Errorf("detach old root: %v", err)
+	}
+
+	for _, path := range []string{"/", "/dev"} {
+		err = syscall.Mount("", path, "", syscall.MS_REMOUNT|syscall.MS_BIND|syscall.MS_RDONLY|sandboxMountFlags, "")
+		if err != nil {
+			return fmt.Errorf("remount %s read-only: %v", path, err)
+		}
+	}
+	return syscall.Chdir(workDir)
+}
+
+// bindReadOnly makes path of the host available as read-only below root. Symbolic links
+// are recreated, missing paths are skipped.
+func bindReadOnly(path, root string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Join(root, path)
+	err = os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		link, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(link, target)
+	}
+
+	if info.IsDir() {
+		err = os.Mkdir(target, 0755)
+	} else {
+		err = ioutil.WriteFile(target, nil, 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = syscall.Mount(path, target, "", syscall.MS_BIND, "")
+	if err != nil {
+		return err
+	}
+
+	// Flags of the host mount are locked within the user namespace and have to be kept.
+	var stat syscall.Statfs_t
+	err = syscall.Statfs(target, &stat)
+	if err != nil {
+		return err
+	}
+
+	flags := uintptr(syscall.MS_REMOUNT | syscall.MS_BIND | syscall.MS_RDONLY | sandboxMountFlags)
+	flags |= uintptr(stat.Flags) & (syscall.MS_NOEXEC | syscall.MS_NOATIME | syscall.MS_NODIRATIME)
+	if stat.Flags&statfsRelatime != 0 {
+		flags |= syscall.MS_RELATIME
+	}
+	return syscall.Mount("", target, "", flags, "")
+}
+
+func mountProc(target string) error {
+	err := os.Mkdir(target, 0555)
+	if err != nil {
+		return err
+	}
+	return syscall.Mount("proc", target, "proc", sandboxMountFlags|syscall.MS_NOEXEC, "")
+}
+
+// mountDev creates a /dev that only contains the harmless devices of the host.
+func mountDev(target string) error {
+	err := os.Mkdir(target, 0755)
+	if err != nil {
+		return err
+	}
+
+	err = syscall.Mount("tmpfs", target, "tmpfs", syscall.MS_NOSUID|syscall.MS_NOEXEC, "mode=0755,size=64k")
+	if err != nil {
+		return err
+	}
+
+	for _, device := range []string{"null", "zero", "full", "random", "urandom"} {
+		path := filepath.Join(target, device)
+		err = ioutil.WriteFile(path, nil, 0666)
+		if err != nil {
+			return err
+		}
+
+		err = syscall.Mount(filepath.Join("/dev", device), path, "", syscall.MS_BIND, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	links := map[string]string{
+		"fd":     "/proc/self/fd",
+		"stdin":  "/proc/self/fd/0",
+		"stdout": "/proc/self/fd/1",
+		"stderr": "/proc/self/fd/2",
+	}
+	for name, link := range links {
+		err = os.Symlink(link, filepath.Join(target, name))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mountWorkDir mounts a tmpfs of the given size and copies the files of the snippet into it.
+func mountWorkDir(src, target string, size uint64) error {
+	err := os.Mkdir(target, 0700)
+	if err != nil {
+		return err
+	}
+
+	err = syscall.Mount("tmpfs", target, "tmpfs", sandboxMountFlags, "mode=0700,size="+strconv.FormatUint(size, 10))
+	if err != nil {
+		return err
+	}
+
+	files, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		content, err := ioutil.ReadFile(filepath.Join(src, f.Name()))
+		if err != nil {
+			return err
+		}
+
+		err = ioutil.WriteFile(filepath.Join(target, f.Name()), content, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropCapabilities removes all capabilities the process has within its user namespace
+// and makes sure that neither exec nor setuid binaries can bring them back.
+func dropCapabilities() error {
+	err := prctl(prSetNoNewPrivs, 1)
+	if err != nil {
+		return err
+	}
+
+	err = prctl(syscall.PR_SET_SECUREBITS, lockedSecureBits)
+	if err != nil {
+		return err
+	}
+
+	for capability := uintptr(0); ; capability++ {
+		err = prctl(syscall.PR_CAPBSET_DROP, capability)
+		if err == syscall.EINVAL {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	header := struct {
+		version uint32
+		pid     int32
+	}{version: linuxCapVersion3}
+	var data [2]struct {
+		effective, permitted, inheritable uint32
+	}
+
+	_, _, errno := syscall.RawSyscall(syscall.SYS_CAPSET, uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&data)), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func prctl(option, arg uintptr) error {
+	_, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, option, arg, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
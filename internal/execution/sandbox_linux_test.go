@@ -0,0 +1,122 @@
+//go:build linux
+// +build linux
+
+package execution
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+func TestMain(m *testing.M) {
+	SandboxInit()
+	os.Exit(m.Run())
+}
+
+// runScript executes a shell script in the sandbox. The tests are skipped if the sandbox is
+// not available, e.g. because they do not run as root.
+func runScript(t *testing.T, script string) core.ExecutionResult {
+	t.Helper()
+	cfg := Config{
+		Sandbox: Sandbox{UID: 64123, GID: 64123},
+		Limits:  Limits{Timeout: 5 * time.Second},
+	}
+
+	s := NewService(nil, nil, cfg).(*service)
+	if !isSandboxSupported(s.cfg.Sandbox) {
+		t.Skip("sandbox is not supported")
+	}
+
+	runner := Runner{FileName: "main.sh", Run: []string{"sh", "main.sh"}}
+	result := s.run(runner, core.CodeMessage{Code: script}, func(stream, data string) {})
+	if strings.HasPrefix(result.Stderr, "sandbox:") {
+		t.Fatalf("could not set up the sandbox: %s", result.Stderr)
+	}
+	return result
+}
+
+func TestSandboxRunsCode(t *testing.T) {
+	result := runScript(t, "cat main.sh > copy.sh && echo $(pwd) $(id -u) && cat /proc/self/uid_map")
+	fields := strings.Fields(result.Stdout)
+	if result.ExitCode != 0 || len(fields) != 5 || fields[0] != workDir || fields[1] != "0" || fields[3] != "64123" {
+		t.Errorf("unexpected result %#v", result)
+	}
+}
+
+func TestSandboxHidesHostFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "devchat-sandbox-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = os.Chmod(dir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := filepath.Join(dir, "config.yaml")
+	err = ioutil.WriteFile(secret, []byte("password: secret"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scripts := []string{
+		"cat " + secret,
+		"cat /proc/1/root" + secret,
+		"cat /etc/hostname",
+		"ls /home /root /tmp",
+	}
+	for _, script := range scripts {
+		result := runScript(t, script)
+		if result.ExitCode == 0 || strings.Contains(result.Stdout, "secret") {
+			t.Errorf("%q succeeded: %#v", script, result)
+		}
+	}
+}
+
+func TestSandboxIsReadOnly(t *testing.T) {
+	scripts := []string{
+		"touch /usr/devchat-test",
+		"touch /devchat-test",
+		"echo x > /dev/devchat-test",
+		"mount -t tmpfs none /work",
+	}
+	for _, script := range scripts {
+		result := runScript(t, script)
+		if result.ExitCode == 0 {
+			t.Errorf("%q succeeded: %#v", script, result)
+		}
+	}
+
+	if _, err := os.Stat("/usr/devchat-test"); err == nil {
+		t.Error("sandbox wrote to the host")
+		os.Remove("/usr/devchat-test")
+	}
+}
+
+func TestSandboxCannotSignalServer(t *testing.T) {
+	result := runScript(t, "kill -0 "+strconv.Itoa(os.Getpid()))
+	if result.ExitCode == 0 {
+		t.Errorf("server is visible: %#v", result)
+	}
+
+	// Only the processes of the sandbox itself are visible.
+	result = runScript(t, "ls /proc | grep -c '^[0-9]'")
+	if result.ExitCode != 0 || strings.TrimSpace(result.Stdout) != "3" {
+		t.Errorf("unexpected processes: %#v", result)
+	}
+
+	// Would also kill the test if it could reach it.
+	result = runScript(t, "kill -KILL -1")
+	if result.ExitCode == 0 {
+		t.Errorf("processes outside of the sandbox were signalled: %#v", result)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !linux
+// +build !linux
+
+package execution
+
+import (
+	"context"
+	"io"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+// SandboxInit does nothing on this platform, as the sandbox is only available on linux.
+func SandboxInit() {}
+
+func isSandboxSupported(sandbox Sandbox) bool {
+	return false
+}
+
+func runSandboxed(
+	ctx context.Context,
+	sandbox Sandbox,
+	limits Limits,
+	dir string,
+	command []string,
+	stdout, stderr io.Writer) (int, error) {
+
+	return -1, core.ErrFeatureDeactivated
+}
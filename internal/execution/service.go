@@ -0,0 +1,291 @@
+package execution
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+// Service defines all use cases related to the execution of code messages.
+type Service interface {
+	Execute(userCtx, conversationID, messageID int, pusher core.Pusher, ctx context.Context) error
+	GetExecutionResult(userCtx, conversationID, messageID int) (core.ExecutionResult, error)
+}
+
+// Runner describes how the code of a programming language is executed.
+// The code is written to FileName within an empty working directory. If Compile is
+// set, it is executed before Run. Both commands are executed within that directory.
+type Runner struct {
+	Language string
+	FileName string
+	Compile  []string
+	Run      []string
+}
+
+// Config contains the runners, the sandbox and the resource limits of every execution.
+type Config struct {
+	Runners                 []Runner
+	Sandbox                 Sandbox
+	Limits                  Limits
+	MaxConcurrentExecutions int
+	WorkFolder              string
+}
+
+type service struct {
+	messageRepo      core.MessageRepo
+	conversationRepo core.ConversationRepo
+	cfg              Config
+	runners          map[string]Runner
+	slots            chan struct{}
+
+	running struct {
+		sync.Mutex
+		m map[int]bool
+	}
+}
+
+type executionEvent struct {
+	MessageID int                   `json:"messageId"`
+	Event     string                `json:"event"`
+	Stream    string                `json:"stream,omitempty"`
+	Data      string                `json:"data,omitempty"`
+	Result    *core.ExecutionResult `json:"result,omitempty"`
+}
+
+// NewService creates and returns new Service
+func NewService(messageRepo core.MessageRepo, conversationRepo core.ConversationRepo, cfg Config) Service {
+	if cfg.MaxConcurrentExecutions <= 0 {
+		cfg.MaxConcurrentExecutions = 2
+	}
+	cfg.Limits = cfg.Limits.withDefaults()
+	cfg.Sandbox = cfg.Sandbox.withDefaults()
+
+	runners := make(map[string]Runner, len(cfg.Runners))
+	for _, r := range cfg.Runners {
+		runners[r.Language] = r
+	}
+
+	s := &service{
+		messageRepo:      messageRepo,
+		conversationRepo: conversationRepo,
+		cfg:              cfg,
+		runners:          runners,
+		slots:            make(chan struct{}, cfg.MaxConcurrentExecutions),
+	}
+	s.running.m = make(map[int]bool)
+	return s
+}
+
+// Execute starts the execution of a code message. The output is streamed to the room
+// while the program is running. The result is broadcasted and stored after it has finished.
+func (s *service) Execute(userCtx, conversationID, messageID int, pusher core.Pusher, ctx context.Context) error {
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return err
+	}
+
+	if !isSandboxSupported(s.cfg.Sandbox) {
+		return core.ErrFeatureDeactivated
+	}
+
+	message, err := s.messageRepo.FindCodeMessageForID(messageID, conversationID)
+	if err != nil {
+		return err
+	}
+
+	runner, err := s.findRunner(message.Language)
+	if err != nil {
+		return err
+	}
+
+	s.running.Lock()
+	if s.running.m[messageID] {
+		s.running.Unlock()
+		return core.ErrExecutionInProgress
+	}
+	s.running.m[messageID] = true
+	s.running.Unlock()
+
+	pusher.BroadcastToRoom(conversationID, executionEvent{MessageID: messageID, Event: "started"}, ctx)
+
+	go func() {
+		defer func() {
+			s.running.Lock()
+			delete(s.running.m, messageID)
+			s.running.Unlock()
+		}()
+
+		s.slots <- struct{}{}
+		result := s.run(runner, message, func(stream, data string) {
+			event := executionEvent{MessageID: messageID, Event: "output", Stream: stream, Data: data}
+			pusher.BroadcastToRoom(conversationID, event, ctx)
+		})
+		<-s.slots
+
+		result.UserID = userCtx
+		err := s.messageRepo.StoreExecutionResult(result)
+		if err != nil {
+			event := executionEvent{MessageID: messageID, Event: "failed"}
+			pusher.BroadcastToRoom(conversationID, event, ctx)
+			return
+		}
+
+		stored, err := s.messageRepo.FindExecutionResult(messageID)
+		if err == nil {
+			result = stored
+		}
+		pusher.BroadcastToRoom(conversationID, executionEvent{MessageID: messageID, Event: "finished", Result: &result}, ctx)
+	}()
+
+	return nil
+}
+
+func (s *service) GetExecutionResult(userCtx, conversationID, messageID int) (core.ExecutionResult, error) {
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return core.ExecutionResult{}, err
+	}
+
+	_, err = s.messageRepo.FindCodeMessageForID(messageID, conversationID)
+	if err != nil {
+		return core.ExecutionResult{}, err
+	}
+
+	return s.messageRepo.FindExecutionResult(messageID)
+}
+
+func (s *service) findRunner(language string) (Runner, error) {
+	languages, err := s.messageRepo.FindAllProgrammingLanguages()
+	if err != nil {
+		return Runner{}, err
+	}
+
+	isRunnable := false
+	for _, l := range languages {
+		if l.Name == language {
+			isRunnable = l.IsRunnable
+			break
+		}
+	}
+
+	runner, ok := s.runners[language]
+	if !isRunnable || !ok || len(runner.Run) == 0 {
+		return Runner{}, core.ErrLanguageNotRunnable
+	}
+	return runner, nil
+}
+
+// run compiles and executes the code of the message. Errors of the execution environment
+// are reported as part of stderr.
+func (s *service) run(runner Runner, message core.CodeMessage, onOutput func(stream, data string)) (result core.ExecutionResult) {
+	result = core.ExecutionResult{
+		MessageID: message.ID,
+		Revision:  message.Revision,
+		Language:  message.Language,
+		ExitCode:  -1,
+	}
+
+	output := newOutputCollector(s.cfg.Limits.MaxOutputBytes, onOutput)
+	begin := time.Now()
+	defer func() {
+		result.Duration = int(time.Since(begin) / time.Millisecond)
+		result.Stdout = output.stdout.String()
+		result.Stderr = output.stderr.String()
+		result.Truncated = output.isTruncated()
+	}()
+
+	dir, err := ioutil.TempDir(s.cfg.WorkFolder, "devchat-execution-")
+	if err != nil {
+		output.stream("stderr").Write([]byte(err.Error()))
+		return result
+	}
+	defer os.RemoveAll(dir)
+
+	err = s.prepareWorkFolder(dir, runner, message)
+	if err != nil {
+		output.stream("stderr").Write([]byte(err.Error()))
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Limits.Timeout)
+	defer cancel()
+	output.onLimitExceeded = cancel
+
+	commands := [][]string{runner.Run}
+	if len(runner.Compile) > 0 {
+		commands = [][]string{runner.Compile, runner.Run}
+	}
+
+	for _, command := range commands {
+		result.ExitCode, err = runSandboxed(ctx, s.cfg.Sandbox, s.cfg.Limits, dir, command, output.stream("stdout"), output.stream("stderr"))
+		if ctx.Err() == context.DeadlineExceeded {
+			result.TimedOut = true
+		}
+
+		if err != nil {
+			output.stream("stderr").Write([]byte(err.Error()))
+			return result
+		}
+
+		if result.ExitCode != 0 || ctx.Err() != nil {
+			break
+		}
+	}
+
+	return result
+}
+
+// prepareWorkFolder writes the files of the snippet to dir/src and creates dir/root as the
+// mount point of the sandbox root. Only the sandbox user can access dir, the sandbox copies
+// the files into its own work directory.
+func (s *service) prepareWorkFolder(dir string, runner Runner, message core.CodeMessage) error {
+	src := filepath.Join(dir, "src")
+	paths := []string{dir, src, filepath.Join(dir, "root")}
+	for _, path := range paths[1:] {
+		err := os.Mkdir(path, 0700)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Further files of the snippet are placed next to the main file, which takes precedence.
+	files := make(map[string]string, len(message.Files)+1)
+	for _, f := range message.Files {
+		files[filepath.Base(f.Name)] = f.Code
+	}
+	files[filepath.Base(runner.FileName)] = message.Code
+
+	for name, code := range files {
+		path := filepath.Join(src, name)
+		err := ioutil.WriteFile(path, []byte(code), 0600)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, path)
+	}
+
+	for _, path := range paths {
+		err := os.Chown(path, s.cfg.Sandbox.UID, s.cfg.Sandbox.GID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *service) errorIFIsNotInConversation(userCtx, conversationID int) error {
+	isMember, err := s.conversationRepo.IsUserInConversation(userCtx, conversationID)
+	if err != nil {
+		return err
+	}
+
+	if !isMember {
+		return core.ErrAccessDenied
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	core "github.com/miphilipp/devchat-server/internal"
+	"github.com/miphilipp/devchat-server/internal/messaging"
+)
+
+// archive is everything that is written into the ZIP file of an export. Patches and
+// media objects are not part of the JSON dump but are stored as separate files.
+type archive struct {
+	ExportDate   time.Time                 `json:"exportDate"`
+	Conversation core.Conversation         `json:"conversation"`
+	Members      []core.UserInConversation `json:"members"`
+	Messages     []interface{}             `json:"messages"`
+
+	patches map[int]string
+	media   map[int][]core.MediaObject
+}
+
+// write adds the JSON dump, the transcripts and all attached files to the ZIP file.
+// Media files that no longer exist on disk are skipped.
+func (a archive) write(zw *zip.Writer, mediaFolder string) error {
+	w, err := a.create(zw, "conversation.json")
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(a)
+	if err != nil {
+		return err
+	}
+
+	entries := a.transcriptEntries()
+	w, err = a.create(zw, "transcript.md")
+	if err != nil {
+		return err
+	}
+
+	err = writeMarkdownTranscript(w, a, entries)
+	if err != nil {
+		return err
+	}
+
+	w, err = a.create(zw, "transcript.html")
+	if err != nil {
+		return err
+	}
+
+	err = writeHTMLTranscript(w, a, entries)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range a.Messages {
+		switch message := m.(type) {
+		case core.CodeMessage:
+			for _, f := range messaging.CodeFiles(message) {
+				err = a.writeFile(zw, codePath(message, f), f.Code)
+				if err != nil {
+					break
+				}
+			}
+		case core.DiffMessage:
+			err = a.writeFile(zw, patchPath(message), a.patches[message.ID])
+		case core.MediaMessage:
+			err = a.copyMediaFiles(zw, mediaFolder, a.media[message.ID])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a archive) create(zw *zip.Writer, name string) (io.Writer, error) {
+	return zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: a.ExportDate,
+	})
+}
+
+func (a archive) writeFile(zw *zip.Writer, name, content string) error {
+	w, err := a.create(zw, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, content)
+	return err
+}
+
+func (a archive) copyMediaFiles(zw *zip.Writer, mediaFolder string, objects []core.MediaObject) error {
+	for _, obj := range objects {
+		file, err := os.Open(filepath.Join(mediaFolder, strconv.Itoa(obj.FileID)+"-"+obj.Name))
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		w, err := a.create(zw, mediaPath(obj))
+		if err == nil {
+			_, err = io.Copy(w, file)
+		}
+		file.Close()
+
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func codePath(message core.CodeMessage, file core.CodeFile) string {
+	return "code/" + strconv.Itoa(message.ID) + "-" + file.Name
+}
+
+func patchPath(message core.DiffMessage) string {
+	return "patches/" + strconv.Itoa(message.ID) + "-" + messaging.FileSlug(message.Title, "changes") + ".patch"
+}
+
+func mediaPath(obj core.MediaObject) string {
+	return "media/" + strconv.Itoa(obj.ID) + "-" + path.Base(filepath.ToSlash(obj.Name))
+}
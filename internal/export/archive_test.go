@@ -0,0 +1,119 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+func TestWriteArchive(t *testing.T) {
+	mediaFolder, err := ioutil.TempDir("", "devchat-export-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(mediaFolder)
+
+	// The forwarded media object 7 shares the file of media object 5.
+	err = ioutil.WriteFile(filepath.Join(mediaFolder, "5-chart.png"), []byte("png"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sentdate := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	a := archive{
+		ExportDate:   sentdate,
+		Conversation: core.Conversation{ID: 1, Title: "Project"},
+		Members:      []core.UserInConversation{{User: core.User{ID: 1, Name: "alice"}, IsAdmin: true}},
+		Messages: []interface{}{
+			core.TextMessage{
+				Message: core.Message{ID: 1, Type: core.TextMessageType, Author: "alice", Sentdate: sentdate},
+				Text:    "Hello **world**",
+				HTML:    "<p>Hello <strong>world</strong></p>\n",
+			},
+			core.CodeMessage{
+				Message:  core.Message{ID: 2, Type: core.CodeMessageType, Author: "alice", Sentdate: sentdate, ParentID: 1},
+				Title:    "Main file",
+				Language: "Go",
+				Code:     "// ```\nfmt.Println(\"<script>\")\n",
+				Files:    []core.CodeFile{{Name: "main_test.go", Language: "Go", Code: "package main\n"}},
+			},
+			core.DiffMessage{
+				Message: core.Message{ID: 3, Type: core.DiffMessageType, Author: "alice", Sentdate: sentdate},
+				Title:   "Fix typo",
+			},
+			core.MediaMessage{
+				Message: core.Message{ID: 4, Type: core.MediaMessageType, Author: "alice", Sentdate: sentdate},
+			},
+		},
+		patches: map[int]string{3: "--- a/x\n+++ b/x\n"},
+		media: map[int][]core.MediaObject{
+			4: {
+				{ID: 7, FileID: 5, Name: "chart.png", MIMEType: "image/png"},
+				{ID: 8, FileID: 8, Name: "missing.txt", MIMEType: "text/plain"},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	err = a.write(zw, mediaFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := ioutil.ReadAll(r)
+		r.Close()
+		files[f.Name] = string(content)
+	}
+
+	expected := []string{
+		"conversation.json",
+		"transcript.md",
+		"transcript.html",
+		"code/2-Main-file.go",
+		"code/2-main_test.go",
+		"patches/3-Fix-typo.patch",
+		"media/7-chart.png",
+	}
+	for _, name := range expected {
+		if _, ok := files[name]; !ok {
+			t.Errorf("%s is missing", name)
+		}
+	}
+
+	if len(files) != len(expected) {
+		t.Errorf("got %d files, want %d", len(files), len(expected))
+	}
+
+	if files["media/7-chart.png"] != "png" {
+		t.Errorf("media file has not been copied")
+	}
+
+	if !strings.Contains(files["transcript.md"], "````go\n") {
+		t.Errorf("code fence does not enclose the backticks of the code:\n%s", files["transcript.md"])
+	}
+
+	html := files["transcript.html"]
+	if strings.Contains(html, "<script>") || !strings.Contains(html, "<strong>world</strong>") {
+		t.Errorf("unexpected html transcript:\n%s", html)
+	}
+}
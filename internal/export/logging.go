@@ -0,0 +1,66 @@
+package export
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+type loggingService struct {
+	logger  log.Logger
+	next    Service
+	verbose bool
+}
+
+func NewLoggingService(logger log.Logger, s Service, verbose bool) Service {
+	return &loggingService{logger, s, verbose}
+}
+
+func (s *loggingService) StartExport(
+	userCtx, conversationID int,
+	pusher core.Pusher,
+	ctx context.Context) (job core.ExportJob, err error) {
+
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "StartExport",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"jobID", job.ID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.StartExport(userCtx, conversationID, pusher, ctx)
+}
+
+func (s *loggingService) ListExportJobs(userCtx int) (jobs []core.ExportJob, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "ListExportJobs",
+				"userCtx", userCtx,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.ListExportJobs(userCtx)
+}
+
+func (s *loggingService) OpenExport(userCtx, jobID int) (job core.ExportJob, file *os.File, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "OpenExport",
+				"userCtx", userCtx,
+				"jobID", jobID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.OpenExport(userCtx, jobID)
+}
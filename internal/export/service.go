@@ -0,0 +1,299 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	core "github.com/miphilipp/devchat-server/internal"
+	"github.com/miphilipp/devchat-server/internal/messaging"
+)
+
+const batchSize = 200
+
+// Service defines all use cases related to the export of conversations.
+type Service interface {
+	StartExport(userCtx, conversationID int, pusher core.Pusher, ctx context.Context) (core.ExportJob, error)
+	ListExportJobs(userCtx int) ([]core.ExportJob, error)
+
+	// OpenExport returns the archive of a finished export. The caller has to close the file.
+	OpenExport(userCtx, jobID int) (core.ExportJob, *os.File, error)
+}
+
+// Config contains the folders exports are read from and written to.
+// DownloadPath is the path under which the archives are served, the id of the
+// job and the file name are appended to it.
+type Config struct {
+	MediaFolder          string
+	WorkFolder           string
+	DownloadPath         string
+	ExpiresAfter         time.Duration
+	MaxConcurrentExports int
+}
+
+type service struct {
+	messageRepo      core.MessageRepo
+	conversationRepo core.ConversationRepo
+	cfg              Config
+	slots            chan struct{}
+
+	jobs struct {
+		sync.Mutex
+		lastID int
+		m      map[int]*job
+	}
+}
+
+type job struct {
+	core.ExportJob
+	filePath string
+}
+
+// NewService creates and returns new Service
+func NewService(messageRepo core.MessageRepo, conversationRepo core.ConversationRepo, cfg Config) Service {
+	if cfg.MaxConcurrentExports <= 0 {
+		cfg.MaxConcurrentExports = 1
+	}
+
+	if cfg.ExpiresAfter <= 0 {
+		cfg.ExpiresAfter = 24 * time.Hour
+	}
+
+	s := &service{
+		messageRepo:      messageRepo,
+		conversationRepo: conversationRepo,
+		cfg:              cfg,
+		slots:            make(chan struct{}, cfg.MaxConcurrentExports),
+	}
+	s.jobs.m = make(map[int]*job)
+
+	// Jobs are only kept in memory, so archives of a previous run can never be downloaded.
+	workFolder := cfg.WorkFolder
+	if workFolder == "" {
+		workFolder = os.TempDir()
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(workFolder, "devchat-export-*.zip"))
+	for _, f := range leftovers {
+		os.Remove(f)
+	}
+	return s
+}
+
+// StartExport creates an export job for the conversation. The archive is written in the
+// background and the finished job is sent to all connections of the user. If the user
+// is already exporting the conversation, the pending job is returned.
+func (s *service) StartExport(userCtx, conversationID int, pusher core.Pusher, ctx context.Context) (core.ExportJob, error) {
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return core.ExportJob{}, err
+	}
+
+	s.jobs.Lock()
+	defer s.jobs.Unlock()
+	for _, j := range s.jobs.m {
+		isPending := j.State == core.ExportPending || j.State == core.ExportRunning
+		if j.UserID == userCtx && j.ConversationID == conversationID && isPending {
+			return j.ExportJob, nil
+		}
+	}
+
+	s.jobs.lastID++
+	j := &job{
+		ExportJob: core.ExportJob{
+			ID:             s.jobs.lastID,
+			ConversationID: conversationID,
+			UserID:         userCtx,
+			State:          core.ExportPending,
+			CreationDate:   time.Now().UTC(),
+		},
+	}
+	s.jobs.m[j.ID] = j
+
+	go func() {
+		s.slots <- struct{}{}
+		s.setState(j.ID, core.ExportRunning)
+		fileName, filePath, size, err := s.writeArchive(conversationID)
+		<-s.slots
+
+		s.jobs.Lock()
+		now := time.Now().UTC()
+		j.CompletionDate = &now
+		if err != nil {
+			j.State = core.ExportFailed
+		} else {
+			j.State = core.ExportFinished
+			j.Size = size
+			j.filePath = filePath
+			j.DownloadURL = s.cfg.DownloadPath + "/" + strconv.Itoa(j.ID) + "/" + fileName
+		}
+		finished := j.ExportJob
+		s.jobs.Unlock()
+
+		time.AfterFunc(s.cfg.ExpiresAfter, func() { s.removeJob(j.ID) })
+		pusher.Unicast(ctx, userCtx, finished)
+	}()
+
+	return j.ExportJob, nil
+}
+
+func (s *service) ListExportJobs(userCtx int) ([]core.ExportJob, error) {
+	s.jobs.Lock()
+	defer s.jobs.Unlock()
+
+	jobs := make([]core.ExportJob, 0, 1)
+	for _, j := range s.jobs.m {
+		if j.UserID == userCtx {
+			jobs = append(jobs, j.ExportJob)
+		}
+	}
+	return jobs, nil
+}
+
+func (s *service) OpenExport(userCtx, jobID int) (core.ExportJob, *os.File, error) {
+	s.jobs.Lock()
+	j, ok := s.jobs.m[jobID]
+	if !ok || j.UserID != userCtx {
+		s.jobs.Unlock()
+		return core.ExportJob{}, nil, core.ErrRessourceDoesNotExist
+	}
+	exportJob, filePath := j.ExportJob, j.filePath
+	s.jobs.Unlock()
+
+	if exportJob.State != core.ExportFinished {
+		return core.ExportJob{}, nil, core.ErrExportNotFinished
+	}
+
+	// Archives of members who have left the conversation are no longer handed out.
+	err := s.errorIFIsNotInConversation(userCtx, exportJob.ConversationID)
+	if err != nil {
+		return core.ExportJob{}, nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return core.ExportJob{}, nil, err
+	}
+	return exportJob, file, nil
+}
+
+// writeArchive writes the ZIP archive of the conversation into the work folder and returns
+// its name, path and size.
+func (s *service) writeArchive(conversationID int) (string, string, int64, error) {
+	a, err := s.collect(conversationID)
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	file, err := ioutil.TempFile(s.cfg.WorkFolder, "devchat-export-*.zip")
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	zw := zip.NewWriter(file)
+	err = a.write(zw, s.cfg.MediaFolder)
+	if err == nil {
+		err = zw.Close()
+	}
+
+	var info os.FileInfo
+	if err == nil {
+		info, err = file.Stat()
+	}
+
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(file.Name())
+		return "", "", 0, err
+	}
+
+	fileName := "conversation-" + messaging.FileSlug(a.Conversation.Title, "export") + "-" + a.ExportDate.Format("20060102") + ".zip"
+	return fileName, file.Name(), info.Size(), nil
+}
+
+// collect loads the conversation with all of its members and messages.
+func (s *service) collect(conversationID int) (archive, error) {
+	conversation, err := s.conversationRepo.FindConversationForID(conversationID)
+	if err != nil {
+		return archive{}, err
+	}
+
+	members, err := s.conversationRepo.GetUsersInConversation(conversationID)
+	if err != nil {
+		return archive{}, err
+	}
+
+	a := archive{
+		ExportDate:   time.Now().UTC(),
+		Conversation: conversation,
+		Members:      members,
+		Messages:     make([]interface{}, 0, batchSize),
+		patches:      make(map[int]string),
+		media:        make(map[int][]core.MediaObject),
+	}
+
+	lastID := 0
+	for {
+		messages, err := s.messageRepo.FindMessagesAfter(conversationID, lastID, batchSize)
+		if err != nil {
+			return archive{}, err
+		}
+
+		for _, m := range messages {
+			switch message := m.(type) {
+			case core.DiffMessage:
+				a.patches[message.ID], err = s.messageRepo.FindPatchOfDiffMessage(message.ID, conversationID)
+			case core.MediaMessage:
+				a.media[message.ID], err = s.messageRepo.FindMediaObjectsForMessage(message.ID)
+			}
+			if err != nil {
+				return archive{}, err
+			}
+			lastID = m.(core.Sequencable).GetSequenceNumber()
+		}
+		a.Messages = append(a.Messages, messages...)
+
+		if len(messages) < batchSize {
+			return a, nil
+		}
+	}
+}
+
+func (s *service) setState(jobID int, state core.ExportState) {
+	s.jobs.Lock()
+	defer s.jobs.Unlock()
+	if j, ok := s.jobs.m[jobID]; ok {
+		j.State = state
+	}
+}
+
+func (s *service) removeJob(jobID int) {
+	s.jobs.Lock()
+	j, ok := s.jobs.m[jobID]
+	delete(s.jobs.m, jobID)
+	s.jobs.Unlock()
+
+	if ok && j.filePath != "" {
+		os.Remove(j.filePath)
+	}
+}
+
+func (s *service) errorIFIsNotInConversation(userCtx, conversationID int) error {
+	isMember, err := s.conversationRepo.IsUserInConversation(userCtx, conversationID)
+	if err != nil {
+		return err
+	}
+
+	if !isMember {
+		return core.ErrAccessDenied
+	}
+	return nil
+}
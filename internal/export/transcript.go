@@ -0,0 +1,266 @@
+package export
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	core "github.com/miphilipp/devchat-server/internal"
+	"github.com/miphilipp/devchat-server/internal/messaging"
+)
+
+const dateLayout = "2006-01-02 15:04 UTC"
+
+// transcriptEntry is the representation of a message that is shared by all transcripts.
+type transcriptEntry struct {
+	ID            int
+	ParentID      int
+	Author        string
+	Sentdate      time.Time
+	ForwardedFrom string
+
+	// Text is the Markdown source of text messages and the caption of media messages.
+	Text string
+	HTML template.HTML
+
+	Code      *core.CodeMessage
+	CodeFiles []codeFileEntry
+	Poll      *core.PollMessage
+	Diff      *core.DiffMessage
+	PatchPath string
+	Media     []mediaEntry
+}
+
+type codeFileEntry struct {
+	core.CodeFile
+	Path string
+}
+
+type mediaEntry struct {
+	Name    string
+	Path    string
+	IsImage bool
+}
+
+func (a archive) transcriptEntries() []transcriptEntry {
+	entries := make([]transcriptEntry, 0, len(a.Messages))
+	for _, m := range a.Messages {
+		var base core.Message
+		entry := transcriptEntry{}
+		switch message := m.(type) {
+		case core.TextMessage:
+			base = message.Message
+			entry.Text = message.Text
+			// The HTML has been rendered from Markdown by the server, which escapes all raw HTML.
+			entry.HTML = template.HTML(message.HTML)
+		case core.CodeMessage:
+			base = message.Message
+			entry.Code = &message
+			for _, f := range messaging.CodeFiles(message) {
+				entry.CodeFiles = append(entry.CodeFiles, codeFileEntry{f, codePath(message, f)})
+			}
+		case core.PollMessage:
+			base = message.Message
+			entry.Poll = &message
+		case core.DiffMessage:
+			base = message.Message
+			entry.Diff = &message
+			entry.PatchPath = patchPath(message)
+		case core.MediaMessage:
+			base = message.Message
+			entry.Text = message.Text
+			for _, obj := range a.media[message.ID] {
+				entry.Media = append(entry.Media, mediaEntry{
+					Name:    obj.Name,
+					Path:    mediaPath(obj),
+					IsImage: strings.HasPrefix(obj.MIMEType, "image/"),
+				})
+			}
+		default:
+			continue
+		}
+
+		entry.ID = base.ID
+		entry.ParentID = base.ParentID
+		entry.Author = base.Author
+		entry.Sentdate = base.Sentdate
+		if base.Origin != nil {
+			entry.ForwardedFrom = base.Origin.Author
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func writeMarkdownTranscript(w io.Writer, a archive, entries []transcriptEntry) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\nExported on %s\n\n## Members\n\n", a.Conversation.Title, a.ExportDate.Format(dateLayout))
+	for _, member := range a.Members {
+		b.WriteString("- " + member.Name)
+		if member.IsAdmin {
+			b.WriteString(" (admin)")
+		}
+		if member.HasLeft {
+			b.WriteString(" (left)")
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n## Messages\n")
+	for _, e := range entries {
+		fmt.Fprintf(&b, "\n<a id=\"message-%d\"></a>\n### %s, %s\n\n", e.ID, e.Author, e.Sentdate.UTC().Format(dateLayout))
+		if e.ParentID != 0 {
+			fmt.Fprintf(&b, "*Reply to [message %d](#message-%d)*\n\n", e.ParentID, e.ParentID)
+		}
+		if e.ForwardedFrom != "" {
+			fmt.Fprintf(&b, "*Forwarded from %s*\n\n", e.ForwardedFrom)
+		}
+		if e.Text != "" {
+			b.WriteString(e.Text + "\n\n")
+		}
+
+		switch {
+		case e.Code != nil:
+			fmt.Fprintf(&b, "**%s**\n", e.Code.Title)
+			for _, f := range e.CodeFiles {
+				fence := codeFence(f.Code)
+				fmt.Fprintf(&b, "\n[%s](<%s>) (%s)\n\n%s%s\n%s\n%s\n",
+					f.Name, f.Path, f.Language,
+					fence, strings.ToLower(f.Language), strings.TrimRight(f.Code, "\n"), fence)
+			}
+		case e.Poll != nil:
+			fmt.Fprintf(&b, "**Poll:** %s\n\n", e.Poll.Question)
+			for _, option := range e.Poll.Options {
+				fmt.Fprintf(&b, "- %s: %d\n", option.Text, option.Votes)
+			}
+		case e.Diff != nil:
+			fmt.Fprintf(&b, "**%s** (+%d −%d), [%s](<%s>)\n\n", e.Diff.Title, e.Diff.Additions, e.Diff.Deletions, e.PatchPath, e.PatchPath)
+			for _, file := range e.Diff.Files {
+				fmt.Fprintf(&b, "- %s %s\n", file.Status, diffFilePath(file))
+			}
+		}
+
+		for _, media := range e.Media {
+			if media.IsImage {
+				b.WriteString("!")
+			}
+			fmt.Fprintf(&b, "[%s](<%s>)\n", media.Name, media.Path)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// codeFence returns a fence that is longer than every run of backticks within the code.
+func codeFence(code string) string {
+	longest, current := 0, 0
+	for _, r := range code {
+		if r == '`' {
+			current++
+			if current > longest {
+				longest = current
+			}
+		} else {
+			current = 0
+		}
+	}
+
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
+
+func diffFilePath(file core.DiffFile) string {
+	if file.Status == core.DiffFileRenamed {
+		return file.OldPath + " → " + file.NewPath
+	}
+
+	if file.NewPath != "" {
+		return file.NewPath
+	}
+	return file.OldPath
+}
+
+var htmlTranscript = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"date":     func(t time.Time) string { return t.UTC().Format(dateLayout) },
+	"filePath": diffFilePath,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Archive.Conversation.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 2em auto; padding: 0 1em; color: #222; }
+.message { border-top: 1px solid #ddd; padding: .5em 0; }
+.reply { margin-left: 2em; }
+.meta { color: #666; font-size: .9em; }
+pre { background: #f5f5f5; padding: .5em; overflow-x: auto; }
+img { max-width: 100%; }
+</style>
+</head>
+<body>
+<h1>{{.Archive.Conversation.Title}}</h1>
+<p class="meta">Exported on {{date .Archive.ExportDate}}</p>
+<h2>Members</h2>
+<ul>
+{{- range .Archive.Members}}
+<li>{{.Name}}{{if .IsAdmin}} (admin){{end}}{{if .HasLeft}} (left){{end}}</li>
+{{- end}}
+</ul>
+<h2>Messages</h2>
+{{- range .Entries}}
+<div class="message{{if .ParentID}} reply{{end}}" id="message-{{.ID}}">
+<p class="meta"><strong>{{.Author}}</strong>, {{date .Sentdate}}
+{{- if .ParentID}}, reply to <a href="#message-{{.ParentID}}">message {{.ParentID}}</a>{{end}}
+{{- if .ForwardedFrom}}, forwarded from {{.ForwardedFrom}}{{end}}</p>
+{{- if .HTML}}
+{{.HTML}}
+{{- else if .Text}}
+<p>{{.Text}}</p>
+{{- end}}
+{{- if .Code}}
+<p><strong>{{.Code.Title}}</strong></p>
+{{- range .CodeFiles}}
+<p><a href="{{.Path}}">{{.Name}}</a> ({{.Language}})</p>
+<pre><code>{{.Code}}</code></pre>
+{{- end}}
+{{- end}}
+{{- if .Poll}}
+<p><strong>Poll:</strong> {{.Poll.Question}}</p>
+<ul>
+{{- range .Poll.Options}}
+<li>{{.Text}}: {{.Votes}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Diff}}
+<p><strong>{{.Diff.Title}}</strong> (+{{.Diff.Additions}} −{{.Diff.Deletions}}), <a href="{{.PatchPath}}">{{.PatchPath}}</a></p>
+<ul>
+{{- range .Diff.Files}}
+<li>{{.Status}} {{filePath .}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- range .Media}}
+{{- if .IsImage}}
+<p><a href="{{.Path}}"><img src="{{.Path}}" alt="{{.Name}}"></a></p>
+{{- else}}
+<p><a href="{{.Path}}">{{.Name}}</a></p>
+{{- end}}
+{{- end}}
+</div>
+{{- end}}
+</body>
+</html>
+`))
+
+func writeHTMLTranscript(w io.Writer, a archive, entries []transcriptEntry) error {
+	return htmlTranscript.Execute(w, struct {
+		Archive archive
+		Entries []transcriptEntry
+	}{a, entries})
+}
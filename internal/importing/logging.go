@@ -0,0 +1,76 @@
+package importing
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+type loggingService struct {
+	logger  log.Logger
+	next    Service
+	verbose bool
+}
+
+func NewLoggingService(logger log.Logger, s Service, verbose bool) Service {
+	return &loggingService{logger, s, verbose}
+}
+
+func (s *loggingService) Import(
+	userCtx int,
+	format Format,
+	r io.ReaderAt,
+	size int64) (result core.ImportResult, err error) {
+
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "Import",
+				"userCtx", userCtx,
+				"format", format,
+				"size", size,
+				"conversations", len(result.Conversations),
+				"messages", result.Messages,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.Import(userCtx, format, r, size)
+}
+
+func (s *loggingService) StartImport(
+	userCtx int,
+	format Format,
+	file *os.File,
+	pusher core.Pusher,
+	ctx context.Context) (err error) {
+
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "StartImport",
+				"userCtx", userCtx,
+				"format", format,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.StartImport(userCtx, format, file, pusher, ctx)
+}
+
+func (s *loggingService) AuthorizeImport(userCtx int) (err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "AuthorizeImport",
+				"userCtx", userCtx,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.AuthorizeImport(userCtx)
+}
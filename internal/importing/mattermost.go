@@ -0,0 +1,201 @@
+package importing
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+type mattermostLine struct {
+	Type    string `json:"type"`
+	Channel *struct {
+		Team        string `json:"team"`
+		Name        string `json:"name"`
+		DisplayName string `json:"display_name"`
+	} `json:"channel"`
+	User *struct {
+		Username string `json:"username"`
+		Email    string `json:"email"`
+		Teams    []struct {
+			Name     string `json:"name"`
+			Channels []struct {
+				Name string `json:"name"`
+			} `json:"channels"`
+		} `json:"teams"`
+	} `json:"user"`
+	Post          *mattermostPost `json:"post"`
+	DirectChannel *struct {
+		Members []string `json:"members"`
+	} `json:"direct_channel"`
+	DirectPost *mattermostPost `json:"direct_post"`
+}
+
+type mattermostPost struct {
+	Team           string   `json:"team"`
+	Channel        string   `json:"channel"`
+	ChannelMembers []string `json:"channel_members"`
+	mattermostReply
+	Replies []mattermostReply `json:"replies"`
+}
+
+type mattermostReply struct {
+	User        string `json:"user"`
+	Message     string `json:"message"`
+	CreateAt    int64  `json:"create_at"`
+	Attachments []struct {
+		Path string `json:"path"`
+	} `json:"attachments"`
+}
+
+// parseMattermost reads a Mattermost bulk export. The export is either the JSONL file
+// itself or a ZIP archive that contains it along with the attachments.
+func parseMattermost(r io.ReaderAt, size int64) (source, error) {
+	var lines io.Reader = io.NewSectionReader(r, 0, size)
+	var index map[string]*zip.File
+	var baseDir string
+
+	zr, err := zip.NewReader(r, size)
+	if err == nil {
+		index = zipIndex(zr)
+		var jsonl *zip.File
+		for name, f := range index {
+			if path.Ext(name) == ".jsonl" && (jsonl == nil || name < cleanZipPath(jsonl.Name)) {
+				jsonl = f
+			}
+		}
+
+		if jsonl == nil {
+			return source{}, core.NewInvalidValueError("export")
+		}
+
+		rc, err := jsonl.Open()
+		if err != nil {
+			return source{}, err
+		}
+		defer rc.Close()
+		lines = rc
+		baseDir = path.Dir(cleanZipPath(jsonl.Name))
+	}
+
+	p := mattermostParser{
+		src:      source{users: make(map[string]sourceUser)},
+		channels: make(map[string]*sourceChannel),
+		index:    index,
+		baseDir:  baseDir,
+	}
+
+	decoder := json.NewDecoder(lines)
+	for lineNumber := 1; ; lineNumber++ {
+		var line mattermostLine
+		err := decoder.Decode(&line)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return source{}, core.NewJSONFormatError("line " + strconv.Itoa(lineNumber) + ": " + err.Error())
+		}
+		p.add(line)
+	}
+
+	for _, key := range p.order {
+		channel := p.channels[key]
+		channel.sortPosts()
+		p.src.channels = append(p.src.channels, *channel)
+	}
+
+	if len(p.src.channels) == 0 {
+		return source{}, core.NewInvalidValueError("export")
+	}
+	return p.src, nil
+}
+
+type mattermostParser struct {
+	src      source
+	channels map[string]*sourceChannel
+	order    []string
+	index    map[string]*zip.File
+	baseDir  string
+	nPosts   int
+}
+
+func (p *mattermostParser) add(line mattermostLine) {
+	switch {
+	case line.Type == "channel" && line.Channel != nil:
+		title := line.Channel.DisplayName
+		if title == "" {
+			title = line.Channel.Name
+		}
+		p.channel(line.Channel.Team + "/" + line.Channel.Name).Title = title
+	case line.Type == "user" && line.User != nil:
+		p.src.users[line.User.Username] = sourceUser{Name: line.User.Username, Email: line.User.Email}
+		for _, team := range line.User.Teams {
+			for _, c := range team.Channels {
+				channel := p.channel(team.Name + "/" + c.Name)
+				channel.Members = append(channel.Members, line.User.Username)
+			}
+		}
+	case line.Type == "direct_channel" && line.DirectChannel != nil:
+		p.directChannel(line.DirectChannel.Members)
+	case line.Type == "post" && line.Post != nil:
+		p.addPost(p.channel(line.Post.Team+"/"+line.Post.Channel), line.Post)
+	case line.Type == "direct_post" && line.DirectPost != nil:
+		p.addPost(p.directChannel(line.DirectPost.ChannelMembers), line.DirectPost)
+	}
+}
+
+func (p *mattermostParser) channel(key string) *sourceChannel {
+	channel, ok := p.channels[key]
+	if !ok {
+		channel = &sourceChannel{Title: path.Base(key)}
+		p.channels[key] = channel
+		p.order = append(p.order, key)
+	}
+	return channel
+}
+
+// directChannel returns the channel of the direct messages between the members.
+func (p *mattermostParser) directChannel(members []string) *sourceChannel {
+	sorted := append([]string(nil), members...)
+	sort.Strings(sorted)
+	channel := p.channel("@" + strings.Join(sorted, ","))
+	channel.Title = strings.Join(sorted, ", ")
+	channel.Members = sorted
+	return channel
+}
+
+func (p *mattermostParser) addPost(channel *sourceChannel, post *mattermostPost) {
+	root := p.post(post.mattermostReply, "")
+	channel.Posts = append(channel.Posts, root)
+	for _, reply := range post.Replies {
+		channel.Posts = append(channel.Posts, p.post(reply, root.Key))
+	}
+}
+
+func (p *mattermostParser) post(reply mattermostReply, threadKey string) sourcePost {
+	p.nPosts++
+	post := sourcePost{
+		Key:       strconv.Itoa(p.nPosts),
+		User:      reply.User,
+		Text:      reply.Message,
+		Date:      time.Unix(0, reply.CreateAt*int64(time.Millisecond)).UTC(),
+		ThreadKey: threadKey,
+	}
+
+	for _, attachment := range reply.Attachments {
+		name := cleanZipPath(attachment.Path)
+		file, ok := p.index[name]
+		if !ok {
+			file = p.index[path.Join(p.baseDir, name)]
+		}
+		post.Files = append(post.Files, sourceFile{Name: path.Base(name), file: file})
+	}
+	return post
+}
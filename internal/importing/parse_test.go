@@ -0,0 +1,149 @@
+package importing
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlackToMarkdown(t *testing.T) {
+	users := map[string]sourceUser{"U1": {Name: "alice"}}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Hi <@U1>", "Hi @alice"},
+		{"<@U2|bob> see <#C1|general>", "@bob see #general"},
+		{"<!here> look at <https://example.com|this>", "@here look at [this](https://example.com)"},
+		{"<https://example.com>", "https://example.com"},
+		{"a &lt; b &amp;&amp; c &gt; d", "a < b && c > d"},
+	}
+
+	for _, test := range tests {
+		if got := slackToMarkdown(test.input, users); got != test.expected {
+			t.Errorf("slackToMarkdown(%q) = %q, want %q", test.input, got, test.expected)
+		}
+	}
+}
+
+func TestSegments(t *testing.T) {
+	s := &service{}
+	languages := map[string]string{"go": "Go", "python": "Python"}
+	text := "Look:\n```golang\nfmt.Println()\n```\nand\n```unknown\nx\n```\nor\n```py\npass\n```"
+
+	segments := s.segments(text, languages)
+	expected := []segment{
+		{Text: "Look:"},
+		{IsCode: true, Language: "Go", Text: "fmt.Println()"},
+		{Text: "and\n\n```unknown\nx\n```\n\nor"},
+		{IsCode: true, Language: "Python", Text: "pass"},
+	}
+
+	if len(segments) != len(expected) {
+		t.Fatalf("got %d segments, want %d: %#v", len(segments), len(expected), segments)
+	}
+
+	for i := range expected {
+		if segments[i] != expected[i] {
+			t.Errorf("segment %d = %#v, want %#v", i, segments[i], expected[i])
+		}
+	}
+
+	// Slack does not know the languages of code blocks.
+	s.cfg.DefaultLanguage = "Python"
+	segments = s.segments(slackToMarkdown("Run ```print(1)```", nil), languages)
+	if len(segments) != 2 || !segments[1].IsCode || segments[1].Text != "print(1)" {
+		t.Errorf("unexpected segments %#v", segments)
+	}
+}
+
+func TestParseSlack(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := map[string]string{
+		"users.json":    `[{"id":"U1","name":"alice","profile":{"email":"alice@example.com"}},{"id":"U2","name":"bob"}]`,
+		"channels.json": `[{"id":"C1","name":"general","members":["U1"]}]`,
+		"general/2020-05-02.json": `[
+			{"type":"message","user":"U2","text":"Thanks","ts":"1588410000.000100","thread_ts":"1588320000.000200"}
+		]`,
+		"general/2020-05-01.json": `[
+			{"type":"message","subtype":"channel_join","user":"U2","text":"joined","ts":"1588310000.000000"},
+			{"type":"message","user":"U1","text":"Hello","ts":"1588320000.000200","files":[{"id":"F1","name":"a.png"},{"id":"F2","name":"b.txt"}]}
+		]`,
+		"__uploads/F1/a.png": "png",
+	}
+	for name, content := range files {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := parseSlack(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(src.channels) != 1 || src.channels[0].Title != "general" {
+		t.Fatalf("unexpected channels %#v", src.channels)
+	}
+
+	posts := src.channels[0].Posts
+	if len(posts) != 2 {
+		t.Fatalf("got %d posts, want 2", len(posts))
+	}
+
+	if !posts[0].Date.Equal(time.Date(2020, 5, 1, 8, 0, 0, 200000, time.UTC)) || posts[0].ThreadKey != "" {
+		t.Errorf("unexpected first post %#v", posts[0])
+	}
+
+	if posts[1].ThreadKey != posts[0].Key {
+		t.Errorf("reply does not reference its thread")
+	}
+
+	if len(posts[0].Files) != 2 || posts[0].Files[0].file == nil || posts[0].Files[1].file != nil {
+		t.Errorf("unexpected files %#v", posts[0].Files)
+	}
+
+	participants := src.channels[0].participants()
+	if strings.Join(participants, ",") != "U1,U2" {
+		t.Errorf("unexpected participants %v", participants)
+	}
+}
+
+func TestParseMattermost(t *testing.T) {
+	jsonl := `{"type":"version","version":1}
+{"type":"channel","channel":{"team":"dev","name":"town-square","display_name":"Town Square"}}
+{"type":"user","user":{"username":"alice","email":"alice@example.com","teams":[{"name":"dev","channels":[{"name":"town-square"}]}]}}
+{"type":"post","post":{"team":"dev","channel":"town-square","user":"alice","message":"Hello","create_at":1588320000000,"replies":[{"user":"bob","message":"Hi","create_at":1588320060000}]}}
+{"type":"direct_post","direct_post":{"channel_members":["bob","alice"],"user":"bob","message":"Psst","create_at":1588320120000,"attachments":[{"path":"data/x.txt"}]}}
+`
+	src, err := parseMattermost(strings.NewReader(jsonl), int64(len(jsonl)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(src.channels) != 2 {
+		t.Fatalf("got %d channels, want 2", len(src.channels))
+	}
+
+	channel := src.channels[0]
+	if channel.Title != "Town Square" || len(channel.Members) != 1 || len(channel.Posts) != 2 {
+		t.Errorf("unexpected channel %#v", channel)
+	}
+
+	if channel.Posts[1].ThreadKey != channel.Posts[0].Key || channel.Posts[1].User != "bob" {
+		t.Errorf("unexpected reply %#v", channel.Posts[1])
+	}
+
+	direct := src.channels[1]
+	if direct.Title != "alice, bob" || len(direct.Posts[0].Files) != 1 || direct.Posts[0].Files[0].file != nil {
+		t.Errorf("unexpected direct channel %#v", direct)
+	}
+}
@@ -0,0 +1,548 @@
+package importing
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	core "github.com/miphilipp/devchat-server/internal"
+	"github.com/miphilipp/devchat-server/internal/messaging"
+)
+
+// Format is the chat system an export stems from.
+type Format string
+
+const (
+	SlackFormat      Format = "slack"
+	MattermostFormat Format = "mattermost"
+)
+
+// MaxExportSize is the largest export that can be uploaded through the API.
+const MaxExportSize = 32 << 20
+
+const (
+	maxAttachmentSize = 32 << 20
+	maxUserNameLength = 40
+	maxEmailLength    = 40
+	maxTitleLength    = 100
+	codeTitle         = "Snippet"
+)
+
+// languageAliases maps common names of code fences onto the names of programming languages.
+var languageAliases = map[string]string{
+	"c++":    "cpp",
+	"cs":     "c#",
+	"golang": "go",
+	"js":     "javascript",
+	"kt":     "kotlin",
+	"py":     "python",
+	"rb":     "ruby",
+	"rs":     "rust",
+	"sh":     "bash",
+	"ts":     "typescript",
+	"yml":    "yaml",
+}
+
+// Service defines all use cases related to the import of conversations from other chat systems.
+type Service interface {
+	// Import creates a conversation for every channel of the export. The user becomes
+	// the admin of all of them.
+	Import(userCtx int, format Format, r io.ReaderAt, size int64) (core.ImportResult, error)
+
+	// StartImport imports the export in the background and sends the result to all
+	// connections of the user. The file is removed afterwards.
+	StartImport(userCtx int, format Format, file *os.File, pusher core.Pusher, ctx context.Context) error
+
+	// AuthorizeImport fails unless the user is allowed to import exports.
+	AuthorizeImport(userCtx int) error
+}
+
+// Config lists the users who are allowed to import exports. Code blocks without a
+// known language are imported as DefaultLanguage or are kept in the text if it is empty.
+type Config struct {
+	MediaFolder     string
+	Admins          []string
+	DefaultLanguage string
+}
+
+type service struct {
+	userRepo         core.UserRepo
+	conversationRepo core.ConversationRepo
+	messageRepo      core.MessageRepo
+	messagingService messaging.Service
+	cfg              Config
+}
+
+// NewService creates and returns new Service
+func NewService(
+	userRepo core.UserRepo,
+	conversationRepo core.ConversationRepo,
+	messageRepo core.MessageRepo,
+	messagingService messaging.Service,
+	cfg Config) Service {
+
+	return &service{
+		userRepo:         userRepo,
+		conversationRepo: conversationRepo,
+		messageRepo:      messageRepo,
+		messagingService: messagingService,
+		cfg:              cfg,
+	}
+}
+
+func (s *service) StartImport(userCtx int, format Format, file *os.File, pusher core.Pusher, ctx context.Context) error {
+	err := s.errorIFIsNotAdmin(userCtx)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+
+	go func() {
+		defer os.Remove(file.Name())
+		defer file.Close()
+
+		result, err := s.Import(userCtx, format, file, info.Size())
+		if err != nil {
+			result.Error = err.Error()
+		}
+		pusher.Unicast(ctx, userCtx, result)
+	}()
+	return nil
+}
+
+// Import maps the users of the export onto existing accounts by their email address.
+// Placeholder accounts, which cannot log in, are created for everybody else. A channel
+// is either imported completely or not at all, channels imported before a failure remain.
+func (s *service) Import(userCtx int, format Format, r io.ReaderAt, size int64) (core.ImportResult, error) {
+	result := core.ImportResult{
+		Conversations: make([]core.Conversation, 0, 1),
+		CreatedUsers:  make([]string, 0),
+	}
+
+	err := s.errorIFIsNotAdmin(userCtx)
+	if err != nil {
+		return result, err
+	}
+
+	src, err := parse(format, r, size)
+	if err != nil {
+		return result, err
+	}
+
+	languages, err := s.loadLanguages()
+	if err != nil {
+		return result, err
+	}
+
+	participants := make([]string, 0, len(src.users))
+	for _, channel := range src.channels {
+		if len(channel.Posts) > 0 {
+			participants = append(participants, channel.participants()...)
+		}
+	}
+
+	users, err := s.mapUsers(src, participants, &result)
+	if err != nil {
+		return result, err
+	}
+
+	for _, channel := range src.channels {
+		if len(channel.Posts) == 0 {
+			continue
+		}
+
+		counts := result
+		conversation, err := s.importChannel(userCtx, channel, users, languages, &result)
+		if err != nil {
+			result.Messages, result.Attachments = counts.Messages, counts.Attachments
+			result.SkippedAttachments = counts.SkippedAttachments
+			if conversation.ID > 0 {
+				s.removeConversation(conversation.ID)
+			}
+			return result, err
+		}
+		result.Conversations = append(result.Conversations, conversation)
+	}
+	return result, nil
+}
+
+func parse(format Format, r io.ReaderAt, size int64) (source, error) {
+	switch format {
+	case SlackFormat:
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			return source{}, core.NewInvalidValueError("export")
+		}
+		return parseSlack(zr)
+	case MattermostFormat:
+		return parseMattermost(r, size)
+	default:
+		return source{}, core.NewInvalidValueError("format")
+	}
+}
+
+func (s *service) importChannel(
+	userCtx int,
+	channel sourceChannel,
+	users map[string]int,
+	languages map[string]string,
+	result *core.ImportResult) (core.Conversation, error) {
+
+	members := make([]int, 0, len(channel.Members))
+	for _, key := range channel.participants() {
+		if id := users[key]; id != userCtx && !containsInt(members, id) {
+			members = append(members, id)
+		}
+	}
+	sort.Ints(members)
+
+	title := truncate(strings.TrimSpace(channel.Title), maxTitleLength)
+	if title == "" {
+		title = "Import"
+	}
+
+	conversation, err := s.conversationRepo.CreateConversation(userCtx, core.Conversation{
+		Title: title,
+		ID:    -1,
+	}, members)
+	if err != nil {
+		return core.Conversation{}, err
+	}
+
+	for _, member := range members {
+		_, err := s.conversationRepo.MarkAsJoined(member, conversation.ID)
+		if err != nil {
+			return conversation, err
+		}
+	}
+
+	threads := make(map[string]int)
+	for _, post := range channel.Posts {
+		parentID := 0
+		if post.ThreadKey != "" {
+			parentID = threads[post.ThreadKey]
+		}
+
+		firstID, err := s.importPost(conversation.ID, users[post.User], parentID, post, languages, result)
+		if err != nil {
+			return conversation, err
+		}
+
+		if post.ThreadKey == "" && firstID != 0 {
+			threads[post.Key] = firstID
+		}
+	}
+
+	// The history of an imported conversation has been read already.
+	now := time.Now().UTC()
+	for _, member := range append(members, userCtx) {
+		_, err := s.messageRepo.SetReadFlags(member, conversation.ID, 0, now)
+		if err != nil {
+			return conversation, err
+		}
+	}
+	return conversation, nil
+}
+
+// removeConversation deletes a partially imported conversation including its media files.
+// Errors are dropped in favour of the one that caused the import to fail.
+func (s *service) removeConversation(conversationID int) {
+	_, err := s.messagingService.DeleteExpiredMessages(
+		conversationID,
+		time.Now().UTC(),
+		s.cfg.MediaFolder,
+		silentPusher{},
+		context.Background())
+	if err != nil {
+		return
+	}
+	s.conversationRepo.DeleteConversation(conversationID)
+}
+
+// silentPusher drops all messages. Nobody is connected to a conversation during its import.
+type silentPusher struct{}
+
+func (silentPusher) BroadcastToRoom(roomNumber int, payload interface{}, ctx context.Context) {}
+func (silentPusher) Unicast(ctx context.Context, userID int, payload interface{})             {}
+
+// importPost stores the post as one or more messages and returns the id of the first one.
+func (s *service) importPost(
+	conversationID, author, parentID int,
+	post sourcePost,
+	languages map[string]string,
+	result *core.ImportResult) (int, error) {
+
+	firstID := 0
+	base := core.Message{Sentdate: post.Date, ParentID: parentID}
+	for _, seg := range s.segments(post.Text, languages) {
+		var id int
+		var err error
+		if seg.IsCode {
+			id, err = s.messageRepo.StoreCodeMessage(conversationID, author, core.CodeMessage{
+				Message:  base,
+				Code:     seg.Text,
+				Language: seg.Language,
+				Title:    codeTitle,
+			})
+		} else {
+			message := core.TextMessage{Message: base, Text: seg.Text}
+			message.HTML, message.PlainText = messaging.RenderMarkdown(seg.Text)
+			id, err = s.messageRepo.StoreTextMessage(conversationID, author, message)
+		}
+		if err != nil {
+			return firstID, err
+		}
+
+		result.Messages++
+		if firstID == 0 {
+			firstID = id
+		}
+	}
+
+	files := make([]sourceFile, 0, len(post.Files))
+	for _, f := range post.Files {
+		if f.file == nil || f.file.UncompressedSize64 > maxAttachmentSize {
+			result.SkippedAttachments++
+			continue
+		}
+		files = append(files, f)
+	}
+
+	if len(files) == 0 {
+		return firstID, nil
+	}
+
+	messageID, err := s.messageRepo.StoreMediaMessage(conversationID, author, core.MediaMessage{Message: base})
+	if err != nil {
+		return firstID, err
+	}
+	result.Messages++
+	if firstID == 0 {
+		firstID = messageID
+	}
+
+	var mediaErr error
+	for _, f := range files {
+		mediaErr = s.addFile(conversationID, author, messageID, f)
+		if mediaErr != nil {
+			break
+		}
+		result.Attachments++
+	}
+	return firstID, s.messagingService.CompleteMessage(messageID, mediaErr)
+}
+
+func (s *service) addFile(conversationID, author, messageID int, f sourceFile) error {
+	r, err := f.file.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	buffer, err := ioutil.ReadAll(io.LimitReader(r, maxAttachmentSize))
+	if err != nil {
+		return err
+	}
+
+	return s.messagingService.AddFileToMessage(
+		author,
+		conversationID,
+		messageID,
+		buffer,
+		s.cfg.MediaFolder,
+		f.Name,
+		http.DetectContentType(buffer),
+	)
+}
+
+// segments splits the text into text and code messages. Code blocks whose language
+// is unknown remain part of the surrounding text.
+func (s *service) segments(text string, languages map[string]string) []segment {
+	merged := make([]segment, 0, 1)
+	for _, seg := range splitCodeBlocks(text) {
+		if seg.IsCode {
+			language, ok := resolveLanguage(seg.Language, languages)
+			if !ok {
+				language, ok = resolveLanguage(s.cfg.DefaultLanguage, languages)
+			}
+
+			if ok {
+				seg.Language = language
+				merged = append(merged, seg)
+				continue
+			}
+			seg = segment{Text: "```" + seg.Language + "\n" + seg.Text + "\n```"}
+		}
+
+		last := len(merged) - 1
+		if last >= 0 && !merged[last].IsCode {
+			merged[last].Text += "\n\n" + seg.Text
+		} else {
+			merged = append(merged, seg)
+		}
+	}
+	return merged
+}
+
+func resolveLanguage(name string, languages map[string]string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := languageAliases[name]; ok {
+		name = alias
+	}
+	language, ok := languages[name]
+	return language, ok
+}
+
+// loadLanguages returns the programming languages by their lower case names.
+func (s *service) loadLanguages() (map[string]string, error) {
+	languages, err := s.messageRepo.FindAllProgrammingLanguages()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(languages))
+	for _, l := range languages {
+		names[strings.ToLower(l.Name)] = l.Name
+	}
+	return names, nil
+}
+
+// mapUsers returns the ids of the accounts of the users with the given keys.
+func (s *service) mapUsers(src source, keys []string, result *core.ImportResult) (map[string]int, error) {
+	ids := make(map[string]int, len(keys))
+	for _, key := range keys {
+		if _, ok := ids[key]; ok {
+			continue
+		}
+
+		u, ok := src.users[key]
+		if !ok {
+			u = sourceUser{Name: key}
+		}
+
+		user, err := s.findUser(u)
+		if err == core.ErrUserDoesNotExist {
+			user, err = s.createPlaceholder(u)
+			if err == nil {
+				result.CreatedUsers = append(result.CreatedUsers, user.Name)
+			}
+		} else if err == nil {
+			result.MappedUsers++
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		ids[key] = user.ID
+	}
+	return ids, nil
+}
+
+// findUser returns the account of the export user. Users are only identified by their email
+// address. Otherwise a local account that merely shares the name would become the author
+// of someone else's posts.
+func (s *service) findUser(u sourceUser) (core.User, error) {
+	if u.Email == "" {
+		return core.User{}, core.ErrUserDoesNotExist
+	}
+
+	user, err := s.userRepo.GetUserForEmail(u.Email)
+	if err != nil {
+		return core.User{}, err
+	}
+
+	if user.IsDeleted {
+		return core.User{}, core.ErrUserDoesNotExist
+	}
+	return user, nil
+}
+
+// createPlaceholder creates an unconfirmed account with a random password. The name
+// gets a numeric suffix if it is taken by a deleted account.
+func (s *service) createPlaceholder(u sourceUser) (core.User, error) {
+	name := truncate(u.Name, maxUserNameLength)
+	for i := 2; ; i++ {
+		_, err := s.userRepo.GetUserForName(name)
+		if err == core.ErrUserDoesNotExist {
+			break
+		}
+
+		if err != nil {
+			return core.User{}, err
+		}
+
+		suffix := "-" + strconv.Itoa(i)
+		name = truncate(u.Name, maxUserNameLength-len(suffix)) + suffix
+	}
+
+	secret := make([]byte, 24)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return core.User{}, err
+	}
+
+	email := u.Email
+	if email == "" || len(email) > maxEmailLength || s.isEmailTaken(email) {
+		email = "imported-" + hex.EncodeToString(secret[:4]) + "@devchat.invalid"
+	}
+
+	return s.userRepo.CreateUser(core.User{Name: name, Email: email}, hex.EncodeToString(secret))
+}
+
+func (s *service) isEmailTaken(email string) bool {
+	_, err := s.userRepo.GetUserForEmail(email)
+	return err != core.ErrUserDoesNotExist
+}
+
+func (s *service) AuthorizeImport(userCtx int) error {
+	return s.errorIFIsNotAdmin(userCtx)
+}
+
+func (s *service) errorIFIsNotAdmin(userCtx int) error {
+	user, err := s.userRepo.GetUserForID(userCtx)
+	if err != nil {
+		return err
+	}
+
+	for _, admin := range s.cfg.Admins {
+		if admin == user.Name {
+			return nil
+		}
+	}
+	return core.ErrAccessDenied
+}
+
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate shortens the string to at most n runes.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
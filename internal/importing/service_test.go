@@ -0,0 +1,169 @@
+package importing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	core "github.com/miphilipp/devchat-server/internal"
+	"github.com/miphilipp/devchat-server/internal/messaging"
+)
+
+type fakeUserRepo struct {
+	core.UserRepo
+	users []core.User
+}
+
+func (r *fakeUserRepo) GetUserForName(name string) (core.User, error) {
+	for _, u := range r.users {
+		if u.Name == name {
+			return u, nil
+		}
+	}
+	return core.User{}, core.ErrUserDoesNotExist
+}
+
+func (r *fakeUserRepo) GetUserForEmail(email string) (core.User, error) {
+	for _, u := range r.users {
+		if strings.EqualFold(u.Email, email) {
+			return u, nil
+		}
+	}
+	return core.User{}, core.ErrUserDoesNotExist
+}
+
+func (r *fakeUserRepo) GetUserForID(userID int) (core.User, error) {
+	for _, u := range r.users {
+		if u.ID == userID {
+			return u, nil
+		}
+	}
+	return core.User{}, core.ErrUserDoesNotExist
+}
+
+func (r *fakeUserRepo) CreateUser(user core.User, password string) (core.User, error) {
+	user.ID = len(r.users) + 1
+	r.users = append(r.users, user)
+	return user, nil
+}
+
+func TestMapUsers(t *testing.T) {
+	repo := &fakeUserRepo{users: []core.User{
+		{ID: 1, Name: "alice", Email: "alice@example.com"},
+		{ID: 2, Name: "bob", Email: "bob@example.com"},
+		{ID: 3, Name: "carol", Email: "carol@example.com"},
+	}}
+	s := &service{userRepo: repo}
+
+	src := source{users: map[string]sourceUser{
+		"U1": {Name: "someone", Email: "ALICE@example.com"},
+		"U2": {Name: "bob", Email: "bob@elsewhere.com"},
+		"U3": {Name: "carol"},
+	}}
+
+	var result core.ImportResult
+	ids, err := s.mapUsers(src, []string{"U1", "U2", "U3"}, &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ids["U1"] != 1 {
+		t.Errorf("users were not mapped: %v", ids)
+	}
+
+	// Sharing a name with a local account is not enough, neither with a different email nor without one.
+	if ids["U2"] == 2 || ids["U3"] == 3 || result.MappedUsers != 1 ||
+		strings.Join(result.CreatedUsers, ",") != "bob-2,carol-2" {
+		t.Errorf("unexpected result %v %#v", ids, result)
+	}
+}
+
+type fakeConversationRepo struct {
+	core.ConversationRepo
+	deleted []int
+}
+
+func (r *fakeConversationRepo) CreateConversation(userID int, c core.Conversation, initialMembers []int) (core.Conversation, error) {
+	c.ID = 10 + len(r.deleted)
+	return c, nil
+}
+
+func (r *fakeConversationRepo) MarkAsJoined(userID, conversationID int) (int, error) {
+	return conversationID, nil
+}
+
+func (r *fakeConversationRepo) DeleteConversation(id int) error {
+	r.deleted = append(r.deleted, id)
+	return nil
+}
+
+type fakeMessageRepo struct {
+	core.MessageRepo
+	stored int
+	failAt int
+}
+
+func (r *fakeMessageRepo) FindAllProgrammingLanguages() ([]core.ProgrammingLanguage, error) {
+	return []core.ProgrammingLanguage{}, nil
+}
+
+func (r *fakeMessageRepo) StoreTextMessage(conversation, user int, m core.TextMessage) (int, error) {
+	r.stored++
+	if r.stored == r.failAt {
+		return 0, errors.New("connection lost")
+	}
+	return r.stored, nil
+}
+
+func (r *fakeMessageRepo) SetReadFlags(userID, conversationID, upToMessageID int, readDate time.Time) (int, error) {
+	return 0, nil
+}
+
+type fakeMessagingService struct {
+	messaging.Service
+	cleared []int
+}
+
+func (s *fakeMessagingService) DeleteExpiredMessages(
+	conversationID int,
+	sentBefore time.Time,
+	pathPrefix string,
+	pusher core.Pusher,
+	ctx context.Context) (int, error) {
+
+	s.cleared = append(s.cleared, conversationID)
+	return 0, nil
+}
+
+func TestImportRemovesFailedChannel(t *testing.T) {
+	jsonl := `{"type":"version","version":1}
+{"type":"channel","channel":{"team":"dev","name":"town-square","display_name":"Town Square"}}
+{"type":"user","user":{"username":"alice","email":"alice@example.com","teams":[{"name":"dev","channels":[{"name":"town-square"}]}]}}
+{"type":"post","post":{"team":"dev","channel":"town-square","user":"alice","message":"Hello","create_at":1588320000000,"replies":[{"user":"alice","message":"Hi","create_at":1588320060000}]}}
+`
+	conversationRepo := &fakeConversationRepo{}
+	messagingService := &fakeMessagingService{}
+	s := &service{
+		userRepo:         &fakeUserRepo{users: []core.User{{ID: 1, Name: "admin", Email: "admin@example.com"}}},
+		conversationRepo: conversationRepo,
+		messageRepo:      &fakeMessageRepo{failAt: 2},
+		messagingService: messagingService,
+		cfg:              Config{Admins: []string{"admin"}},
+	}
+
+	result, err := s.Import(1, MattermostFormat, strings.NewReader(jsonl), int64(len(jsonl)))
+	if err == nil {
+		t.Fatal("import succeeded")
+	}
+
+	if len(result.Conversations) != 0 || result.Messages != 0 {
+		t.Errorf("unexpected result %#v", result)
+	}
+
+	if len(conversationRepo.deleted) != 1 || conversationRepo.deleted[0] != 10 ||
+		len(messagingService.cleared) != 1 || messagingService.cleared[0] != 10 {
+		t.Errorf("conversation was not removed: %v %v", conversationRepo.deleted, messagingService.cleared)
+	}
+}
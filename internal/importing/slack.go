@@ -0,0 +1,228 @@
+package importing
+
+import (
+	"archive/zip"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+// importedSlackSubtypes are the subtypes of Slack messages that have been written by a
+// user. All other subtypes are notifications like joins or topic changes.
+var importedSlackSubtypes = map[string]bool{
+	"":                 true,
+	"thread_broadcast": true,
+	"file_share":       true,
+	"me_message":       true,
+}
+
+type slackUser struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Profile struct {
+		Email string `json:"email"`
+	} `json:"profile"`
+}
+
+type slackChannel struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+type slackMessage struct {
+	Type     string `json:"type"`
+	Subtype  string `json:"subtype"`
+	User     string `json:"user"`
+	Text     string `json:"text"`
+	TS       string `json:"ts"`
+	ThreadTS string `json:"thread_ts"`
+	Files    []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"files"`
+}
+
+// parseSlack reads a Slack workspace export. Public and private channels as well as
+// direct messages are imported. Slack does not include attachments in its exports,
+// they are only imported if they have been added as __uploads/<file id>/<name>.
+func parseSlack(zr *zip.Reader) (source, error) {
+	index := zipIndex(zr)
+
+	var users []slackUser
+	err := readZipJSON(index, "users.json", &users)
+	if err == errMissingFile {
+		return source{}, core.NewInvalidValueError("export")
+	}
+
+	if err != nil {
+		return source{}, err
+	}
+
+	src := source{users: make(map[string]sourceUser, len(users))}
+	for _, u := range users {
+		src.users[u.ID] = sourceUser{Name: u.Name, Email: u.Profile.Email}
+	}
+
+	// Channels, private channels and group messages are stored in folders named after
+	// them, direct messages in folders named after their id.
+	for _, fileName := range []string{"channels.json", "groups.json", "mpims.json", "dms.json"} {
+		var channels []slackChannel
+		err := readZipJSON(index, fileName, &channels)
+		if err == errMissingFile {
+			continue
+		}
+
+		if err != nil {
+			return source{}, err
+		}
+
+		for _, c := range channels {
+			folder, title := c.Name, c.Name
+			if fileName == "dms.json" {
+				folder, title = c.ID, slackDirectMessageTitle(c.Members, src.users)
+			}
+
+			channel, err := parseSlackChannel(index, folder, src.users)
+			if err != nil {
+				return source{}, err
+			}
+			channel.Title = title
+			channel.Members = c.Members
+			src.channels = append(src.channels, channel)
+		}
+	}
+
+	if len(src.channels) == 0 {
+		return source{}, core.NewInvalidValueError("export")
+	}
+	return src, nil
+}
+
+func parseSlackChannel(index map[string]*zip.File, folder string, users map[string]sourceUser) (sourceChannel, error) {
+	days := make([]string, 0, 10)
+	for name := range index {
+		if path.Dir(name) == folder && path.Ext(name) == ".json" {
+			days = append(days, name)
+		}
+	}
+	sort.Strings(days)
+
+	channel := sourceChannel{}
+	for _, day := range days {
+		var messages []slackMessage
+		err := readZipJSON(index, day, &messages)
+		if err != nil {
+			return sourceChannel{}, err
+		}
+
+		for _, m := range messages {
+			if m.Type != "message" || !importedSlackSubtypes[m.Subtype] || m.User == "" {
+				continue
+			}
+
+			date, err := parseSlackTimestamp(m.TS)
+			if err != nil {
+				return sourceChannel{}, core.NewInvalidValueError("ts")
+			}
+
+			post := sourcePost{
+				Key:  m.TS,
+				User: m.User,
+				Text: slackToMarkdown(m.Text, users),
+				Date: date,
+			}
+			if m.ThreadTS != "" && m.ThreadTS != m.TS {
+				post.ThreadKey = m.ThreadTS
+			}
+
+			for _, f := range m.Files {
+				post.Files = append(post.Files, sourceFile{
+					Name: path.Base(f.Name),
+					file: index[path.Join("__uploads", f.ID, path.Base(f.Name))],
+				})
+			}
+			channel.Posts = append(channel.Posts, post)
+		}
+	}
+	channel.sortPosts()
+	return channel, nil
+}
+
+func slackDirectMessageTitle(members []string, users map[string]sourceUser) string {
+	names := make([]string, 0, len(members))
+	for _, member := range members {
+		if u, ok := users[member]; ok {
+			names = append(names, u.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// parseSlackTimestamp parses the ts field of Slack messages, which consists of the
+// seconds since the epoch and a unique suffix of microseconds.
+func parseSlackTimestamp(ts string) (time.Time, error) {
+	parts := strings.SplitN(ts, ".", 2)
+	seconds, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var micros int64
+	if len(parts) == 2 {
+		micros, err = strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Unix(seconds, micros*int64(time.Microsecond)).UTC(), nil
+}
+
+var slackControlSequence = regexp.MustCompile(`<([^<>\n]+)>`)
+
+var slackEntities = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+
+// slackToMarkdown converts the mrkdwn of Slack into Markdown. Mentions, channel
+// references and links are resolved and code fences are put onto lines of their own.
+func slackToMarkdown(text string, users map[string]sourceUser) string {
+	text = slackControlSequence.ReplaceAllStringFunc(text, func(match string) string {
+		content := match[1 : len(match)-1]
+		target, label := content, ""
+		if i := strings.Index(content, "|"); i != -1 {
+			target, label = content[:i], content[i+1:]
+		}
+
+		switch {
+		case strings.HasPrefix(target, "@"):
+			if u, ok := users[target[1:]]; ok {
+				return "@" + u.Name
+			}
+			if label != "" {
+				return "@" + label
+			}
+			return target
+		case strings.HasPrefix(target, "#"):
+			if label != "" {
+				return "#" + label
+			}
+			return target
+		case strings.HasPrefix(target, "!"):
+			if label != "" {
+				return label
+			}
+			return "@" + target[1:]
+		case label != "":
+			return "[" + label + "](" + target + ")"
+		default:
+			return target
+		}
+	})
+
+	text = slackEntities.Replace(text)
+	return strings.ReplaceAll(text, "```", "\n```\n")
+}
@@ -0,0 +1,163 @@
+package importing
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+// source is the content of an export independent of the chat system it stems from.
+// Users are referenced by a key that is unique within the export.
+type source struct {
+	users    map[string]sourceUser
+	channels []sourceChannel
+}
+
+type sourceUser struct {
+	Name  string
+	Email string
+}
+
+type sourceChannel struct {
+	Title   string
+	Members []string
+	Posts   []sourcePost
+}
+
+// sourcePost is a message of the export. Replies reference the key of the first post
+// of their thread.
+type sourcePost struct {
+	Key       string
+	User      string
+	Text      string
+	Date      time.Time
+	ThreadKey string
+	Files     []sourceFile
+}
+
+// sourceFile is an attachment of a post. file is nil if the export only contains a
+// reference to the attachment.
+type sourceFile struct {
+	Name string
+	file *zip.File
+}
+
+// participants returns the members of the channel and everybody who has posted into it.
+func (c sourceChannel) participants() []string {
+	seen := make(map[string]bool)
+	participants := make([]string, 0, len(c.Members))
+	add := func(key string) {
+		if key != "" && !seen[key] {
+			seen[key] = true
+			participants = append(participants, key)
+		}
+	}
+
+	for _, member := range c.Members {
+		add(member)
+	}
+	for _, post := range c.Posts {
+		add(post.User)
+	}
+	return participants
+}
+
+func (c *sourceChannel) sortPosts() {
+	sort.SliceStable(c.Posts, func(i, j int) bool {
+		return c.Posts[i].Date.Before(c.Posts[j].Date)
+	})
+}
+
+// segment is a part of the text of a post. Fenced code blocks become segments of
+// their own so that they can be imported as code messages.
+type segment struct {
+	IsCode   bool
+	Language string
+	Text     string
+}
+
+// splitCodeBlocks splits Markdown at its fenced code blocks. A fence that is never
+// closed is kept as text.
+func splitCodeBlocks(text string) []segment {
+	segments := make([]segment, 0, 1)
+	var current []string
+	flushText := func() {
+		t := strings.Trim(strings.Join(current, "\n"), "\n")
+		if strings.TrimSpace(t) != "" {
+			segments = append(segments, segment{Text: t})
+		}
+		current = nil
+	}
+
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(trimmed, "```") {
+			current = append(current, lines[i])
+			continue
+		}
+
+		end := -1
+		for j := i + 1; j < len(lines); j++ {
+			if strings.TrimSpace(lines[j]) == "```" {
+				end = j
+				break
+			}
+		}
+
+		if end == -1 {
+			current = append(current, lines[i:]...)
+			break
+		}
+
+		flushText()
+		segments = append(segments, segment{
+			IsCode:   true,
+			Language: strings.TrimSpace(strings.TrimPrefix(trimmed, "```")),
+			Text:     strings.Join(lines[i+1:end], "\n"),
+		})
+		i = end
+	}
+	flushText()
+	return segments
+}
+
+// zipIndex maps the cleaned paths within the archive to its files.
+func zipIndex(zr *zip.Reader) map[string]*zip.File {
+	index := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		index[cleanZipPath(f.Name)] = f
+	}
+	return index
+}
+
+func cleanZipPath(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	return strings.TrimPrefix(strings.TrimPrefix(name, "./"), "/")
+}
+
+var errMissingFile = errors.New("The export does not contain the file")
+
+func readZipJSON(index map[string]*zip.File, name string, v interface{}) error {
+	f, ok := index[name]
+	if !ok {
+		return errMissingFile
+	}
+
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	err = json.NewDecoder(r).Decode(v)
+	if err != nil {
+		return core.NewJSONFormatError(name + ": " + err.Error())
+	}
+	return nil
+}
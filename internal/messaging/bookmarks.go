@@ -0,0 +1,92 @@
+package messaging
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+const (
+	maxBookmarksLimit     = 100
+	maxBookmarkNoteLength = 500
+	maxBookmarkTags       = 10
+	maxBookmarkTagLength  = 30
+)
+
+// ListBookmarks returns the bookmarks of the user across all conversations, newest first.
+func (s *service) ListBookmarks(userCtx, beforeID, limit int, tag string) ([]core.Bookmark, error) {
+	if limit <= 0 || limit > maxBookmarksLimit {
+		return nil, core.NewInvalidValueError("limit")
+	}
+
+	return s.messageRepo.FindBookmarksForUser(userCtx, beforeID, limit, strings.ToLower(strings.TrimSpace(tag)))
+}
+
+// SaveBookmark bookmarks a message. Bookmarking a message again replaces note and tags.
+func (s *service) SaveBookmark(userCtx int, bookmark core.Bookmark) (core.Bookmark, error) {
+	err := s.errorIFIsNotInConversation(userCtx, bookmark.ConversationID)
+	if err != nil {
+		return core.Bookmark{}, err
+	}
+
+	_, err = s.messageRepo.FindMessageStubForConversation(bookmark.ConversationID, bookmark.MessageID)
+	if err != nil {
+		return core.Bookmark{}, err
+	}
+
+	bookmark.Note = strings.TrimSpace(bookmark.Note)
+	if utf8.RuneCountInString(bookmark.Note) > maxBookmarkNoteLength {
+		return core.Bookmark{}, core.NewInvalidValueError("note")
+	}
+
+	bookmark.Tags, err = normalizeTags(bookmark.Tags)
+	if err != nil {
+		return core.Bookmark{}, err
+	}
+
+	err = s.messageRepo.StoreBookmark(userCtx, bookmark)
+	if err != nil {
+		return core.Bookmark{}, err
+	}
+
+	return s.messageRepo.FindBookmark(userCtx, bookmark.MessageID)
+}
+
+func (s *service) DeleteBookmark(userCtx, conversationID, messageID int) error {
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return err
+	}
+
+	return s.messageRepo.DeleteBookmark(userCtx, messageID)
+}
+
+// normalizeTags trims and lower cases the tags and removes duplicates.
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || utf8.RuneCountInString(tag) > maxBookmarkTagLength {
+			return nil, core.NewInvalidValueError("tags")
+		}
+
+		if !containsString(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+
+	if len(normalized) > maxBookmarkTags {
+		return nil, core.NewInvalidValueError("tags")
+	}
+	return normalized, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
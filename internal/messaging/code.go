@@ -0,0 +1,222 @@
+package messaging
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+const (
+	maxCodeFiles          = 20
+	maxCodeFileNameLength = 100
+)
+
+// fileExtensions maps the lower case names of programming languages to the extensions
+// of their source files.
+var fileExtensions = map[string]string{
+	"bash":           ".sh",
+	"c":              ".c",
+	"c#":             ".cs",
+	"c# (.net core)": ".cs",
+	"c++":            ".cpp",
+	"coffee script":  ".coffee",
+	"cpp":            ".cpp",
+	"csharp":         ".cs",
+	"css":            ".css",
+	"dart":           ".dart",
+	"f#":             ".fs",
+	"go":             ".go",
+	"graphql":        ".graphql",
+	"groovy":         ".groovy",
+	"haskell":        ".hs",
+	"html":           ".html",
+	"java":           ".java",
+	"javascript":     ".js",
+	"json":           ".json",
+	"kotlin":         ".kt",
+	"lua":            ".lua",
+	"markdown":       ".md",
+	"matlab":         ".m",
+	"objective-c":    ".m",
+	"perl":           ".pl",
+	"php":            ".php",
+	"pl/sql":         ".sql",
+	"python":         ".py",
+	"r":              ".r",
+	"ruby":           ".rb",
+	"rust":           ".rs",
+	"scala":          ".scala",
+	"shell":          ".sh",
+	"sql":            ".sql",
+	"swift":          ".swift",
+	"typescript":     ".ts",
+	"webassembly":    ".wat",
+	"xml":            ".xml",
+	"yaml":           ".yaml",
+}
+
+// FileExtension returns the extension of source files of the language or .txt if it is unknown.
+func FileExtension(language string) string {
+	if ext, ok := fileExtensions[strings.ToLower(language)]; ok {
+		return ext
+	}
+	return ".txt"
+}
+
+// CodeFiles returns all files of the code message starting with the main file. Main files
+// without a name, e.g. those of messages created before snippets could span several files,
+// are named after the title.
+func CodeFiles(m core.CodeMessage) []core.CodeFile {
+	name := m.FileName
+	if name == "" {
+		name = FileSlug(m.Title, "code") + FileExtension(m.Language)
+	}
+
+	files := make([]core.CodeFile, 0, len(m.Files)+1)
+	files = append(files, core.CodeFile{Name: name, Language: m.Language, Code: m.Code})
+	return append(files, m.Files...)
+}
+
+// setCodeFiles is the counterpart of CodeFiles. The first file becomes the main file.
+func setCodeFiles(m *core.CodeMessage, files []core.CodeFile) {
+	m.FileName, m.Language, m.Code = files[0].Name, files[0].Language, files[0].Code
+	m.Files = append(make([]core.CodeFile, 0, len(files)-1), files[1:]...)
+}
+
+// prepareCodeMessage detects missing languages and validates the files of a new code message.
+func (s *service) prepareCodeMessage(message *core.CodeMessage) error {
+	message.FileName = strings.TrimSpace(message.FileName)
+	if message.Files == nil {
+		message.Files = make([]core.CodeFile, 0)
+	}
+
+	err := s.detectMissingLanguages(message)
+	if err != nil {
+		return err
+	}
+	return s.validateCodeFiles(*message)
+}
+
+// validateCodeFiles checks that the names of all files are unique and can be used as file
+// names on every system. Only the main file may come without a name. The language of the
+// main file is checked by the database.
+func (s *service) validateCodeFiles(message core.CodeMessage) error {
+	if len(message.Files)+1 > maxCodeFiles {
+		return core.NewInvalidValueError("files")
+	}
+
+	if message.FileName != "" && !isValidCodeFileName(message.FileName) {
+		return core.NewInvalidValueError("fileName")
+	}
+
+	names := make(map[string]bool, len(message.Files)+1)
+	for _, f := range CodeFiles(message) {
+		if names[f.Name] {
+			return core.NewInvalidValueError("files")
+		}
+		names[f.Name] = true
+	}
+
+	if len(message.Files) == 0 {
+		return nil
+	}
+
+	languages, err := s.messageRepo.FindAllProgrammingLanguages()
+	if err != nil {
+		return err
+	}
+
+	for _, f := range message.Files {
+		if !isValidCodeFileName(f.Name) || !isKnownLanguage(f.Language, languages) {
+			return core.NewInvalidValueError("files")
+		}
+	}
+	return nil
+}
+
+func isValidCodeFileName(name string) bool {
+	if name == "" || name == "." || name == ".." || utf8.RuneCountInString(name) > maxCodeFileNameLength {
+		return false
+	}
+
+	if strings.TrimSpace(name) != name {
+		return false
+	}
+
+	for _, r := range name {
+		if r == '/' || r == '\\' || r == '"' || unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isKnownLanguage(language string, languages []core.ProgrammingLanguage) bool {
+	for _, l := range languages {
+		if l.Name == language {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCodeFile returns a file of a code message. The main file has the index 0.
+func (s *service) GetCodeFile(userCtx, conversationID, messageID, index int) (core.CodeFile, error) {
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return core.CodeFile{}, err
+	}
+
+	message, err := s.messageRepo.FindCodeMessageForID(messageID, conversationID)
+	if err != nil {
+		return core.CodeFile{}, err
+	}
+
+	files := CodeFiles(message)
+	if index < 0 || index >= len(files) {
+		return core.CodeFile{}, core.ErrRessourceDoesNotExist
+	}
+	return files[index], nil
+}
+
+// GetCodeArchive returns a ZIP archive of all files of a code message together with a
+// file name for it.
+func (s *service) GetCodeArchive(userCtx, conversationID, messageID int) ([]byte, string, error) {
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	message, err := s.messageRepo.FindCodeMessageForID(messageID, conversationID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range CodeFiles(message) {
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.Name,
+			Method:   zip.Deflate,
+			Modified: message.Sentdate,
+		})
+		if err != nil {
+			return nil, "", err
+		}
+
+		_, err = w.Write([]byte(f.Code))
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	err = zw.Close()
+	if err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), FileSlug(message.Title, "code") + ".zip", nil
+}
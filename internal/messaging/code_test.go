@@ -0,0 +1,50 @@
+package messaging
+
+import (
+	"testing"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+func TestCodeFiles(t *testing.T) {
+	message := core.CodeMessage{
+		Title:    "HTTP handler",
+		Language: "Go",
+		Code:     "package main",
+		Files:    []core.CodeFile{{Name: "handler_test.go", Language: "Go", Code: "package main_test"}},
+	}
+
+	files := CodeFiles(message)
+	if len(files) != 2 || files[0].Name != "HTTP-handler.go" || files[1].Name != "handler_test.go" {
+		t.Fatalf("unexpected files %#v", files)
+	}
+
+	files[0].Name = "handler.go"
+	files = append(files[:1], core.CodeFile{Name: "config.yaml", Language: "YAML", Code: "port: 80"})
+	setCodeFiles(&message, files)
+	if message.FileName != "handler.go" || message.Code != "package main" || len(message.Files) != 1 || message.Files[0].Name != "config.yaml" {
+		t.Errorf("unexpected message %#v", message)
+	}
+}
+
+func TestIsValidCodeFileName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"main.go", true},
+		{"Makefile", true},
+		{"", false},
+		{"..", false},
+		{"cmd/main.go", false},
+		{"..\\main.go", false},
+		{" main.go", false},
+		{"a\"b", false},
+	}
+
+	for _, test := range tests {
+		if valid := isValidCodeFileName(test.name); valid != test.valid {
+			t.Errorf("isValidCodeFileName(%q) = %v, want %v", test.name, valid, test.valid)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package messaging
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const diffContextLines = 3
+
+type diffLine struct {
+	operation diffmatchpatch.Operation
+	text      string
+}
+
+// unifiedDiff returns the line based difference between a and b in the unified diff format.
+// An empty string is returned if both texts are equal.
+func unifiedDiff(fromName, toName, a, b string) string {
+	dmp := diffmatchpatch.New()
+	charsA, charsB, lineArray := dmp.DiffLinesToChars(a, b)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(charsA, charsB, false), lineArray)
+
+	lines := make([]diffLine, 0, len(diffs))
+	for _, d := range diffs {
+		for _, text := range splitLines(d.Text) {
+			lines = append(lines, diffLine{d.Type, text})
+		}
+	}
+
+	// linesBefore[i] holds the number of lines of a and b preceding lines[i].
+	linesBefore := make([][2]int, len(lines)+1)
+	for i, l := range lines {
+		linesBefore[i+1] = linesBefore[i]
+		if l.operation != diffmatchpatch.DiffInsert {
+			linesBefore[i+1][0]++
+		}
+		if l.operation != diffmatchpatch.DiffDelete {
+			linesBefore[i+1][1]++
+		}
+	}
+
+	var builder strings.Builder
+	hunkEnd := 0
+	for i := 0; i < len(lines); i++ {
+		if lines[i].operation == diffmatchpatch.DiffEqual {
+			continue
+		}
+
+		start := i - diffContextLines
+		if start < hunkEnd {
+			start = hunkEnd
+		}
+
+		lastChange := i
+		for j := i + 1; j < len(lines) && j-lastChange <= 2*diffContextLines; j++ {
+			if lines[j].operation != diffmatchpatch.DiffEqual {
+				lastChange = j
+			}
+		}
+
+		hunkEnd = lastChange + diffContextLines + 1
+		if hunkEnd > len(lines) {
+			hunkEnd = len(lines)
+		}
+
+		if builder.Len() == 0 {
+			fmt.Fprintf(&builder, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		writeHunk(&builder, lines[start:hunkEnd], linesBefore[start], linesBefore[hunkEnd])
+		i = hunkEnd - 1
+	}
+
+	return builder.String()
+}
+
+func writeHunk(builder *strings.Builder, lines []diffLine, before, after [2]int) {
+	fmt.Fprintf(builder, "@@ -%s +%s @@\n",
+		hunkRange(before[0], after[0]-before[0]),
+		hunkRange(before[1], after[1]-before[1]))
+
+	for _, l := range lines {
+		prefix := " "
+		switch l.operation {
+		case diffmatchpatch.DiffDelete:
+			prefix = "-"
+		case diffmatchpatch.DiffInsert:
+			prefix = "+"
+		}
+
+		builder.WriteString(prefix)
+		builder.WriteString(l.text)
+		if !strings.HasSuffix(l.text, "\n") {
+			builder.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(linesBefore, count int) string {
+	start := linesBefore + 1
+	if count == 0 {
+		start = linesBefore
+	}
+
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits a text into lines. Each line keeps its line break.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
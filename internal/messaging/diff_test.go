@@ -0,0 +1,67 @@
+package messaging
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        string
+		b        string
+		expected string
+	}{
+		{
+			name:     "equal",
+			a:        "a\nb\n",
+			b:        "a\nb\n",
+			expected: "",
+		},
+		{
+			name: "changed line",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			b:    "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			expected: "--- a\n+++ b\n" +
+				"@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "separate hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			b:    "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			expected: "--- a\n+++ b\n" +
+				"@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n" +
+				"@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+		{
+			name: "insertion into empty text",
+			a:    "",
+			b:    "new",
+			expected: "--- a\n+++ b\n" +
+				"@@ -0,0 +1 @@\n+new\n\\ No newline at end of file\n",
+		},
+	}
+
+	for _, test := range tests {
+		diff := unifiedDiff("a", "b", test.a, test.b)
+		if diff != test.expected {
+			t.Errorf("%s: expected\n%q\ngot\n%q", test.name, test.expected, diff)
+		}
+	}
+}
+
+func TestFileSlug(t *testing.T) {
+	tests := []struct {
+		title string
+		slug  string
+	}{
+		{"Main file", "Main-file"},
+		{"../../etc/passwd", "etc-passwd"},
+		{"Über", "ber"},
+		{"Fix parser.go", "Fix-parser.go"},
+		{"...", "fallback"},
+	}
+
+	for _, tt := range tests {
+		if slug := FileSlug(tt.title, "fallback"); slug != tt.slug {
+			t.Errorf("FileSlug(%q) = %q, want %q", tt.title, slug, tt.slug)
+		}
+	}
+}
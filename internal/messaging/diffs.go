@@ -0,0 +1,126 @@
+package messaging
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+const maxDiffTitleLength = 200
+
+// prepareDiffMessage parses the patch of a new diff message and fills in the file metadata.
+// If no title is given, the subject of the patch mail or the changed file is used.
+func prepareDiffMessage(message *core.DiffMessage) error {
+	if len(message.Patch) > maxPatchSize {
+		return core.NewInvalidValueError("patch")
+	}
+
+	patch, err := parsePatch(message.Patch)
+	if err != nil {
+		return err
+	}
+
+	title := strings.TrimSpace(message.Title)
+	if title == "" {
+		title = patch.Subject
+	}
+
+	if title == "" {
+		if len(patch.Files) == 1 {
+			title = patch.Files[0].NewPath
+			if title == "" {
+				title = patch.Files[0].OldPath
+			}
+		} else {
+			title = strconv.Itoa(len(patch.Files)) + " files"
+		}
+	}
+
+	if utf8.RuneCountInString(title) > maxDiffTitleLength {
+		title = string([]rune(title)[:maxDiffTitleLength])
+	}
+	message.Title = title
+
+	message.Files = make([]core.DiffFile, len(patch.Files))
+	for i, f := range patch.Files {
+		f.Hunks = nil
+		message.Files[i] = f
+	}
+	message.Additions, message.Deletions = patch.lineCounts()
+
+	return nil
+}
+
+// GetPatchOfMessage returns the original patch of a diff message together with a file name for it.
+func (s *service) GetPatchOfMessage(userCtx, conversationID, messageID int) (string, string, error) {
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return "", "", err
+	}
+
+	message, err := s.messageRepo.FindDiffMessageForID(messageID, conversationID)
+	if err != nil {
+		return "", "", err
+	}
+
+	patch, err := s.messageRepo.FindPatchOfDiffMessage(messageID, conversationID)
+	if err != nil {
+		return "", "", err
+	}
+
+	return patch, patchFileName(message.Title), nil
+}
+
+// GetDiffOfMessage returns the files of a diff message including all hunks.
+func (s *service) GetDiffOfMessage(userCtx, conversationID, messageID int) ([]core.DiffFile, error) {
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	patch, err := s.messageRepo.FindPatchOfDiffMessage(messageID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := parsePatch(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsed.Files, nil
+}
+
+// patchFileName derives a file name from the title the way git format-patch does.
+func patchFileName(title string) string {
+	return FileSlug(title, "changes") + ".patch"
+}
+
+// FileSlug turns the title into a portable file name. The fallback is used if nothing
+// of the title remains.
+func FileSlug(title, fallback string) string {
+	var b strings.Builder
+	lastWasDash := true
+	for _, r := range title {
+		isAllowed := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '.'
+		if isAllowed {
+			b.WriteRune(r)
+			lastWasDash = false
+		} else if !lastWasDash {
+			b.WriteByte('-')
+			lastWasDash = true
+		}
+
+		if b.Len() >= 52 {
+			break
+		}
+	}
+
+	name := strings.Trim(b.String(), "-.")
+	if name == "" {
+		name = fallback
+	}
+	return name
+}
@@ -0,0 +1,78 @@
+package messaging
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+const maxDraftLength = 20000
+
+func (s *service) ListDrafts(userCtx int) ([]core.Draft, error) {
+	return s.messageRepo.FindDraftsForUser(userCtx)
+}
+
+func (s *service) GetDraft(userCtx, conversationID int) (core.Draft, error) {
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return core.Draft{}, err
+	}
+
+	return s.messageRepo.FindDraft(userCtx, conversationID)
+}
+
+// SaveDraft stores the draft of a conversation and sends it to all connections of the user.
+// Saving a draft without text clears it.
+func (s *service) SaveDraft(userCtx int, draft core.Draft, pusher core.Pusher, ctx context.Context) (core.Draft, error) {
+	err := s.errorIFIsNotInConversation(userCtx, draft.ConversationID)
+	if err != nil {
+		return core.Draft{}, err
+	}
+
+	if strings.TrimSpace(draft.Text) == "" {
+		err = s.ClearDraft(userCtx, draft.ConversationID, pusher, ctx)
+		if err != nil && err != core.ErrNothingChanged {
+			return core.Draft{}, err
+		}
+		return core.Draft{ConversationID: draft.ConversationID}, nil
+	}
+
+	if utf8.RuneCountInString(draft.Text) > maxDraftLength {
+		return core.Draft{}, core.NewInvalidValueError("text")
+	}
+
+	if draft.ParentID != 0 {
+		_, err = s.messageRepo.FindMessageStubForConversation(draft.ConversationID, draft.ParentID)
+		if err != nil {
+			return core.Draft{}, err
+		}
+	}
+
+	draft.UpdateDate = time.Now().UTC()
+	err = s.messageRepo.StoreDraft(userCtx, draft)
+	if err != nil {
+		return core.Draft{}, err
+	}
+
+	pusher.Unicast(ctx, userCtx, draft)
+	return draft, nil
+}
+
+// ClearDraft deletes the draft of a conversation on all devices of the user.
+func (s *service) ClearDraft(userCtx, conversationID int, pusher core.Pusher, ctx context.Context) error {
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return err
+	}
+
+	err = s.messageRepo.DeleteDraft(userCtx, conversationID)
+	if err != nil {
+		return err
+	}
+
+	pusher.Unicast(ctx, userCtx, core.Draft{ConversationID: conversationID})
+	return nil
+}
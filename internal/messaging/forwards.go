@@ -0,0 +1,118 @@
+package messaging
+
+import (
+	"context"
+	"time"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+// resolveQuote returns the preview of a quoted message. Only messages of the same conversation can be quoted.
+func (s *service) resolveQuote(conversationID, quotedID int) (*core.MessagePreview, error) {
+	if quotedID == 0 {
+		return nil, nil
+	}
+
+	preview, err := s.messageRepo.FindMessagePreview(conversationID, quotedID)
+	if err == core.ErrRessourceDoesNotExist {
+		return nil, core.NewInvalidValueError("quotedId")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &preview, nil
+}
+
+// ForwardMessage copies a text, code or media message into another conversation of the user.
+// The copy records the message it originates from. Media objects of the copy share the files of the original.
+func (s *service) ForwardMessage(
+	userCtx, conversationID, messageID, targetConversationID int,
+	pusher core.Pusher,
+	ctx context.Context) (interface{}, error) {
+
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.errorIFIsNotInConversation(userCtx, targetConversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := s.findMessage(conversationID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	var copyID int
+	switch m := original.(type) {
+	case core.TextMessage:
+		m.Message = forwardedMessage(m.Message, conversationID)
+		copyID, err = s.messageRepo.StoreTextMessage(targetConversationID, userCtx, m)
+	case core.CodeMessage:
+		m.Message = forwardedMessage(m.Message, conversationID)
+		copyID, err = s.messageRepo.StoreCodeMessage(targetConversationID, userCtx, m)
+	case core.MediaMessage:
+		m.Message = forwardedMessage(m.Message, conversationID)
+		copyID, err = s.forwardMediaMessage(targetConversationID, userCtx, messageID, m)
+	default:
+		return nil, core.ErrInvalidMessageType
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	answer, err := s.findMessage(targetConversationID, copyID)
+	if err != nil {
+		return nil, err
+	}
+
+	pusher.BroadcastToRoom(targetConversationID, answer, ctx)
+	return answer, nil
+}
+
+func (s *service) forwardMediaMessage(
+	targetConversationID, userCtx, originalID int,
+	message core.MediaMessage) (int, error) {
+
+	files, err := s.messageRepo.FindMediaObjectsForMessage(originalID)
+	if err != nil {
+		return 0, err
+	}
+
+	copyID, err := s.messageRepo.StoreMediaMessage(targetConversationID, userCtx, message)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, file := range files {
+		_, err = s.messageRepo.CopyMediaObject(copyID, file)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return copyID, s.messageRepo.UpdateCompleteFlag(copyID)
+}
+
+// forwardedMessage prepares the copy of a message. A message that is forwarded again keeps its first origin.
+func forwardedMessage(original core.Message, conversationID int) core.Message {
+	origin := original.Origin
+	if origin == nil {
+		origin = &core.MessageOrigin{
+			MessageID:      original.ID,
+			ConversationID: conversationID,
+			Author:         original.Author,
+			Sentdate:       original.Sentdate,
+		}
+	}
+
+	return core.Message{
+		Type:     original.Type,
+		Sentdate: time.Now().UTC(),
+		Origin:   origin,
+	}
+}
@@ -0,0 +1,184 @@
+package messaging
+
+import (
+	"encoding/base64"
+	"sort"
+	"strconv"
+	"strings"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+// MaxHistoryLimit is the largest number of messages a page of the history can hold.
+const MaxHistoryLimit = 100
+
+// ListAllMessages returns a page of the top level messages of a conversation. Pages around
+// an anchor are split evenly between the messages preceding and following it.
+func (s *service) ListAllMessages(userID, conversationID int, query core.HistoryQuery) (core.HistoryPage, error) {
+	err := s.errorIFIsNotInConversation(userID, conversationID)
+	if err != nil {
+		return core.HistoryPage{}, err
+	}
+
+	if query.Limit <= 0 || query.Limit > MaxHistoryLimit {
+		return core.HistoryPage{}, core.NewInvalidValueError("limit")
+	}
+
+	if query.Cursor != "" {
+		query.Direction, query.AnchorID, err = decodeCursor(query.Cursor)
+		if err != nil {
+			return core.HistoryPage{}, err
+		}
+	}
+
+	if query.AnchorID < 0 || (query.Direction != core.HistoryBefore && query.AnchorID == core.MaxInt) {
+		return core.HistoryPage{}, core.NewInvalidValueError("anchor")
+	}
+
+	var page core.HistoryPage
+	switch query.Direction {
+	case core.HistoryBefore:
+		page.Messages, page.HasMoreBefore, err = s.findMessageRange(conversationID, query.Type, core.MessageRange{
+			BeforeID: query.AnchorID,
+			Limit:    query.Limit,
+		})
+		if err != nil {
+			return core.HistoryPage{}, err
+		}
+
+		page.HasMoreAfter, err = s.messageRepo.HasMessagesInRange(conversationID, core.MessageRange{
+			AfterID:  query.AnchorID - 1,
+			BeforeID: core.MaxInt,
+		}, query.Type)
+	case core.HistoryAfter:
+		page.Messages, page.HasMoreAfter, err = s.findMessageRange(conversationID, query.Type, core.MessageRange{
+			AfterID:  query.AnchorID,
+			BeforeID: core.MaxInt,
+			Limit:    query.Limit,
+			Forward:  true,
+		})
+		if err != nil {
+			return core.HistoryPage{}, err
+		}
+
+		page.HasMoreBefore, err = s.messageRepo.HasMessagesInRange(conversationID, core.MessageRange{
+			BeforeID: query.AnchorID + 1,
+		}, query.Type)
+	case core.HistoryAround:
+		var following []interface{}
+		page.Messages, page.HasMoreBefore, err = s.findMessageRange(conversationID, query.Type, core.MessageRange{
+			BeforeID: query.AnchorID + 1,
+			Limit:    query.Limit - query.Limit/2,
+		})
+		if err != nil {
+			return core.HistoryPage{}, err
+		}
+
+		following, page.HasMoreAfter, err = s.findMessageRange(conversationID, query.Type, core.MessageRange{
+			AfterID:  query.AnchorID,
+			BeforeID: core.MaxInt,
+			Limit:    query.Limit / 2,
+			Forward:  true,
+		})
+		page.Messages = append(page.Messages, following...)
+	default:
+		return core.HistoryPage{}, core.NewInvalidValueError("direction")
+	}
+	if err != nil {
+		return core.HistoryPage{}, err
+	}
+
+	if len(page.Messages) > 0 {
+		page.BeforeCursor = encodeCursor(core.HistoryBefore, sequenceNumber(page.Messages[0]))
+		page.AfterCursor = encodeCursor(core.HistoryAfter, sequenceNumber(page.Messages[len(page.Messages)-1]))
+	}
+	return page, nil
+}
+
+// findMessageRange returns the messages of the range in chronological order and whether
+// the range contains further messages beyond the limit.
+func (s *service) findMessageRange(
+	conversationID int,
+	mType core.MessageType,
+	window core.MessageRange) ([]interface{}, bool, error) {
+
+	limit := window.Limit
+	window.Limit++
+	messages, nRows, err := s.findMessages(conversationID, mType, window)
+	if err != nil {
+		return nil, false, err
+	}
+
+	sort.Slice(messages, func(i, j int) bool {
+		return sequenceNumber(messages[i]) < sequenceNumber(messages[j])
+	})
+
+	if len(messages) > limit && window.Forward {
+		messages = messages[:limit]
+	} else if len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+	return messages, nRows > limit, nil
+}
+
+// findMessages returns the messages of the range and the number of rows the range matched
+// up to its limit, which may exceed the number of messages.
+func (s *service) findMessages(
+	conversationID int,
+	mType core.MessageType,
+	window core.MessageRange) ([]interface{}, int, error) {
+
+	var messages []interface{}
+	var err error
+	switch mType {
+	case core.CodeMessageType:
+		messages, err = s.messageRepo.FindCodeMessagesForConversation(conversationID, window)
+	case core.TextMessageType:
+		messages, err = s.messageRepo.FindTextMessagesForConversation(conversationID, window)
+	case core.MediaMessageType:
+		messages, err = s.messageRepo.FindMediaMessagesForConversation(conversationID, window)
+	case core.PollMessageType:
+		messages, err = s.messageRepo.FindPollMessagesForConversation(conversationID, window)
+	case core.DiffMessageType:
+		messages, err = s.messageRepo.FindDiffMessagesForConversation(conversationID, window)
+	case core.UndefinedMesssageType:
+		return s.messageRepo.FindForConversation(conversationID, window)
+	default:
+		return nil, 0, core.ErrInvalidMessageType
+	}
+	return messages, len(messages), err
+}
+
+func sequenceNumber(message interface{}) int {
+	return message.(core.Sequencable).GetSequenceNumber()
+}
+
+// encodeCursor returns an opaque token that continues the history from the anchor.
+func encodeCursor(direction core.HistoryDirection, anchorID int) string {
+	raw := strconv.Itoa(int(direction)) + ":" + strconv.Itoa(anchorID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (core.HistoryDirection, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, core.NewInvalidValueError("cursor")
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return 0, 0, core.NewInvalidValueError("cursor")
+	}
+
+	direction, err := strconv.Atoi(parts[0])
+	if err != nil || (core.HistoryDirection(direction) != core.HistoryBefore && core.HistoryDirection(direction) != core.HistoryAfter) {
+		return 0, 0, core.NewInvalidValueError("cursor")
+	}
+
+	anchorID, err := strconv.Atoi(parts[1])
+	if err != nil || anchorID < 0 {
+		return 0, 0, core.NewInvalidValueError("cursor")
+	}
+
+	return core.HistoryDirection(direction), anchorID, nil
+}
@@ -0,0 +1,118 @@
+package messaging
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		direction core.HistoryDirection
+		anchorID  int
+	}{
+		{core.HistoryBefore, 1},
+		{core.HistoryAfter, 42},
+		{core.HistoryAfter, 0},
+	}
+
+	for _, tt := range tests {
+		direction, anchorID, err := decodeCursor(encodeCursor(tt.direction, tt.anchorID))
+		if err != nil {
+			t.Fatalf("decodeCursor returned %v", err)
+		}
+
+		if direction != tt.direction || anchorID != tt.anchorID {
+			t.Errorf("got (%d, %d), want (%d, %d)", direction, anchorID, tt.direction, tt.anchorID)
+		}
+	}
+}
+
+func TestDecodeInvalidCursor(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "%%%"},
+		{"missing separator", encode("42")},
+		{"around is not a cursor direction", encode("2:42")},
+		{"negative anchor", encode("0:-1")},
+		{"anchor not a number", encode("1:abc")},
+	}
+
+	for _, tt := range tests {
+		_, _, err := decodeCursor(tt.cursor)
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+// fakeHistoryRepo returns the messages of a range like the database does: ordered away
+// from the anchor and cut off at the limit.
+// Media messages without files are counted, but not returned.
+type fakeHistoryRepo struct {
+	core.MessageRepo
+	ids          []int
+	withoutFiles []int
+}
+
+func (r *fakeHistoryRepo) FindForConversation(conversationID int, window core.MessageRange) ([]interface{}, int, error) {
+	messages, nRows := make([]interface{}, 0), 0
+	for i := range r.ids {
+		id := r.ids[len(r.ids)-1-i]
+		if window.Forward {
+			id = r.ids[i]
+		}
+
+		if id > window.AfterID && id < window.BeforeID && nRows < window.Limit {
+			nRows++
+			if !containsInt(r.withoutFiles, id) {
+				messages = append(messages, core.Message{ID: id})
+			}
+		}
+	}
+	return messages, nRows, nil
+}
+
+func TestFindMessageRange(t *testing.T) {
+	tests := []struct {
+		name         string
+		withoutFiles []int
+		window       core.MessageRange
+		expected     []int
+		hasMore      bool
+	}{
+		{"newest", nil, core.MessageRange{BeforeID: core.MaxInt, Limit: 2}, []int{13, 21}, true},
+		{"before anchor", nil, core.MessageRange{BeforeID: 8, Limit: 2}, []int{3, 5}, true},
+		{"exactly the limit", nil, core.MessageRange{BeforeID: 8, Limit: 3}, []int{2, 3, 5}, false},
+		{"less than the limit", nil, core.MessageRange{BeforeID: 8, Limit: 10}, []int{2, 3, 5}, false},
+		{"after anchor", nil, core.MessageRange{AfterID: 3, BeforeID: core.MaxInt, Limit: 2, Forward: true}, []int{5, 8}, true},
+		{"end of history", nil, core.MessageRange{AfterID: 8, BeforeID: core.MaxInt, Limit: 2, Forward: true}, []int{13, 21}, false},
+		{"empty", nil, core.MessageRange{AfterID: 21, BeforeID: core.MaxInt, Limit: 2, Forward: true}, []int{}, false},
+		{"media without files", []int{13}, core.MessageRange{BeforeID: core.MaxInt, Limit: 2}, []int{8, 21}, true},
+	}
+
+	for _, tt := range tests {
+		s := &service{messageRepo: &fakeHistoryRepo{ids: []int{2, 3, 5, 8, 13, 21}, withoutFiles: tt.withoutFiles}}
+		messages, hasMore, err := s.findMessageRange(1, core.UndefinedMesssageType, tt.window)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		ids := make([]int, len(messages))
+		for i, message := range messages {
+			ids[i] = sequenceNumber(message)
+		}
+
+		if !reflect.DeepEqual(ids, tt.expected) || hasMore != tt.hasMore {
+			t.Errorf("%s: got %v, %v, want %v, %v", tt.name, ids, hasMore, tt.expected, tt.hasMore)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+package messaging
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"strings"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+// PlainTextLanguage is assigned to code whose language could not be detected.
+const PlainTextLanguage = "Plain Text"
+
+const (
+	extensionConfidence          = 0.9
+	ambiguousExtensionConfidence = 0.75
+	shebangConfidence            = 0.85
+	maxContentConfidence         = 0.8
+	minContentScore              = 3
+)
+
+// extensionLanguages maps file extensions to the languages that use them. Extensions
+// shared by several languages are resolved by the content.
+var extensionLanguages = map[string][]string{
+	".bash":     {"Bash"},
+	".c":        {"C"},
+	".cc":       {"C++"},
+	".coffee":   {"Coffee Script"},
+	".cpp":      {"C++"},
+	".cs":       {"C# (.Net Core)"},
+	".css":      {"CSS"},
+	".cxx":      {"C++"},
+	".fs":       {"F#"},
+	".fsx":      {"F#"},
+	".go":       {"Go"},
+	".gql":      {"GraphQL"},
+	".graphql":  {"GraphQL"},
+	".groovy":   {"Groovy"},
+	".h":        {"C", "C++", "Objective-C"},
+	".hpp":      {"C++"},
+	".htm":      {"HTML"},
+	".html":     {"HTML"},
+	".java":     {"Java"},
+	".js":       {"JavaScript"},
+	".json":     {"JSON"},
+	".jsx":      {"JavaScript"},
+	".kt":       {"Kotlin"},
+	".kts":      {"Kotlin"},
+	".m":        {"Objective-C", "MatLab"},
+	".markdown": {"Markdown"},
+	".md":       {"Markdown"},
+	".mjs":      {"JavaScript"},
+	".mm":       {"Objective-C"},
+	".php":      {"PHP"},
+	".pl":       {"Perl"},
+	".pm":       {"Perl"},
+	".py":       {"Python"},
+	".r":        {"R"},
+	".rb":       {"Ruby"},
+	".rs":       {"Rust"},
+	".sh":       {"Bash"},
+	".sql":      {"SQL", "PL/SQL"},
+	".svg":      {"XML"},
+	".swift":    {"Swift"},
+	".ts":       {"TypeScript"},
+	".tsx":      {"TypeScript"},
+	".txt":      {PlainTextLanguage},
+	".wat":      {"WebAssembly"},
+	".xml":      {"XML"},
+	".yaml":     {"YAML"},
+	".yml":      {"YAML"},
+	".zsh":      {"Bash"},
+}
+
+// interpreterLanguages maps the interpreters of shebang lines to languages.
+var interpreterLanguages = map[string]string{
+	"bash":    "Bash",
+	"dash":    "Bash",
+	"groovy":  "Groovy",
+	"ksh":     "Bash",
+	"node":    "JavaScript",
+	"nodejs":  "JavaScript",
+	"perl":    "Perl",
+	"php":     "PHP",
+	"python":  "Python",
+	"rscript": "R",
+	"ruby":    "Ruby",
+	"sh":      "Bash",
+	"swift":   "Swift",
+	"ts-node": "TypeScript",
+	"zsh":     "Bash",
+}
+
+// contentHint is a pattern that is typical for the source code of the languages. Each
+// pattern adds its weight to the score of its languages at most once.
+type contentHint struct {
+	languages []string
+	weight    int
+	pattern   *regexp.Regexp
+}
+
+func hint(weight int, pattern string, languages ...string) contentHint {
+	return contentHint{languages, weight, regexp.MustCompile("(?m)" + pattern)}
+}
+
+var contentHints = []contentHint{
+	hint(4, `^package \w+\s*$`, "Go"),
+	hint(3, `^func (\(\w+ \*?\w+\) )?\w+\(`, "Go"),
+	hint(2, `^import (\($|")`, "Go"),
+	hint(2, `\bfmt\.\w+\(|\berr != nil\b`, "Go"),
+	hint(1, `\w+ := `, "Go"),
+
+	hint(3, `^\s*def \w+\(.*\)( -> [^:]+)?:\s*$`, "Python"),
+	hint(3, `^from [\w.]+ import \w`, "Python"),
+	hint(3, `^\s*(elif|except)\b.*:\s*$`, "Python"),
+	hint(4, `^if __name__ == .__main__.:`, "Python"),
+	hint(1, `^import [\w.]+( as \w+)?\s*$`, "Python"),
+	hint(1, `\bself\.\w+`, "Python", "Ruby"),
+	hint(1, `\bprint\(`, "Python", "Swift"),
+
+	hint(2, `^\s*def \w+[?!]?(\(.*\))?\s*$`, "Ruby"),
+	hint(2, `^\s*puts\b`, "Ruby"),
+	hint(3, `\.each do\b|\bdo \|\w+(, *\w+)*\|`, "Ruby"),
+	hint(2, `^\s*require ['"]`, "Ruby"),
+	hint(1, `^\s*end\s*$`, "Ruby", "MatLab"),
+
+	hint(3, `^#include\s*<\w+\.h>`, "C"),
+	hint(2, `^#include\s*"[\w/]+\.h"`, "C", "C++"),
+	hint(1, `^\s*#(define|ifndef|endif|pragma)\b`, "C", "C++"),
+	hint(2, `\b(printf|malloc|free|sizeof)\(`, "C"),
+	hint(2, `\bint main\s*\(`, "C", "C++"),
+	hint(4, `^#include\s*<\w+>`, "C++"),
+	hint(4, `\bstd::`, "C++"),
+	hint(3, `\b(cout|cin|cerr)\s*(<<|>>)|^using namespace \w+;`, "C++"),
+	hint(3, `^\s*template\s*<`, "C++"),
+	hint(2, `\b(nullptr|constexpr)\b`, "C++"),
+
+	hint(4, `^#import\s*[<"]`, "Objective-C"),
+	hint(4, `@(interface|implementation|property|end)\b`, "Objective-C"),
+	hint(3, `\bNSString\b|\bNSLog\(`, "Objective-C"),
+
+	hint(5, `^import javax?\.|\bSystem\.out\.print|\bpublic static void main\(String`, "Java"),
+	hint(4, `^package [\w.]+;`, "Java"),
+	hint(3, `@Override\b`, "Java", "Kotlin"),
+	hint(1, `^\s*(public|private|protected)( static)?( final)? [\w<>\[\]]+ \w+\(`, "Java", "C# (.Net Core)"),
+
+	hint(5, `^using System(\.\w+)*;|\bConsole\.Write(Line)?\(`, "C# (.Net Core)"),
+	hint(3, `^\s*namespace [\w.]+\s*[{;]?\s*$`, "C# (.Net Core)", "C++"),
+	hint(4, `\{ get; (set; )?\}`, "C# (.Net Core)"),
+	hint(3, `\basync Task\b`, "C# (.Net Core)"),
+
+	hint(3, `\bconsole\.log\(`, "JavaScript", "TypeScript"),
+	hint(1, `^\s*(const|let|var) \w+ = `, "JavaScript", "TypeScript", "Swift"),
+	hint(2, `\bfunction\s*\w*\s*\(`, "JavaScript", "TypeScript", "PHP"),
+	hint(1, `=>\s*\{|===`, "JavaScript", "TypeScript"),
+	hint(3, `\brequire\(['"]|\bmodule\.exports\b`, "JavaScript"),
+	hint(4, `\bdocument\.(getElementById|querySelector)`, "JavaScript", "TypeScript"),
+	hint(2, `^\s*export (default |const |function |class )|^import .* from ['"]`, "JavaScript", "TypeScript"),
+	hint(3, `^\s*(export )?(interface \w+(<.*>)? \{|type \w+(<.*>)? = )`, "TypeScript"),
+	hint(3, `\w: (string|number|boolean|any|void)(\[\])?\b`, "TypeScript"),
+
+	hint(10, `<\?php`, "PHP"),
+	hint(2, `\$\w+\s*=[^=]`, "PHP", "Perl"),
+	hint(1, `->\w+\(`, "PHP", "C++"),
+
+	hint(5, `^use strict;`, "Perl"),
+	hint(4, `^\s*my [$@%]\w+`, "Perl"),
+	hint(3, `^\s*sub \w+\s*\{`, "Perl"),
+
+	hint(3, `^\s*(if|while) \[\[? `, "Bash"),
+	hint(3, `^\s*(fi|done|esac)\s*$`, "Bash"),
+	hint(2, `^\s*echo\b`, "Bash", "PHP"),
+	hint(1, `\$\{\w+\}|\$\(`, "Bash"),
+	hint(1, `^\s*(export )?[A-Z_][A-Z0-9_]*=`, "Bash"),
+	hint(1, `^\s*(sudo|apt(-get)?|cd|ls|mkdir|rm|source|chmod|curl|git|npm|pip|docker) `, "Bash"),
+
+	hint(5, `(?i)^\s*(select .+ from|insert into|update \w+ set|delete from|create (table|index|view)|alter table|drop table)\b`, "SQL"),
+	hint(1, `\b(WHERE|JOIN|GROUP BY|ORDER BY)\b`, "SQL"),
+	hint(6, `(?i)^\s*create (or replace )?(function|procedure|trigger)\b`, "PL/SQL"),
+	hint(3, `(?i)^\s*(declare|begin)\s*$|\blanguage plpgsql\b`, "PL/SQL"),
+
+	hint(10, `(?i)<!doctype html`, "HTML"),
+	hint(3, `(?i)</(html|head|body|div|span|script|p|ul|li|table|form)>`, "HTML"),
+	hint(10, `^<\?xml `, "XML"),
+	hint(3, `</\w+:\w+>|\bxmlns(:\w+)?=`, "XML"),
+
+	hint(1, `^\s*[.#]?[\w-]+(\s*[,>+~]?\s*[.#:]?[\w-]+)*\s*\{\s*$`, "CSS"),
+	hint(2, `^\s*[a-z-]+:\s*[^;{}]+;\s*$`, "CSS"),
+	hint(3, `^@media\b|!important\b`, "CSS"),
+
+	hint(2, `^---\s*$`, "YAML"),
+	hint(1, `^[\w-]+:(\s+[^;{]*)?$`, "YAML"),
+	hint(3, `^\s*- [\w-]+:(\s|$)`, "YAML"),
+
+	hint(3, "^#{2,6} \\S|^```", "Markdown"),
+	hint(2, `\[[^\]]+\]\([^)]+\)|\*\*[^*]+\*\*`, "Markdown"),
+	hint(1, `^\s*([-*]|\d+\.) \S`, "Markdown"),
+
+	hint(4, `^\s*fun \w+\(`, "Kotlin"),
+	hint(2, `^\s*val \w+(: \w+)? = `, "Kotlin"),
+	hint(1, `\bprintln\(`, "Kotlin", "Groovy"),
+
+	hint(5, `^import (Foundation|UIKit|SwiftUI|Cocoa)\b`, "Swift"),
+	hint(4, `^\s*func \w+(<.*>)?\(.*\)( throws)? -> `, "Swift"),
+	hint(4, `\b(guard|if) let\b`, "Swift"),
+
+	hint(3, `^\s*(pub )?fn \w+(<.*>)?\(`, "Rust"),
+	hint(4, `\blet mut\b|\b(println|format|vec|panic)!\(|^use (std|crate)::`, "Rust"),
+	hint(3, `^\s*impl\b`, "Rust"),
+
+	hint(3, `\w+ <- `, "R"),
+	hint(4, `\blibrary\(\w+\)`, "R"),
+
+	hint(3, `^\s*def \w+ = `, "Groovy"),
+	hint(2, `\bprintln\s+["']`, "Groovy"),
+
+	hint(3, `^\s*let (rec )?\w+( \w+)+ =|^open [\w.]+\s*$`, "F#"),
+	hint(3, `\|>|^\s*match .+ with\s*$`, "F#"),
+	hint(4, `\bprintfn\b`, "F#"),
+
+	hint(5, `^\s*\w+ = (\(.*\) )?->`, "Coffee Script"),
+
+	hint(4, `^\s*function \[?[\w, ]*\]?\s*=\s*\w+\(`, "MatLab"),
+	hint(2, `\b(disp|zeros|ones|plot)\(`, "MatLab"),
+
+	hint(4, `^\s*(query|mutation|subscription|fragment)\b[^=]*\{`, "GraphQL"),
+	hint(3, `^\s*type \w+ (implements \w+ )?\{`, "GraphQL"),
+
+	hint(6, `^\s*\(module\b`, "WebAssembly"),
+	hint(3, `\((func|param|result|local\.get|i32\.\w+)\b`, "WebAssembly"),
+}
+
+// detectLanguage guesses the language of code from the extension of the file name, a
+// shebang line and typical patterns of its content. Only the given languages are
+// considered. If none of them fits, the plain text language is chosen with a confidence
+// of 0. The language of the guess is empty if even that is unknown.
+func detectLanguage(fileName, code string, languages []core.ProgrammingLanguage) core.LanguageGuess {
+	known := make(map[string]string, len(languages))
+	for _, l := range languages {
+		known[strings.ToLower(l.Name)] = l.Name
+	}
+
+	var candidates []string
+	for _, name := range extensionLanguages[strings.ToLower(path.Ext(fileName))] {
+		if language, ok := known[strings.ToLower(name)]; ok {
+			candidates = append(candidates, language)
+		}
+	}
+
+	if len(candidates) == 1 {
+		return core.LanguageGuess{Language: candidates[0], Confidence: extensionConfidence}
+	}
+
+	if len(candidates) > 1 {
+		language, score, _ := rankContent(code, candidates)
+		if score == 0 {
+			language = candidates[0]
+		}
+		return core.LanguageGuess{Language: language, Confidence: ambiguousExtensionConfidence}
+	}
+
+	if language, ok := known[strings.ToLower(shebangLanguage(code))]; ok {
+		return core.LanguageGuess{Language: language, Confidence: shebangConfidence}
+	}
+
+	candidates = make([]string, 0, len(known))
+	for _, l := range languages {
+		candidates = append(candidates, l.Name)
+	}
+
+	language, best, second := rankContent(code, candidates)
+	if best >= minContentScore {
+		confidence := float64(best) / float64(best+second+2)
+		if confidence > maxContentConfidence {
+			confidence = maxContentConfidence
+		}
+		return core.LanguageGuess{Language: language, Confidence: confidence}
+	}
+	return core.LanguageGuess{Language: known[strings.ToLower(PlainTextLanguage)]}
+}
+
+// shebangLanguage returns the language of the interpreter named in the first line of the code.
+func shebangLanguage(code string) string {
+	if !strings.HasPrefix(code, "#!") {
+		return ""
+	}
+
+	line := code[2:]
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+
+	fields := strings.Fields(line)
+	for i, field := range fields {
+		interpreter := path.Base(field)
+		if i == 0 && interpreter == "env" {
+			continue
+		}
+
+		if strings.HasPrefix(field, "-") {
+			continue
+		}
+
+		interpreter = strings.TrimRight(strings.ToLower(interpreter), "0123456789.")
+		return interpreterLanguages[interpreter]
+	}
+	return ""
+}
+
+// rankContent scores the code for each of the candidates and returns the best candidate
+// along with its score and the score of the runner-up.
+func rankContent(code string, candidates []string) (string, int, int) {
+	scores := make(map[string]int, len(candidates))
+	for _, c := range candidates {
+		scores[c] = 0
+	}
+
+	trimmed := strings.TrimSpace(code)
+	if _, ok := scores["JSON"]; ok && (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		scores["JSON"] += 10
+	}
+
+	for _, h := range contentHints {
+		matched := false
+		for _, language := range h.languages {
+			if _, ok := scores[language]; !ok {
+				continue
+			}
+
+			if !matched && !h.pattern.MatchString(code) {
+				break
+			}
+			matched = true
+			scores[language] += h.weight
+		}
+	}
+
+	var best string
+	var bestScore, secondScore int
+	for _, c := range candidates {
+		score := scores[c]
+		if score > bestScore {
+			best, bestScore, secondScore = c, score, bestScore
+		} else if score > secondScore {
+			secondScore = score
+		}
+	}
+	return best, bestScore, secondScore
+}
+
+// detectMissingLanguages guesses the language of every file of the message that comes
+// without one. The guess for the main file is reported in DetectedLanguage. The main file
+// is detected from the title if it has no name.
+func (s *service) detectMissingLanguages(message *core.CodeMessage) error {
+	var languages []core.ProgrammingLanguage
+	detect := func(fileName, code string) (core.LanguageGuess, error) {
+		if languages == nil {
+			var err error
+			languages, err = s.messageRepo.FindAllProgrammingLanguages()
+			if err != nil {
+				return core.LanguageGuess{}, err
+			}
+		}
+
+		guess := detectLanguage(fileName, code, languages)
+		if guess.Language == "" {
+			return guess, core.NewInvalidValueError("language")
+		}
+		return guess, nil
+	}
+
+	message.Language = strings.TrimSpace(message.Language)
+	if message.Language == "" {
+		fileName := message.FileName
+		if fileName == "" {
+			fileName = message.Title
+		}
+
+		guess, err := detect(fileName, message.Code)
+		if err != nil {
+			return err
+		}
+		message.Language, message.DetectedLanguage = guess.Language, &guess
+	}
+
+	for i := range message.Files {
+		f := &message.Files[i]
+		f.Language = strings.TrimSpace(f.Language)
+		if f.Language != "" {
+			continue
+		}
+
+		guess, err := detect(f.Name, f.Code)
+		if err != nil {
+			return err
+		}
+		f.Language = guess.Language
+	}
+	return nil
+}
+
+// DetectLanguage guesses the language of code so that clients can suggest it.
+func (s *service) DetectLanguage(fileName, code string) (core.LanguageGuess, error) {
+	languages, err := s.messageRepo.FindAllProgrammingLanguages()
+	if err != nil {
+		return core.LanguageGuess{}, err
+	}
+
+	guess := detectLanguage(fileName, code, languages)
+	if guess.Language == "" {
+		return core.LanguageGuess{}, core.NewInvalidValueError("language")
+	}
+	return guess, nil
+}
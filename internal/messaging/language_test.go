@@ -0,0 +1,77 @@
+package messaging
+
+import (
+	"testing"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	languages := []core.ProgrammingLanguage{
+		{Name: "C"}, {Name: "C++"}, {Name: "Python"}, {Name: "Go"}, {Name: "C# (.Net Core)"},
+		{Name: "Bash"}, {Name: "JavaScript"}, {Name: "TypeScript"}, {Name: "JSON"}, {Name: "YAML"},
+		{Name: "SQL"}, {Name: "PL/SQL"}, {Name: "Objective-C"}, {Name: "MatLab"}, {Name: "Rust"},
+		{Name: PlainTextLanguage},
+	}
+
+	tests := []struct {
+		fileName string
+		code     string
+		expected string
+	}{
+		{"main.go", "", "Go"},
+		{"README.TXT", "", PlainTextLanguage},
+		{"list.h", "#include <vector>\nstd::vector<int> v;", "C++"},
+		{"list.h", "", "C"},
+		{"deploy", "#!/usr/bin/env python3 -u\nprint(1)", "Python"},
+		{"", "#!/bin/sh\nls", "Bash"},
+		{"", "package main\n\nfunc main() {\n\tfmt.Println(1)\n}", "Go"},
+		{"", "#include <iostream>\nint main() {\n\tstd::cout << 1;\n}", "C++"},
+		{"", "#include <stdio.h>\nint main() {\n\tprintf(\"1\");\n}", "C"},
+		{"", "def add(a, b):\n    return a + b\n", "Python"},
+		{"", "using System;\nConsole.WriteLine(1);", "C# (.Net Core)"},
+		{"", "const x: number = 1;\nconsole.log(x);", "TypeScript"},
+		{"", "const x = require('x');\nconsole.log(x);", "JavaScript"},
+		{"", `{"a": [1, 2]}`, "JSON"},
+		{"", "SELECT name FROM users WHERE id = 1;", "SQL"},
+		{"", "fn main() {\n    let mut x = 1;\n    println!(\"{}\", x);\n}", "Rust"},
+		{"", "server:\n  port: 80\n  hosts:\n    - name: a\n", "YAML"},
+		{"", "Just some words.", PlainTextLanguage},
+		{"script.rb", "puts 1", PlainTextLanguage},
+	}
+
+	for _, test := range tests {
+		guess := detectLanguage(test.fileName, test.code, languages)
+		if guess.Language != test.expected {
+			t.Errorf("detectLanguage(%q, %q) = %q, want %q", test.fileName, test.code, guess.Language, test.expected)
+		}
+
+		if guess.Confidence < 0 || guess.Confidence > 1 {
+			t.Errorf("detectLanguage(%q, %q) has confidence %f", test.fileName, test.code, guess.Confidence)
+		}
+	}
+
+	guess := detectLanguage("", "words", languages[:1])
+	if guess.Language != "" || guess.Confidence != 0 {
+		t.Errorf("unexpected guess %#v without plain text", guess)
+	}
+}
+
+func TestShebangLanguage(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"#!/bin/bash\n", "Bash"},
+		{"#!/usr/bin/env node", "JavaScript"},
+		{"#!/usr/bin/python2.7\n", "Python"},
+		{"#!/usr/bin/env -S ruby -w\n", "Ruby"},
+		{"# comment\n", ""},
+	}
+
+	for _, test := range tests {
+		if got := shebangLanguage(test.code); got != test.expected {
+			t.Errorf("shebangLanguage(%q) = %q, want %q", test.code, got, test.expected)
+		}
+	}
+}
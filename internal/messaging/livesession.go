@@ -0,0 +1,133 @@
+package messaging
+
+import (
+	"sync"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+// maxOperationHistory is the number of operations kept per live document. Operations based
+// on older revisions cannot be transformed anymore and are rejected.
+const maxOperationHistory = 200
+
+// liveDocument is the canonical state of a code message while it is edited in a live session.
+type liveDocument struct {
+	sync.Mutex
+	code     string
+	revision int
+
+	// history holds the operations that lead to the current revision. The last
+	// element produced revision, the first one revision - len(history) + 1.
+	history []textOperation
+}
+
+type liveDocuments struct {
+	sync.Mutex
+	m map[int]*liveDocument
+}
+
+type operationData struct {
+	MessageID int            `json:"messageId"`
+	Revision  int            `json:"revision"`
+	Operation *textOperation `json:"operation"`
+	Title     string         `json:"title,omitempty"`
+	Language  string         `json:"language,omitempty"`
+	Author    int            `json:"author"`
+}
+
+func (d *liveDocuments) get(messageID int) *liveDocument {
+	d.Lock()
+	defer d.Unlock()
+
+	document, ok := d.m[messageID]
+	if !ok {
+		document = &liveDocument{revision: -1}
+		d.m[messageID] = document
+	}
+	return document
+}
+
+func (d *liveDocuments) remove(messageID int) {
+	d.Lock()
+	delete(d.m, messageID)
+	d.Unlock()
+}
+
+// applyOperation transforms the operation against all operations the client has not seen yet,
+// applies it to the code message and returns the transformed operation together with the
+// revision it produced. Every member may edit while a live session is active. Operations
+// always apply to the main file of the message.
+func (s *service) applyOperation(userCtx, conversationID int, data operationData) (operationData, error) {
+	codeMessage, err := s.messageRepo.FindCodeMessageForID(data.MessageID, conversationID)
+	if err != nil {
+		return operationData{}, err
+	}
+
+	if codeMessage.LockedBy == 0 {
+		return operationData{}, core.ErrAccessDenied
+	}
+
+	document := s.documents.get(data.MessageID)
+	document.Lock()
+	defer document.Unlock()
+
+	// The message was changed outside of the live session, e.g. by restoring an older revision.
+	if document.revision != codeMessage.Revision {
+		document.code = codeMessage.Code
+		document.revision = codeMessage.Revision
+		document.history = nil
+	}
+
+	missedOperations := document.revision - data.Revision
+	if missedOperations < 0 || missedOperations > len(document.history) {
+		return operationData{}, core.ErrEditConflict
+	}
+
+	operation := *data.Operation
+	for _, concurrent := range document.history[len(document.history)-missedOperations:] {
+		operation, _, err = transformOperations(operation, concurrent)
+		if err != nil {
+			return operationData{}, core.NewInvalidValueError("operation")
+		}
+	}
+
+	updatedCode, err := operation.apply(document.code)
+	if err != nil {
+		return operationData{}, core.NewInvalidValueError("operation")
+	}
+
+	updatedMessage := codeMessage
+	updatedMessage.Code = updatedCode
+	if data.Title != "" {
+		updatedMessage.Title = data.Title
+	}
+
+	if data.Language != "" {
+		updatedMessage.Language = data.Language
+	}
+
+	revision, err := s.messageRepo.UpdateCode(data.MessageID, userCtx, updatedMessage)
+	if err != nil {
+		return operationData{}, err
+	}
+
+	if revision == document.revision+1 {
+		document.history = append(document.history, operation)
+		if len(document.history) > maxOperationHistory {
+			document.history = document.history[len(document.history)-maxOperationHistory:]
+		}
+	} else {
+		document.history = nil
+	}
+	document.code = updatedCode
+	document.revision = revision
+
+	return operationData{
+		MessageID: data.MessageID,
+		Revision:  revision,
+		Operation: &operation,
+		Title:     data.Title,
+		Language:  data.Language,
+		Author:    userCtx,
+	}, nil
+}
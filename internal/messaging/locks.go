@@ -0,0 +1,205 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+// handOver is a pending request of a lock holder to pass the lock of a code message to another member.
+type handOver struct {
+	conversationID int
+	from           int
+	to             int
+}
+
+type handOvers struct {
+	sync.Mutex
+	m map[int]handOver
+}
+
+type lockOwnerChange struct {
+	MessageID int `json:"messageId"`
+	NewOwner  int `json:"newOwner"`
+}
+
+// ReleaseLocksOfUser releases every lock held by the user.
+func (s *service) ReleaseLocksOfUser(userID int, pusher core.Pusher, ctx context.Context) error {
+	locks, err := s.messageRepo.FindLocks(userID, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	return s.releaseLocks(locks, pusher, ctx)
+}
+
+// ReleaseIdleLocks releases every lock of a live session that has not been active for the passed duration.
+func (s *service) ReleaseIdleLocks(idleTimeout time.Duration, pusher core.Pusher, ctx context.Context) error {
+	locks, err := s.messageRepo.FindLocks(0, time.Now().UTC().Add(-idleTimeout))
+	if err != nil {
+		return err
+	}
+
+	return s.releaseLocks(locks, pusher, ctx)
+}
+
+// ForceReleaseLock allows admins to end a live session of another user.
+func (s *service) ForceReleaseLock(userCtx, conversationID, messageID int, pusher core.Pusher, ctx context.Context) error {
+	isAdmin, err := s.conversationRepo.IsUserAdminOfConveration(userCtx, conversationID)
+	if err != nil {
+		return err
+	}
+
+	if !isAdmin {
+		return core.ErrAccessDenied
+	}
+
+	message, err := s.messageRepo.FindCodeMessageForID(messageID, conversationID)
+	if err != nil {
+		return err
+	}
+
+	if message.LockedBy == 0 {
+		return core.ErrNothingChanged
+	}
+
+	return s.releaseLocks([]core.CodeLock{{
+		MessageID:      messageID,
+		ConversationID: conversationID,
+		LockedBy:       message.LockedBy,
+	}}, pusher, ctx)
+}
+
+func (s *service) releaseLocks(locks []core.CodeLock, pusher core.Pusher, ctx context.Context) error {
+	for _, lock := range locks {
+		err := s.messageRepo.ReleaseLock(lock.MessageID, lock.LockedBy)
+		if err == core.ErrNothingChanged {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		s.documents.remove(lock.MessageID)
+		s.handOvers.Lock()
+		delete(s.handOvers.m, lock.MessageID)
+		s.handOvers.Unlock()
+
+		pusher.BroadcastToRoom(lock.ConversationID, lockOwnerChange{lock.MessageID, 0}, ctx)
+	}
+	return nil
+}
+
+// RequestLockHandOver asks another member to take over the lock of a code message.
+// Only the current holder of the lock may do so.
+func (s *service) RequestLockHandOver(
+	userCtx, conversationID int,
+	message json.RawMessage,
+	pusher core.Pusher,
+	ctx context.Context) (int, error) {
+
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return 0, err
+	}
+
+	payload := struct {
+		MessageID int `json:"messageId"`
+		Recipient int `json:"recipient"`
+	}{}
+	err = json.Unmarshal(message, &payload)
+	if err != nil {
+		return 0, core.NewJSONFormatError(err.Error())
+	}
+
+	codeMessage, err := s.messageRepo.FindCodeMessageForID(payload.MessageID, conversationID)
+	if err != nil {
+		return 0, err
+	}
+
+	if codeMessage.LockedBy != userCtx {
+		return 0, core.ErrAccessDenied
+	}
+
+	if payload.Recipient == userCtx {
+		return 0, core.NewInvalidValueError("recipient")
+	}
+
+	isMember, err := s.conversationRepo.IsUserInConversation(payload.Recipient, conversationID)
+	if err != nil {
+		return 0, err
+	}
+
+	if !isMember {
+		return 0, core.NewInvalidValueError("recipient")
+	}
+
+	s.handOvers.Lock()
+	s.handOvers.m[payload.MessageID] = handOver{conversationID, userCtx, payload.Recipient}
+	s.handOvers.Unlock()
+
+	request := struct {
+		MessageID int `json:"messageId"`
+		From      int `json:"from"`
+	}{payload.MessageID, userCtx}
+	pusher.Unicast(ctx, payload.Recipient, request)
+
+	return payload.MessageID, nil
+}
+
+// AnswerLockHandOver accepts or declines a hand over request. The old holder is told
+// about a declined request, an accepted one is announced to the whole conversation.
+func (s *service) AnswerLockHandOver(
+	userCtx, conversationID int,
+	message json.RawMessage,
+	pusher core.Pusher,
+	ctx context.Context) (int, error) {
+
+	payload := struct {
+		MessageID int  `json:"messageId"`
+		Accept    bool `json:"accept"`
+	}{}
+	err := json.Unmarshal(message, &payload)
+	if err != nil {
+		return 0, core.NewJSONFormatError(err.Error())
+	}
+
+	s.handOvers.Lock()
+	request, ok := s.handOvers.m[payload.MessageID]
+	if ok && request.to == userCtx && request.conversationID == conversationID {
+		delete(s.handOvers.m, payload.MessageID)
+	}
+	s.handOvers.Unlock()
+
+	if !ok || request.to != userCtx || request.conversationID != conversationID {
+		return 0, core.ErrRessourceDoesNotExist
+	}
+
+	if !payload.Accept {
+		answer := struct {
+			MessageID int  `json:"messageId"`
+			Recipient int  `json:"recipient"`
+			Accepted  bool `json:"accepted"`
+		}{payload.MessageID, userCtx, false}
+		pusher.Unicast(ctx, request.from, answer)
+		return payload.MessageID, nil
+	}
+
+	_, err = s.messageRepo.FindCodeMessageForID(payload.MessageID, conversationID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Fails if the lock has been released in the meantime.
+	err = s.messageRepo.TransferLock(payload.MessageID, request.from, userCtx)
+	if err != nil {
+		return 0, err
+	}
+
+	pusher.BroadcastToRoom(conversationID, lockOwnerChange{payload.MessageID, userCtx}, ctx)
+	return payload.MessageID, nil
+}
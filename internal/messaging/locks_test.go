@@ -0,0 +1,100 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+type fakeLockRepo struct {
+	core.MessageRepo
+	lockedBy int
+}
+
+func (r *fakeLockRepo) FindCodeMessageForID(messageID, conversationID int) (core.CodeMessage, error) {
+	return core.CodeMessage{LockedBy: r.lockedBy}, nil
+}
+
+func (r *fakeLockRepo) TransferLock(messageID, holderID, newHolderID int) error {
+	if r.lockedBy != holderID {
+		return core.ErrEditConflict
+	}
+	r.lockedBy = newHolderID
+	return nil
+}
+
+func (r *fakeLockRepo) ReleaseLock(messageID, holderID int) error {
+	if r.lockedBy != holderID {
+		return core.ErrNothingChanged
+	}
+	r.lockedBy = 0
+	return nil
+}
+
+type fakeMemberRepo struct {
+	core.ConversationRepo
+}
+
+func (r *fakeMemberRepo) IsUserInConversation(userID, conversationID int) (bool, error) {
+	return true, nil
+}
+
+func TestEndLiveSession(t *testing.T) {
+	tests := []struct {
+		name     string
+		userCtx  int
+		expected error
+		lockedBy int
+	}{
+		{"holder", 1, nil, 0},
+		{"other member", 2, core.ErrAccessDenied, 1},
+	}
+
+	for _, tt := range tests {
+		repo := &fakeLockRepo{lockedBy: 1}
+		s := NewService(repo, &fakeMemberRepo{}).(*service)
+		pusher := &fakePusher{}
+
+		message := json.RawMessage(`{"type": 1, "id": 7}`)
+		_, err := s.ToggleLiveSession(tt.userCtx, 1, false, message, pusher, context.Background())
+		if err != tt.expected || repo.lockedBy != tt.lockedBy {
+			t.Errorf("%s: got %v and owner %d, want %v and owner %d", tt.name, err, repo.lockedBy, tt.expected, tt.lockedBy)
+		}
+
+		if (len(pusher.broadcasts) > 0) != (tt.expected == nil) {
+			t.Errorf("%s: unexpected broadcasts %v", tt.name, pusher.broadcasts)
+		}
+	}
+}
+
+func TestAnswerLockHandOver(t *testing.T) {
+	tests := []struct {
+		name     string
+		lockedBy int
+		expected error
+		newOwner int
+	}{
+		{"still locked", 1, nil, 2},
+		{"released in the meantime", 0, core.ErrEditConflict, 0},
+		{"taken by someone else", 3, core.ErrEditConflict, 3},
+	}
+
+	for _, tt := range tests {
+		repo := &fakeLockRepo{lockedBy: tt.lockedBy}
+		s := NewService(repo, nil).(*service)
+		s.handOvers.m[7] = handOver{conversationID: 1, from: 1, to: 2}
+		pusher := &fakePusher{}
+
+		message := json.RawMessage(`{"messageId": 7, "accept": true}`)
+		_, err := s.AnswerLockHandOver(2, 1, message, pusher, context.Background())
+		if err != tt.expected || repo.lockedBy != tt.newOwner {
+			t.Errorf("%s: got %v and owner %d, want %v and owner %d", tt.name, err, repo.lockedBy, tt.expected, tt.newOwner)
+		}
+
+		if (len(pusher.broadcasts) > 0) != (tt.expected == nil) {
+			t.Errorf("%s: unexpected broadcasts %v", tt.name, pusher.broadcasts)
+		}
+	}
+}
@@ -20,7 +20,7 @@ func NewLoggingService(logger log.Logger, s Service, verbose bool) Service {
 	return &loggingService{logger, s, verbose}
 }
 
-func (s *loggingService) ReadMessages(userID int, message json.RawMessage) (err error) {
+func (s *loggingService) ReadMessages(userID int, message json.RawMessage, pusher core.Pusher, ctx context.Context) (err error) {
 	defer func(begin time.Time) {
 		if err != nil || s.verbose {
 			s.logger.Log(
@@ -30,7 +30,7 @@ func (s *loggingService) ReadMessages(userID int, message json.RawMessage) (err
 				"err", err)
 		}
 	}(time.Now())
-	return s.next.ReadMessages(userID, message)
+	return s.next.ReadMessages(userID, message, pusher, ctx)
 }
 
 func (s *loggingService) SendMessage(
@@ -52,21 +52,23 @@ func (s *loggingService) SendMessage(
 	return s.next.SendMessage(target, userID, message, pusher, ctx)
 }
 
-func (s *loggingService) ListAllMessages(userID, conversationID, beforeInSequence, limit int, mType core.MessageType) (messages []interface{}, err error) {
+func (s *loggingService) ListAllMessages(userID, conversationID int, query core.HistoryQuery) (page core.HistoryPage, err error) {
 	defer func(begin time.Time) {
 		if err != nil || s.verbose {
 			s.logger.Log(
 				"Use-Case", "ListAllMessages",
 				"userID", userID,
 				"conversationID", conversationID,
-				"beforeInSequence", beforeInSequence,
-				"type", mType,
-				"limit", limit,
+				"direction", query.Direction,
+				"anchorID", query.AnchorID,
+				"cursor", query.Cursor,
+				"type", query.Type,
+				"limit", query.Limit,
 				"took", time.Since(begin),
 				"err", err)
 		}
 	}(time.Now())
-	return s.next.ListAllMessages(userID, conversationID, beforeInSequence, limit, mType)
+	return s.next.ListAllMessages(userID, conversationID, query)
 }
 
 func (s *loggingService) ListProgrammingLanguages() (languages []core.ProgrammingLanguage, err error) {
@@ -81,6 +83,21 @@ func (s *loggingService) ListProgrammingLanguages() (languages []core.Programmin
 	return s.next.ListProgrammingLanguages()
 }
 
+func (s *loggingService) DetectLanguage(fileName, code string) (guess core.LanguageGuess, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "DetectLanguage",
+				"fileName", fileName,
+				"language", guess.Language,
+				"confidence", guess.Confidence,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.DetectLanguage(fileName, code)
+}
+
 func (s *loggingService) GetCodeOfMessage(userCtx, conversationID int, messageID int) (code string, err error) {
 	defer func(begin time.Time) {
 		if err != nil || s.verbose {
@@ -96,6 +113,67 @@ func (s *loggingService) GetCodeOfMessage(userCtx, conversationID int, messageID
 	return s.next.GetCodeOfMessage(userCtx, conversationID, messageID)
 }
 
+func (s *loggingService) GetCodeFile(userCtx, conversationID, messageID, index int) (file core.CodeFile, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "GetCodeFile",
+				"userCtx", userCtx,
+				"messageID", messageID,
+				"conversationID", conversationID,
+				"index", index,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.GetCodeFile(userCtx, conversationID, messageID, index)
+}
+
+func (s *loggingService) GetCodeArchive(userCtx, conversationID, messageID int) (archive []byte, fileName string, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "GetCodeArchive",
+				"userCtx", userCtx,
+				"messageID", messageID,
+				"conversationID", conversationID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.GetCodeArchive(userCtx, conversationID, messageID)
+}
+
+func (s *loggingService) GetPatchOfMessage(userCtx, conversationID, messageID int) (patch, fileName string, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "GetPatchOfMessage",
+				"userCtx", userCtx,
+				"messageID", messageID,
+				"conversationID", conversationID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.GetPatchOfMessage(userCtx, conversationID, messageID)
+}
+
+func (s *loggingService) GetDiffOfMessage(userCtx, conversationID, messageID int) (files []core.DiffFile, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "GetDiffOfMessage",
+				"userCtx", userCtx,
+				"messageID", messageID,
+				"conversationID", conversationID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.GetDiffOfMessage(userCtx, conversationID, messageID)
+}
+
 func (s *loggingService) EditMessage(
 	userCtx, conversationID int,
 	message json.RawMessage,
@@ -210,3 +288,476 @@ func (s *loggingService) BroadcastUserIsTyping(userCtx, conversationID int, push
 func (s *loggingService) CompleteMessage(id int, err error) error {
 	return s.next.CompleteMessage(id, err)
 }
+
+func (s *loggingService) ValidateMessage(target int, message json.RawMessage) error {
+	return s.next.ValidateMessage(target, message)
+}
+
+func (s *loggingService) ListThread(userCtx, conversationID, messageID int) (thread core.Thread, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "ListThread",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"messageID", messageID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.ListThread(userCtx, conversationID, messageID)
+}
+
+func (s *loggingService) GetThreadSummary(userCtx, conversationID, messageID int) (summary core.ThreadSummary, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "GetThreadSummary",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"messageID", messageID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.GetThreadSummary(userCtx, conversationID, messageID)
+}
+
+func (s *loggingService) ToggleReaction(
+	userCtx, conversationID int,
+	state bool,
+	message json.RawMessage,
+	pusher core.Pusher,
+	ctx context.Context) (messageID int, err error) {
+
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "ToggleReaction",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"state", state,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.ToggleReaction(userCtx, conversationID, state, message, pusher, ctx)
+}
+
+func (s *loggingService) TogglePollVote(
+	userCtx, conversationID int,
+	state bool,
+	message json.RawMessage,
+	pusher core.Pusher,
+	ctx context.Context) (messageID int, err error) {
+
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "TogglePollVote",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"state", state,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.TogglePollVote(userCtx, conversationID, state, message, pusher, ctx)
+}
+
+func (s *loggingService) ListEditHistory(userCtx, conversationID, messageID int) (edits []core.MessageEdit, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "ListEditHistory",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"messageID", messageID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.ListEditHistory(userCtx, conversationID, messageID)
+}
+
+func (s *loggingService) ListReaders(userCtx, conversationID, messageID int) (readers []core.MessageReader, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "ListReaders",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"messageID", messageID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.ListReaders(userCtx, conversationID, messageID)
+}
+
+func (s *loggingService) Search(userCtx int, query core.SearchQuery) (results []core.SearchResult, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "Search",
+				"userCtx", userCtx,
+				"term", query.Term,
+				"conversationID", query.ConversationID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.Search(userCtx, query)
+}
+
+func (s *loggingService) ListMentions(userCtx, beforeInSequence, limit int) (mentions []core.Mention, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "ListMentions",
+				"userCtx", userCtx,
+				"beforeInSequence", beforeInSequence,
+				"limit", limit,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.ListMentions(userCtx, beforeInSequence, limit)
+}
+
+func (s *loggingService) ListPins(userCtx, conversationID int) (pins []core.Pin, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "ListPins",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.ListPins(userCtx, conversationID)
+}
+
+func (s *loggingService) ListCodeRevisions(userCtx, conversationID, messageID int) (revisions []core.CodeRevision, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "ListCodeRevisions",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"messageID", messageID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.ListCodeRevisions(userCtx, conversationID, messageID)
+}
+
+func (s *loggingService) DiffCodeRevisions(userCtx, conversationID, messageID, from, to int) (diff string, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "DiffCodeRevisions",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"messageID", messageID,
+				"from", from,
+				"to", to,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.DiffCodeRevisions(userCtx, conversationID, messageID, from, to)
+}
+
+func (s *loggingService) RestoreCodeRevision(
+	userCtx, conversationID, messageID, revision int,
+	pusher core.Pusher,
+	ctx context.Context) (newRevision int, err error) {
+
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "RestoreCodeRevision",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"messageID", messageID,
+				"revision", revision,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.RestoreCodeRevision(userCtx, conversationID, messageID, revision, pusher, ctx)
+}
+
+func (s *loggingService) TogglePin(
+	userCtx, conversationID, messageID int,
+	state bool,
+	pusher core.Pusher,
+	ctx context.Context) (err error) {
+
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "TogglePin",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"messageID", messageID,
+				"state", state,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.TogglePin(userCtx, conversationID, messageID, state, pusher, ctx)
+}
+
+func (s *loggingService) DeleteMessage(
+	userCtx, conversationID, messageID int,
+	pathPrefix string,
+	pusher core.Pusher,
+	ctx context.Context) (tombstone core.MessageTombstone, err error) {
+
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "DeleteMessage",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"messageID", messageID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.DeleteMessage(userCtx, conversationID, messageID, pathPrefix, pusher, ctx)
+}
+
+func (s *loggingService) ForceReleaseLock(
+	userCtx, conversationID, messageID int,
+	pusher core.Pusher,
+	ctx context.Context) (err error) {
+
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "ForceReleaseLock",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"messageID", messageID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.ForceReleaseLock(userCtx, conversationID, messageID, pusher, ctx)
+}
+
+func (s *loggingService) RequestLockHandOver(
+	userCtx, conversationID int,
+	message json.RawMessage,
+	pusher core.Pusher,
+	ctx context.Context) (messageID int, err error) {
+
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "RequestLockHandOver",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.RequestLockHandOver(userCtx, conversationID, message, pusher, ctx)
+}
+
+func (s *loggingService) AnswerLockHandOver(
+	userCtx, conversationID int,
+	message json.RawMessage,
+	pusher core.Pusher,
+	ctx context.Context) (messageID int, err error) {
+
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "AnswerLockHandOver",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.AnswerLockHandOver(userCtx, conversationID, message, pusher, ctx)
+}
+
+func (s *loggingService) ReleaseLocksOfUser(userID int, pusher core.Pusher, ctx context.Context) (err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "ReleaseLocksOfUser",
+				"userID", userID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.ReleaseLocksOfUser(userID, pusher, ctx)
+}
+
+func (s *loggingService) ReleaseIdleLocks(idleTimeout time.Duration, pusher core.Pusher, ctx context.Context) (err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "ReleaseIdleLocks",
+				"idleTimeout", idleTimeout,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.ReleaseIdleLocks(idleTimeout, pusher, ctx)
+}
+
+func (s *loggingService) DeleteExpiredMessages(
+	conversationID int,
+	sentBefore time.Time,
+	pathPrefix string,
+	pusher core.Pusher,
+	ctx context.Context) (nDeleted int, err error) {
+
+	defer func(begin time.Time) {
+		if err != nil || (s.verbose && nDeleted > 0) {
+			s.logger.Log(
+				"Use-Case", "DeleteExpiredMessages",
+				"conversationID", conversationID,
+				"sentBefore", sentBefore,
+				"nDeleted", nDeleted,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.DeleteExpiredMessages(conversationID, sentBefore, pathPrefix, pusher, ctx)
+}
+
+func (s *loggingService) ForwardMessage(
+	userCtx, conversationID, messageID, targetConversationID int,
+	pusher core.Pusher,
+	ctx context.Context) (answer interface{}, err error) {
+
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "ForwardMessage",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"messageID", messageID,
+				"targetConversationID", targetConversationID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.ForwardMessage(userCtx, conversationID, messageID, targetConversationID, pusher, ctx)
+}
+
+func (s *loggingService) ListDrafts(userCtx int) (drafts []core.Draft, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "ListDrafts",
+				"userCtx", userCtx,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.ListDrafts(userCtx)
+}
+
+func (s *loggingService) GetDraft(userCtx, conversationID int) (draft core.Draft, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "GetDraft",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.GetDraft(userCtx, conversationID)
+}
+
+func (s *loggingService) SaveDraft(
+	userCtx int,
+	draft core.Draft,
+	pusher core.Pusher,
+	ctx context.Context) (saved core.Draft, err error) {
+
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "SaveDraft",
+				"userCtx", userCtx,
+				"conversationID", draft.ConversationID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.SaveDraft(userCtx, draft, pusher, ctx)
+}
+
+func (s *loggingService) ClearDraft(userCtx, conversationID int, pusher core.Pusher, ctx context.Context) (err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "ClearDraft",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.ClearDraft(userCtx, conversationID, pusher, ctx)
+}
+
+func (s *loggingService) ListBookmarks(userCtx, beforeID, limit int, tag string) (bookmarks []core.Bookmark, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "ListBookmarks",
+				"userCtx", userCtx,
+				"beforeID", beforeID,
+				"limit", limit,
+				"tag", tag,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.ListBookmarks(userCtx, beforeID, limit, tag)
+}
+
+func (s *loggingService) SaveBookmark(userCtx int, bookmark core.Bookmark) (saved core.Bookmark, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "SaveBookmark",
+				"userCtx", userCtx,
+				"conversationID", bookmark.ConversationID,
+				"messageID", bookmark.MessageID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.SaveBookmark(userCtx, bookmark)
+}
+
+func (s *loggingService) DeleteBookmark(userCtx, conversationID, messageID int) (err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "DeleteBookmark",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"messageID", messageID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.DeleteBookmark(userCtx, conversationID, messageID)
+}
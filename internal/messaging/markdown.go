@@ -0,0 +1,563 @@
+package messaging
+
+import (
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The supported Markdown dialect is intentionally small:
+//   - paragraphs separated by blank lines, single line breaks are kept
+//   - fenced code blocks (``` or ~~~) with an optional language
+//   - inline code spans
+//   - links of the form [label](url) with http, https and mailto targets
+//   - unordered (-, *, +) and ordered (1. or 1)) lists
+//   - block quotes (>), which may contain any other block, up to maxQuoteDepth levels
+//
+// The generated HTML is safe by construction: every piece of user input is escaped
+// and only the tags produced by the renderer itself can appear in the output.
+// Sources longer than maxMarkdownLength are not parsed at all but rendered as plain text.
+
+const (
+	maxQuoteDepth     = 16
+	maxMarkdownLength = 64 << 10
+)
+
+var allowedLinkSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+type markdownRenderer struct {
+	html  strings.Builder
+	plain strings.Builder
+	depth int
+}
+
+// RenderMarkdown converts the source of a text message into sanitized HTML and
+// a plain text fallback without any markup.
+func RenderMarkdown(source string) (string, string) {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = strings.ReplaceAll(source, "\r", "\n")
+
+	if len(source) > maxMarkdownLength {
+		return renderPlainText(source), source
+	}
+
+	r := &markdownRenderer{}
+	r.renderBlocks(strings.Split(source, "\n"))
+	return r.html.String(), r.plain.String()
+}
+
+// renderPlainText escapes the source and keeps its line breaks.
+func renderPlainText(source string) string {
+	lines := strings.Split(html.EscapeString(source), "\n")
+	return "<p>" + strings.Join(lines, "<br>\n") + "</p>\n"
+}
+
+// isQuoteLine reports whether the line starts a nested quote. Beyond maxQuoteDepth
+// quote markers are treated as text.
+func (r *markdownRenderer) isQuoteLine(line string) bool {
+	_, ok := parseQuoteLine(line)
+	return ok && r.depth < maxQuoteDepth
+}
+
+func (r *markdownRenderer) renderBlocks(lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			i++
+			continue
+		}
+
+		if r.plain.Len() > 0 {
+			r.plain.WriteString("\n\n")
+		}
+
+		if _, _, ok := parseFence(line); ok {
+			i = r.renderCodeBlock(lines, i)
+		} else if r.isQuoteLine(line) {
+			i = r.renderQuote(lines, i)
+		} else if _, ok := parseListItem(line); ok {
+			i = r.renderList(lines, i)
+		} else {
+			i = r.renderParagraph(lines, i)
+		}
+	}
+}
+
+func (r *markdownRenderer) renderCodeBlock(lines []string, start int) int {
+	marker, info, _ := parseFence(lines[start])
+	language := sanitizeLanguage(info)
+
+	i := start + 1
+	content := make([]string, 0, 10)
+	for ; i < len(lines); i++ {
+		if isClosingFence(lines[i], marker) {
+			i++
+			break
+		}
+		content = append(content, lines[i])
+	}
+
+	code := strings.Join(content, "\n")
+	if language != "" {
+		r.html.WriteString(`<pre><code class="language-` + language + `">`)
+	} else {
+		r.html.WriteString("<pre><code>")
+	}
+	r.html.WriteString(html.EscapeString(code))
+	if len(content) > 0 {
+		r.html.WriteString("\n")
+	}
+	r.html.WriteString("</code></pre>\n")
+	r.plain.WriteString(code)
+
+	return i
+}
+
+func (r *markdownRenderer) renderQuote(lines []string, start int) int {
+	i := start
+	content := make([]string, 0, 5)
+	for ; i < len(lines); i++ {
+		line, ok := parseQuoteLine(lines[i])
+		if !ok {
+			break
+		}
+		content = append(content, line)
+	}
+
+	inner := &markdownRenderer{depth: r.depth + 1}
+	inner.renderBlocks(content)
+
+	r.html.WriteString("<blockquote>\n")
+	r.html.WriteString(inner.html.String())
+	r.html.WriteString("</blockquote>\n")
+
+	quoted := strings.Split(inner.plain.String(), "\n")
+	for j, line := range quoted {
+		if j > 0 {
+			r.plain.WriteString("\n")
+		}
+		r.plain.WriteString(strings.TrimRight("> "+line, " "))
+	}
+
+	return i
+}
+
+func (r *markdownRenderer) renderList(lines []string, start int) int {
+	first, _ := parseListItem(lines[start])
+	items := make([]string, 0, 5)
+
+	i := start
+	for i < len(lines) {
+		line := lines[i]
+		if item, ok := parseListItem(line); ok {
+			if item.ordered != first.ordered {
+				break
+			}
+			items = append(items, item.content)
+			i++
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			// A blank line only continues the list if another item follows.
+			next := i + 1
+			for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+				next++
+			}
+			if next < len(lines) {
+				if item, ok := parseListItem(lines[next]); ok && item.ordered == first.ordered {
+					i = next
+					continue
+				}
+			}
+			break
+		}
+
+		// Indented lines continue the previous item.
+		if strings.HasPrefix(line, "  ") || strings.HasPrefix(line, "\t") {
+			items[len(items)-1] += "\n" + strings.TrimSpace(line)
+			i++
+			continue
+		}
+		break
+	}
+
+	tag := "ul"
+	if first.ordered {
+		tag = "ol"
+	}
+
+	if first.ordered && first.number != 1 {
+		r.html.WriteString(`<ol start="` + strconv.Itoa(first.number) + `">` + "\n")
+	} else {
+		r.html.WriteString("<" + tag + ">\n")
+	}
+
+	for j, item := range items {
+		itemHTML, itemPlain := renderInlineLines(strings.Split(item, "\n"))
+		r.html.WriteString("<li>" + itemHTML + "</li>\n")
+
+		if j > 0 {
+			r.plain.WriteString("\n")
+		}
+		if first.ordered {
+			r.plain.WriteString(strconv.Itoa(first.number+j) + ". ")
+		} else {
+			r.plain.WriteString("- ")
+		}
+		r.plain.WriteString(itemPlain)
+	}
+	r.html.WriteString("</" + tag + ">\n")
+
+	return i
+}
+
+func (r *markdownRenderer) renderParagraph(lines []string, start int) int {
+	i := start
+	content := make([]string, 0, 5)
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+
+		if i > start {
+			if _, _, ok := parseFence(line); ok {
+				break
+			}
+			if r.isQuoteLine(line) {
+				break
+			}
+			if _, ok := parseListItem(line); ok {
+				break
+			}
+		}
+		content = append(content, strings.TrimSpace(line))
+	}
+
+	paragraphHTML, paragraphPlain := renderInlineLines(content)
+	r.html.WriteString("<p>" + paragraphHTML + "</p>\n")
+	r.plain.WriteString(paragraphPlain)
+
+	return i
+}
+
+// renderInlineLines renders every line on its own and joins them with line breaks.
+func renderInlineLines(lines []string) (string, string) {
+	htmlLines := make([]string, len(lines))
+	plainLines := make([]string, len(lines))
+	for i, line := range lines {
+		htmlLines[i], plainLines[i] = renderInline(line, true)
+	}
+	return strings.Join(htmlLines, "<br>\n"), strings.Join(plainLines, "\n")
+}
+
+func renderInline(text string, allowLinks bool) (string, string) {
+	var htmlOut, plainOut strings.Builder
+	var links *linkIndex
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && strings.IndexByte("\\`*_{}[]()#+-.!<>~|", text[i+1]) >= 0:
+			htmlOut.WriteString(html.EscapeString(text[i+1 : i+2]))
+			plainOut.WriteByte(text[i+1])
+			i += 2
+			continue
+		case c == '`':
+			run := countRun(text[i:], '`')
+			closing := findClosingRun(text, i+run, run)
+			if closing < 0 {
+				htmlOut.WriteString(text[i : i+run])
+				plainOut.WriteString(text[i : i+run])
+				i += run
+				continue
+			}
+
+			code := text[i+run : closing]
+			if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+				code = code[1 : len(code)-1]
+			}
+			htmlOut.WriteString("<code>" + html.EscapeString(code) + "</code>")
+			plainOut.WriteString(code)
+			i = closing + run
+			continue
+		case c == '[' && allowLinks:
+			if links == nil {
+				links = newLinkIndex(text)
+			}
+
+			if label, target, end, ok := parseLink(text, i, links); ok {
+				labelHTML, labelPlain := renderInline(label, false)
+				if isSafeURL(target) {
+					htmlOut.WriteString(`<a href="` + html.EscapeString(target) + `" rel="noopener noreferrer nofollow">`)
+					htmlOut.WriteString(labelHTML)
+					htmlOut.WriteString("</a>")
+
+					plainOut.WriteString(labelPlain)
+					if labelPlain != target {
+						plainOut.WriteString(" (" + target + ")")
+					}
+				} else {
+					htmlOut.WriteString(labelHTML)
+					plainOut.WriteString(labelPlain)
+				}
+				i = end
+				continue
+			}
+		}
+
+		_, size := utf8.DecodeRuneInString(text[i:])
+		htmlOut.WriteString(html.EscapeString(text[i : i+size]))
+		plainOut.WriteString(text[i : i+size])
+		i += size
+	}
+
+	return htmlOut.String(), plainOut.String()
+}
+
+// linkIndex holds the positions parseLink looks up in a text. They are computed in a single
+// pass, so trying to parse a link at every '[' of the text takes linear time.
+type linkIndex struct {
+	closingBrackets []int // position of the next ']' at or after i, or -1
+	closingParens   []int // position of the ')' matching the '(' at i, or -1
+	blanks          []int // number of spaces and tabs before i
+}
+
+func newLinkIndex(text string) *linkIndex {
+	index := &linkIndex{
+		closingBrackets: make([]int, len(text)+1),
+		closingParens:   make([]int, len(text)),
+		blanks:          make([]int, len(text)+1),
+	}
+
+	openParens := make([]int, 0)
+	for i := 0; i < len(text); i++ {
+		index.closingParens[i] = -1
+		index.blanks[i+1] = index.blanks[i]
+		switch text[i] {
+		case '(':
+			openParens = append(openParens, i)
+		case ')':
+			if len(openParens) > 0 {
+				index.closingParens[openParens[len(openParens)-1]] = i
+				openParens = openParens[:len(openParens)-1]
+			}
+		case ' ', '\t':
+			index.blanks[i+1]++
+		}
+	}
+
+	index.closingBrackets[len(text)] = -1
+	for i := len(text) - 1; i >= 0; i-- {
+		if text[i] == ']' {
+			index.closingBrackets[i] = i
+		} else {
+			index.closingBrackets[i] = index.closingBrackets[i+1]
+		}
+	}
+	return index
+}
+
+// parseLink parses a link of the form [label](target) starting at text[start]. Parentheses
+// within the target have to be balanced. It returns the index of the first byte after the link.
+func parseLink(text string, start int, index *linkIndex) (string, string, int, bool) {
+	labelEnd := index.closingBrackets[start+1]
+	if labelEnd < 0 {
+		return "", "", 0, false
+	}
+
+	if labelEnd+1 >= len(text) || text[labelEnd+1] != '(' {
+		return "", "", 0, false
+	}
+
+	linkEnd := index.closingParens[labelEnd+1]
+	if linkEnd < 0 {
+		return "", "", 0, false
+	}
+
+	// The target may be surrounded by whitespace, but must not contain any.
+	targetStart, targetEnd := labelEnd+2, linkEnd
+	for targetStart < targetEnd && isASCIISpace(text[targetStart]) {
+		targetStart++
+	}
+	for targetEnd > targetStart && isASCIISpace(text[targetEnd-1]) {
+		targetEnd--
+	}
+	if index.blanks[targetEnd] != index.blanks[targetStart] {
+		return "", "", 0, false
+	}
+
+	target := strings.TrimSpace(text[targetStart:targetEnd])
+	if target == "" {
+		return "", "", 0, false
+	}
+
+	label := text[start+1 : labelEnd]
+	if strings.TrimSpace(label) == "" {
+		label = target
+	}
+
+	return label, target, linkEnd + 1, true
+}
+
+func isASCIISpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\v' || c == '\f' || c == '\r'
+}
+
+func isSafeURL(target string) bool {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if !allowedLinkSchemes[scheme] {
+		return false
+	}
+
+	return scheme == "mailto" || u.Host != ""
+}
+
+func countRun(text string, c byte) int {
+	n := 0
+	for n < len(text) && text[n] == c {
+		n++
+	}
+	return n
+}
+
+// findClosingRun returns the index of the next run of exactly n backticks at or after start.
+func findClosingRun(text string, start, n int) int {
+	for i := start; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+
+		run := countRun(text[i:], '`')
+		if run == n {
+			return i
+		}
+		i += run
+	}
+	return -1
+}
+
+// stripBlockIndent removes up to three leading spaces. Lines indented further
+// cannot start a block.
+func stripBlockIndent(line string) (string, bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return "", false
+	}
+	return trimmed, true
+}
+
+func parseFence(line string) (string, string, bool) {
+	trimmed, ok := stripBlockIndent(line)
+	if !ok || len(trimmed) < 3 || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return "", "", false
+	}
+
+	run := countRun(trimmed, trimmed[0])
+	if run < 3 {
+		return "", "", false
+	}
+
+	info := strings.TrimSpace(trimmed[run:])
+	if trimmed[0] == '`' && strings.Contains(info, "`") {
+		return "", "", false
+	}
+
+	return trimmed[:run], info, true
+}
+
+func isClosingFence(line, marker string) bool {
+	trimmed, ok := stripBlockIndent(line)
+	if !ok || !strings.HasPrefix(trimmed, marker) {
+		return false
+	}
+
+	run := countRun(trimmed, marker[0])
+	return strings.TrimSpace(trimmed[run:]) == ""
+}
+
+// sanitizeLanguage returns the first word of a fence info string if it only
+// consists of characters which can be used in a class name.
+func sanitizeLanguage(info string) string {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	language := strings.ToLower(fields[0])
+	for _, c := range language {
+		isLetter := c >= 'a' && c <= 'z'
+		isDigit := c >= '0' && c <= '9'
+		if !isLetter && !isDigit && !strings.ContainsRune("+#-_.", c) {
+			return ""
+		}
+	}
+	return language
+}
+
+func parseQuoteLine(line string) (string, bool) {
+	trimmed, ok := stripBlockIndent(line)
+	if !ok || !strings.HasPrefix(trimmed, ">") {
+		return "", false
+	}
+
+	content := trimmed[1:]
+	if strings.HasPrefix(content, " ") {
+		content = content[1:]
+	}
+	return content, true
+}
+
+type listItem struct {
+	ordered bool
+	number  int
+	content string
+}
+
+func parseListItem(line string) (listItem, bool) {
+	trimmed, ok := stripBlockIndent(line)
+	if !ok || len(trimmed) < 2 {
+		return listItem{}, false
+	}
+
+	if strings.IndexByte("-*+", trimmed[0]) >= 0 {
+		if trimmed[1] != ' ' && trimmed[1] != '\t' {
+			return listItem{}, false
+		}
+		return listItem{content: strings.TrimSpace(trimmed[2:])}, true
+	}
+
+	digits := 0
+	for digits < len(trimmed) && digits < 9 && trimmed[digits] >= '0' && trimmed[digits] <= '9' {
+		digits++
+	}
+
+	if digits == 0 || digits+1 >= len(trimmed) {
+		return listItem{}, false
+	}
+
+	delimiter, separator := trimmed[digits], trimmed[digits+1]
+	if (delimiter != '.' && delimiter != ')') || (separator != ' ' && separator != '\t') {
+		return listItem{}, false
+	}
+
+	number, err := strconv.Atoi(trimmed[:digits])
+	if err != nil {
+		return listItem{}, false
+	}
+
+	return listItem{ordered: true, number: number, content: strings.TrimSpace(trimmed[digits+2:])}, true
+}
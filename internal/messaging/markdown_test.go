@@ -0,0 +1,132 @@
+package messaging
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		html  string
+		plain string
+	}{
+		{
+			name:  "paragraphs and line breaks",
+			input: "Hello\nWorld\n\nSecond",
+			html:  "<p>Hello<br>\nWorld</p>\n<p>Second</p>\n",
+			plain: "Hello\nWorld\n\nSecond",
+		},
+		{
+			name:  "html is escaped",
+			input: `<script>alert("x")</script>`,
+			html:  "<p>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;</p>\n",
+			plain: `<script>alert("x")</script>`,
+		},
+		{
+			name:  "fenced code with language",
+			input: "```go\nfmt.Println(\"<b>\")\n```",
+			html:  "<pre><code class=\"language-go\">fmt.Println(&#34;&lt;b&gt;&#34;)\n</code></pre>\n",
+			plain: "fmt.Println(\"<b>\")",
+		},
+		{
+			name:  "invalid language is dropped",
+			input: "~~~\"><img\nx\n~~~",
+			html:  "<pre><code>x\n</code></pre>\n",
+			plain: "x",
+		},
+		{
+			name:  "inline code",
+			input: "use `a < b` and `` `x` ``",
+			html:  "<p>use <code>a &lt; b</code> and <code>`x`</code></p>\n",
+			plain: "use a < b and `x`",
+		},
+		{
+			name:  "links",
+			input: "see [docs](https://example.com/?a=1&b=2) or [me](mailto:a@b.c)",
+			html: "<p>see <a href=\"https://example.com/?a=1&amp;b=2\" rel=\"noopener noreferrer nofollow\">docs</a>" +
+				" or <a href=\"mailto:a@b.c\" rel=\"noopener noreferrer nofollow\">me</a></p>\n",
+			plain: "see docs (https://example.com/?a=1&b=2) or me (mailto:a@b.c)",
+		},
+		{
+			name:  "unsafe links are removed",
+			input: "[click](javascript:alert(1)) [x](//evil.com)",
+			html:  "<p>click x</p>\n",
+			plain: "click x",
+		},
+		{
+			name:  "links with parentheses",
+			input: "[Go](https://en.wikipedia.org/wiki/Go_(programming_language)) (see [here](https://a.b/(x)))",
+			html: "<p><a href=\"https://en.wikipedia.org/wiki/Go_(programming_language)\" rel=\"noopener noreferrer nofollow\">Go</a>" +
+				" (see <a href=\"https://a.b/(x)\" rel=\"noopener noreferrer nofollow\">here</a>)</p>\n",
+			plain: "Go (https://en.wikipedia.org/wiki/Go_(programming_language)) (see here (https://a.b/(x)))",
+		},
+		{
+			name:  "link targets with whitespace",
+			input: "[a](b c) [x]( https://a.b ) [y](https://a.b",
+			html:  "<p>[a](b c) <a href=\"https://a.b\" rel=\"noopener noreferrer nofollow\">x</a> [y](https://a.b</p>\n",
+			plain: "[a](b c) x (https://a.b) [y](https://a.b",
+		},
+		{
+			name:  "lists",
+			input: "- one\n- two\n  continued\n\n3. three\n4. four",
+			html:  "<ul>\n<li>one</li>\n<li>two<br>\ncontinued</li>\n</ul>\n<ol start=\"3\">\n<li>three</li>\n<li>four</li>\n</ol>\n",
+			plain: "- one\n- two\ncontinued\n\n3. three\n4. four",
+		},
+		{
+			name:  "quotes",
+			input: "> quoted\n> - item\n\nafter",
+			html:  "<blockquote>\n<p>quoted</p>\n<ul>\n<li>item</li>\n</ul>\n</blockquote>\n<p>after</p>\n",
+			plain: "> quoted\n>\n> - item\n\nafter",
+		},
+		{
+			name:  "escaped characters",
+			input: `\[not a link\](x) \` + "`",
+			html:  "<p>[not a link](x) `</p>\n",
+			plain: "[not a link](x) `",
+		},
+	}
+
+	for _, test := range tests {
+		html, plain := RenderMarkdown(test.input)
+		if html != test.html {
+			t.Errorf("%s: expected html %q, got %q", test.name, test.html, html)
+		}
+		if plain != test.plain {
+			t.Errorf("%s: expected plain text %q, got %q", test.name, test.plain, plain)
+		}
+	}
+}
+
+func TestRenderMarkdownLimits(t *testing.T) {
+	html, _ := RenderMarkdown(strings.Repeat(">", 20000) + " deep")
+	if n := strings.Count(html, "<blockquote>"); n != maxQuoteDepth {
+		t.Errorf("expected %d nested quotes, got %d", maxQuoteDepth, n)
+	}
+
+	if !strings.Contains(html, "<p>"+strings.Repeat("&gt;", 20000-maxQuoteDepth)+" deep</p>") {
+		t.Errorf("quote markers beyond the maximum depth are not kept as text")
+	}
+
+	source := strings.Repeat("> [a](https://a.b)\n", maxMarkdownLength/10)
+	html, plain := RenderMarkdown(source)
+	if strings.Contains(html, "<blockquote>") || strings.Contains(html, "<a ") || plain != source {
+		t.Errorf("long sources are parsed")
+	}
+
+	if !strings.HasPrefix(html, "<p>&gt; [a](https://a.b)<br>\n") {
+		t.Errorf("unexpected html %q", html[:50])
+	}
+
+	// Unclosed links must not make rendering quadratic.
+	begin := time.Now()
+	html, _ = RenderMarkdown(strings.Repeat("[a](", (maxMarkdownLength-1)/4))
+	if took := time.Since(begin); took > 200*time.Millisecond {
+		t.Errorf("rendering unclosed links took %v", took)
+	}
+	if strings.Contains(html, "<a ") {
+		t.Errorf("unclosed links are rendered")
+	}
+}
@@ -0,0 +1,74 @@
+package messaging
+
+import (
+	"strings"
+	"unicode"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+const maxMentionsLimit = 100
+
+// ListMentions returns the messages the user was mentioned in, newest first.
+func (s *service) ListMentions(userCtx, beforeInSequence, limit int) ([]core.Mention, error) {
+	if limit <= 0 || limit > maxMentionsLimit {
+		return nil, core.NewInvalidValueError("limit")
+	}
+
+	return s.messageRepo.FindMentionsForUser(userCtx, beforeInSequence, limit)
+}
+
+// resolveMentions returns the ids of all joined members of the conversation that are
+// mentioned in the text. The author is never mentioned.
+func (s *service) resolveMentions(conversationID, authorID int, text string) ([]int, error) {
+	names := parseMentions(text)
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	members, err := s.conversationRepo.GetUsersInConversation(conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	mentions := make([]int, 0, len(names))
+	for _, member := range members {
+		if member.ID == authorID || !member.HasJoined || member.HasLeft || member.IsDeleted {
+			continue
+		}
+
+		if _, ok := names[strings.ToLower(member.Name)]; ok {
+			mentions = append(mentions, member.ID)
+		}
+	}
+	return mentions, nil
+}
+
+// parseMentions returns the lower case names of every @name in the text. An @ is only
+// taken into account at the beginning of a word so that e-mail addresses are ignored.
+func parseMentions(text string) map[string]struct{} {
+	names := make(map[string]struct{})
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '@' || (i > 0 && isMentionRune(runes[i-1])) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && isMentionRune(runes[end]) {
+			end++
+		}
+
+		// Trailing dots are treated as punctuation.
+		name := strings.TrimRight(string(runes[i+1:end]), ".")
+		if name != "" {
+			names[strings.ToLower(name)] = struct{}{}
+		}
+		i = end - 1
+	}
+	return names
+}
+
+func isMentionRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.'
+}
@@ -43,16 +43,23 @@ func (s *service) ToggleLiveSession(
 
 	var reply interface{}
 	if state == false {
-		reply = struct {
-			MessageID int `json:"messageId"`
-			NewOwner  int `json:"newOwner"`
-		}{stub.ID, 0}
-		err = s.messageRepo.SetLockedSateForCodeMessage(stub.ID, 0)
+		// Only the holder ends a live session here, admins use ForceReleaseLock.
+		err = s.messageRepo.ReleaseLock(stub.ID, userCtx)
+		if err == core.ErrNothingChanged {
+			return 0, core.ErrAccessDenied
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		s.documents.remove(stub.ID)
+		s.handOvers.Lock()
+		delete(s.handOvers.m, stub.ID)
+		s.handOvers.Unlock()
+		reply = lockOwnerChange{stub.ID, 0}
 	} else if message.LockedBy == 0 {
-		reply = struct {
-			MessageID int `json:"messageId"`
-			NewOwner  int `json:"newOwner"`
-		}{stub.ID, userCtx}
+		reply = lockOwnerChange{stub.ID, userCtx}
 		err = s.messageRepo.SetLockedSateForCodeMessage(stub.ID, userCtx)
 	}
 
@@ -63,23 +70,6 @@ func (s *service) ToggleLiveSession(
 	return message.ID, err
 }
 
-func (s *service) ReadMessages(userID int, message json.RawMessage) error {
-	payload := struct {
-		ConversationID int `json:"conversationId"`
-	}{}
-	err := json.Unmarshal(message, &payload)
-	if err != nil {
-		return core.NewJSONFormatError(err.Error())
-	}
-
-	err = s.errorIFIsNotInConversation(userID, payload.ConversationID)
-	if err != nil {
-		return err
-	}
-
-	return s.messageRepo.SetReadFlags(userID, payload.ConversationID)
-}
-
 func (s *service) SendMessage(
 	target, userID int,
 	message json.RawMessage,
@@ -97,6 +87,16 @@ func (s *service) SendMessage(
 		return nil, core.NewJSONFormatError(err.Error())
 	}
 
+	parentID, err := s.resolveThreadRoot(target, stub.ParentID)
+	if err != nil {
+		return nil, err
+	}
+
+	quote, err := s.resolveQuote(target, stub.QuotedID)
+	if err != nil {
+		return nil, err
+	}
+
 	messageType := core.MessageType(stub.Type)
 	var answer interface{}
 	switch messageType {
@@ -106,11 +106,18 @@ func (s *service) SendMessage(
 		if err != nil {
 			return nil, core.NewJSONFormatError(err.Error())
 		}
+		actualMessage.ParentID = parentID
+		actualMessage.Quote, actualMessage.Origin = quote, nil
+		actualMessage.HTML, actualMessage.PlainText = RenderMarkdown(actualMessage.Text)
 		messageID, err := s.messageRepo.StoreTextMessage(target, userID, actualMessage)
 		if err != nil {
 			return nil, err
 		}
 		actualMessage.ID = messageID
+		actualMessage.Mentions, err = s.storeMentions(target, userID, messageID, actualMessage.Text)
+		if err != nil {
+			return nil, err
+		}
 		answer = actualMessage
 		pusher.BroadcastToRoom(target, answer, ctx)
 	case core.CodeMessageType:
@@ -119,11 +126,22 @@ func (s *service) SendMessage(
 		if err != nil {
 			return nil, core.NewJSONFormatError(err.Error())
 		}
+		err = s.prepareCodeMessage(&actualMessage)
+		if err != nil {
+			return nil, err
+		}
+
+		actualMessage.ParentID = parentID
+		actualMessage.Quote, actualMessage.Origin = quote, nil
 		messageID, err := s.messageRepo.StoreCodeMessage(target, userID, actualMessage)
 		if err != nil {
 			return nil, err
 		}
 		actualMessage.ID = messageID
+		actualMessage.Mentions, err = s.storeMentions(target, userID, messageID, actualMessage.Title)
+		if err != nil {
+			return nil, err
+		}
 		answer = actualMessage
 		pusher.BroadcastToRoom(target, answer, ctx)
 	case core.MediaMessageType:
@@ -132,6 +150,8 @@ func (s *service) SendMessage(
 		if err != nil {
 			return nil, core.NewJSONFormatError(err.Error())
 		}
+		actualMessage.ParentID = parentID
+		actualMessage.Quote, actualMessage.Origin = quote, nil
 		messageID, err := s.messageRepo.StoreMediaMessage(target, userID, actualMessage)
 		if err != nil {
 			return nil, err
@@ -139,6 +159,53 @@ func (s *service) SendMessage(
 		actualMessage.ID = messageID
 		answer = actualMessage
 		pusher.Unicast(ctx, userID, answer)
+	case core.PollMessageType:
+		var actualMessage core.PollMessage
+		err := json.Unmarshal(message, &actualMessage)
+		if err != nil {
+			return nil, core.NewJSONFormatError(err.Error())
+		}
+
+		err = preparePoll(&actualMessage)
+		if err != nil {
+			return nil, err
+		}
+
+		actualMessage.ParentID = parentID
+		actualMessage.Quote, actualMessage.Origin = quote, nil
+		messageID, err := s.messageRepo.StorePollMessage(target, userID, actualMessage)
+		if err != nil {
+			return nil, err
+		}
+		actualMessage.ID = messageID
+		actualMessage.Mentions, err = s.storeMentions(target, userID, messageID, actualMessage.Question)
+		if err != nil {
+			return nil, err
+		}
+		answer = actualMessage
+		pusher.BroadcastToRoom(target, answer, ctx)
+	case core.DiffMessageType:
+		var actualMessage core.DiffMessage
+		err := json.Unmarshal(message, &actualMessage)
+		if err != nil {
+			return nil, core.NewJSONFormatError(err.Error())
+		}
+
+		err = prepareDiffMessage(&actualMessage)
+		if err != nil {
+			return nil, err
+		}
+
+		actualMessage.ParentID = parentID
+		actualMessage.Quote, actualMessage.Origin = quote, nil
+		messageID, err := s.messageRepo.StoreDiffMessage(target, userID, actualMessage)
+		if err != nil {
+			return nil, err
+		}
+		actualMessage.ID = messageID
+		actualMessage.Patch = ""
+		answer = actualMessage
+		pusher.BroadcastToRoom(target, answer, ctx)
 	default:
 		return nil, core.ErrMessageTypeNotImplemented
 	}
@@ -146,6 +213,70 @@ func (s *service) SendMessage(
 	return answer, nil
 }
 
+// ValidateMessage runs the same checks as SendMessage without storing the message.
+// Media messages are rejected, as their files can only be uploaded after sending.
+func (s *service) ValidateMessage(target int, message json.RawMessage) error {
+	var stub messageStub
+	err := json.Unmarshal(message, &stub)
+	if err != nil {
+		return core.NewJSONFormatError(err.Error())
+	}
+
+	_, err = s.resolveThreadRoot(target, stub.ParentID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.resolveQuote(target, stub.QuotedID)
+	if err != nil {
+		return err
+	}
+
+	switch core.MessageType(stub.Type) {
+	case core.TextMessageType:
+		var actualMessage core.TextMessage
+		err = json.Unmarshal(message, &actualMessage)
+	case core.CodeMessageType:
+		var actualMessage core.CodeMessage
+		err = json.Unmarshal(message, &actualMessage)
+		if err == nil {
+			return s.prepareCodeMessage(&actualMessage)
+		}
+	case core.PollMessageType:
+		var actualMessage core.PollMessage
+		err = json.Unmarshal(message, &actualMessage)
+		if err == nil {
+			return preparePoll(&actualMessage)
+		}
+	case core.DiffMessageType:
+		var actualMessage core.DiffMessage
+		err = json.Unmarshal(message, &actualMessage)
+		if err == nil {
+			return prepareDiffMessage(&actualMessage)
+		}
+	default:
+		return core.ErrInvalidMessageType
+	}
+
+	if err != nil {
+		return core.NewJSONFormatError(err.Error())
+	}
+	return nil
+}
+
+func (s *service) storeMentions(conversationID, authorID, messageID int, text string) ([]int, error) {
+	mentions, err := s.resolveMentions(conversationID, authorID, text)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.messageRepo.StoreMentions(messageID, mentions)
+	if err != nil {
+		return nil, err
+	}
+	return mentions, nil
+}
+
 func (s *service) EditMessage(
 	userCtx, conversationID int,
 	message json.RawMessage,
@@ -171,14 +302,36 @@ func (s *service) LiveEditMessage(
 	pusher core.Pusher,
 	ctx context.Context) (int, error) {
 
-	id, err := s.editMessage(userCtx, conversationID, message, pusher, ctx)
+	var data operationData
+	err := json.Unmarshal(message, &data)
+	if err != nil {
+		return 0, core.NewJSONFormatError(err.Error())
+	}
+
+	// Payloads without an operation are whole-file patches. They are rejected while another
+	// member holds the lock, whereas operations may be sent by every member.
+	if data.Operation == nil {
+		id, err := s.editMessage(userCtx, conversationID, message, pusher, ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		pusher.BroadcastToRoom(conversationID, message, ctx)
+		return id, nil
+	}
+
+	err = s.errorIFIsNotInConversation(userCtx, conversationID)
 	if err != nil {
 		return 0, err
 	}
 
-	pusher.BroadcastToRoom(conversationID, message, ctx)
+	canonical, err := s.applyOperation(userCtx, conversationID, data)
+	if err != nil {
+		return 0, err
+	}
 
-	return id, nil
+	pusher.BroadcastToRoom(conversationID, canonical, ctx)
+	return canonical.MessageID, nil
 }
 
 func (s *service) AddFileToMessage(
@@ -258,27 +411,66 @@ func (s *service) applyPatchDataToCodeMessage(userCtx, conversationID int, patch
 		return core.ErrAccessDenied
 	}
 
-	updatedCode := codeMessage.Code
-	if patchData.Patch != "" {
-		dmp := diffmatchpatch.New()
-		patches, err := dmp.PatchFromText(patchData.Patch)
-		if err != nil {
-			return err
+	files := CodeFiles(codeMessage)
+	if patchData.File < 0 || patchData.File > len(files) {
+		return core.NewInvalidValueError("file")
+	}
+
+	if patchData.File == len(files) {
+		if patchData.Delete {
+			return core.NewInvalidValueError("file")
+		}
+		files = append(files, core.CodeFile{})
+	}
+
+	if patchData.Delete {
+		if patchData.File == 0 {
+			return core.NewInvalidValueError("file")
+		}
+		files = append(files[:patchData.File], files[patchData.File+1:]...)
+	} else {
+		file := &files[patchData.File]
+		if patchData.Patch != "" {
+			dmp := diffmatchpatch.New()
+			patches, err := dmp.PatchFromText(patchData.Patch)
+			if err != nil {
+				return err
+			}
+			var results []bool
+			file.Code, results = dmp.PatchApply(patches, file.Code)
+			for _, applied := range results {
+				if !applied {
+					return core.ErrEditConflict
+				}
+			}
+		}
+
+		if patchData.Language != "" {
+			file.Language = patchData.Language
+		}
+
+		if patchData.Name != "" {
+			file.Name = strings.TrimSpace(patchData.Name)
 		}
-		updatedCode, _ = dmp.PatchApply(patches, codeMessage.Code)
 	}
 
-	updatedTitle := codeMessage.Title
 	if patchData.Title != "" {
-		updatedTitle = patchData.Title
+		codeMessage.Title = patchData.Title
+	}
+
+	// New files without a language get a detected one.
+	setCodeFiles(&codeMessage, files)
+	err = s.detectMissingLanguages(&codeMessage)
+	if err != nil {
+		return err
 	}
 
-	updatedLanguage := codeMessage.Language
-	if patchData.Language != "" {
-		updatedLanguage = patchData.Language
+	err = s.validateCodeFiles(codeMessage)
+	if err != nil {
+		return err
 	}
 
-	err = s.messageRepo.UpdateCode(patchData.MessageID, updatedCode, updatedTitle, updatedLanguage)
+	_, err = s.messageRepo.UpdateCode(patchData.MessageID, userCtx, codeMessage)
 	if err != nil {
 		return err
 	}
@@ -322,6 +514,38 @@ func (s *service) editMessage(
 		if err != nil {
 			return 0, err
 		}
+	case core.TextMessageType, core.MediaMessageType:
+		err = s.errorIfIsNotAuthorOrAdmin(userCtx, conversationID, messageFromDB)
+		if err != nil {
+			return 0, err
+		}
+
+		payload := struct {
+			Text *string `json:"text"`
+		}{}
+		err = json.Unmarshal(message, &payload)
+		if err != nil {
+			return 0, core.NewJSONFormatError(err.Error())
+		}
+
+		if payload.Text == nil {
+			return 0, core.NewJSONFormatError("text missing")
+		}
+
+		if messageFromDB.Type == core.TextMessageType && strings.TrimSpace(*payload.Text) == "" {
+			return 0, core.NewInvalidValueError("text")
+		}
+
+		// Only text messages are rendered, media captions are kept as they are.
+		var html, plainText string
+		if messageFromDB.Type == core.TextMessageType {
+			html, plainText = RenderMarkdown(*payload.Text)
+		}
+
+		err = s.messageRepo.UpdateMessageText(messageFromDB.ID, userCtx, *payload.Text, html, plainText)
+		if err != nil {
+			return 0, err
+		}
 	default:
 		return 0, core.ErrMessageTypeNotImplemented
 	}
@@ -329,6 +553,138 @@ func (s *service) editMessage(
 	return patchInfo.ID, nil
 }
 
+func (s *service) DeleteMessage(
+	userCtx, conversationID, messageID int,
+	pathPrefix string,
+	pusher core.Pusher,
+	ctx context.Context) (core.MessageTombstone, error) {
+
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return core.MessageTombstone{}, err
+	}
+
+	messageFromDB, err := s.messageRepo.FindMessageStubForConversation(conversationID, messageID)
+	if err != nil {
+		return core.MessageTombstone{}, err
+	}
+
+	err = s.errorIfIsNotAuthorOrAdmin(userCtx, conversationID, messageFromDB)
+	if err != nil {
+		return core.MessageTombstone{}, err
+	}
+
+	// The replies of a thread root are deleted with it.
+	var replies []core.Message
+	if messageFromDB.ParentID == 0 {
+		replies, err = s.messageRepo.FindReplyStubs([]int{messageID})
+		if err != nil {
+			return core.MessageTombstone{}, err
+		}
+	}
+
+	files, err := s.findMediaFiles(append(replies, messageFromDB))
+	if err != nil {
+		return core.MessageTombstone{}, err
+	}
+
+	err = s.messageRepo.DeleteMessage(messageID)
+	if err != nil {
+		return core.MessageTombstone{}, err
+	}
+
+	err = s.removeMediaFiles(pathPrefix, files)
+	if err != nil {
+		return core.MessageTombstone{}, err
+	}
+
+	tombstone := core.MessageTombstone{
+		MessageID: messageID,
+		ParentID:  messageFromDB.ParentID,
+	}
+	for _, reply := range replies {
+		tombstone.Replies = append(tombstone.Replies, reply.ID)
+	}
+	pusher.BroadcastToRoom(conversationID, tombstone, ctx)
+
+	return tombstone, nil
+}
+
+// findMediaFiles returns the media objects of all the passed messages.
+func (s *service) findMediaFiles(messages []core.Message) ([]core.MediaObject, error) {
+	files := make([]core.MediaObject, 0)
+	for _, message := range messages {
+		if message.Type != core.MediaMessageType {
+			continue
+		}
+
+		mediaObjects, err := s.messageRepo.FindMediaObjectsForMessage(message.ID)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, mediaObjects...)
+	}
+	return files, nil
+}
+
+// removeMediaFiles deletes the files of the passed media objects including their thumbnails.
+// Files that are still shared with a forwarded media object or do not exist are ignored.
+func (s *service) removeMediaFiles(pathPrefix string, files []core.MediaObject) error {
+	fileIDs := make([]int, len(files))
+	for i, file := range files {
+		fileIDs[i] = file.FileID
+	}
+
+	unreferenced, err := s.messageRepo.FindUnreferencedFiles(fileIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if !containsInt(unreferenced, file.FileID) {
+			continue
+		}
+
+		fileNames := []string{
+			fmt.Sprintf("%d-%s", file.FileID, file.Name),
+			fmt.Sprintf("%d-thumbnail-%s", file.FileID, file.Name),
+		}
+
+		for _, fileName := range fileNames {
+			err := os.Remove(path.Join(pathPrefix, fileName))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *service) errorIfIsNotAuthorOrAdmin(userCtx, conversationID int, message core.Message) error {
+	if message.AuthorID == userCtx {
+		return nil
+	}
+
+	isAdmin, err := s.conversationRepo.IsUserAdminOfConveration(userCtx, conversationID)
+	if err != nil {
+		return err
+	}
+
+	if !isAdmin {
+		return core.ErrAccessDenied
+	}
+	return nil
+}
+
 func (s *service) CompleteMessage(id int, err error) error {
 	if err != nil {
 		s.messageRepo.DeleteMessage(id)
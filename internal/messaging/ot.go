@@ -0,0 +1,235 @@
+package messaging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+var errOperationMismatch = errors.New("the base length of the operation does not match the document")
+
+// textOperation is a sequence of retain, insert and delete components that transforms a
+// document of baseLength into a document of targetLength. All lengths and positions are
+// counted in unicode code points.
+//
+// An operation is encoded in JSON as an array. Positive numbers retain, negative numbers
+// delete and strings insert characters, e.g. [3, "abc", -2, 4].
+type textOperation struct {
+	components   []opComponent
+	baseLength   int
+	targetLength int
+}
+
+// opComponent holds exactly one of its fields.
+type opComponent struct {
+	retain int
+	insert string
+	delete int
+}
+
+func (o *textOperation) retain(n int) {
+	if n <= 0 {
+		return
+	}
+
+	o.baseLength += n
+	o.targetLength += n
+	if last := len(o.components) - 1; last >= 0 && o.components[last].retain > 0 {
+		o.components[last].retain += n
+		return
+	}
+	o.components = append(o.components, opComponent{retain: n})
+}
+
+// insert keeps inserts in front of directly adjacent deletes so that equal operations
+// always have the same representation.
+func (o *textOperation) insert(s string) {
+	if s == "" {
+		return
+	}
+
+	o.targetLength += utf8.RuneCountInString(s)
+	last := len(o.components) - 1
+	if last >= 0 && o.components[last].insert != "" {
+		o.components[last].insert += s
+		return
+	}
+
+	if last >= 0 && o.components[last].delete > 0 {
+		if last > 0 && o.components[last-1].insert != "" {
+			o.components[last-1].insert += s
+			return
+		}
+
+		o.components = append(o.components, o.components[last])
+		o.components[last] = opComponent{insert: s}
+		return
+	}
+	o.components = append(o.components, opComponent{insert: s})
+}
+
+func (o *textOperation) delete(n int) {
+	if n <= 0 {
+		return
+	}
+
+	o.baseLength += n
+	if last := len(o.components) - 1; last >= 0 && o.components[last].delete > 0 {
+		o.components[last].delete += n
+		return
+	}
+	o.components = append(o.components, opComponent{delete: n})
+}
+
+func (o textOperation) apply(document string) (string, error) {
+	runes := []rune(document)
+	if len(runes) != o.baseLength {
+		return "", errOperationMismatch
+	}
+
+	result := make([]rune, 0, o.targetLength)
+	position := 0
+	for _, c := range o.components {
+		switch {
+		case c.retain > 0:
+			result = append(result, runes[position:position+c.retain]...)
+			position += c.retain
+		case c.insert != "":
+			result = append(result, []rune(c.insert)...)
+		case c.delete > 0:
+			position += c.delete
+		}
+	}
+	return string(result), nil
+}
+
+// transformOperations transforms two concurrent operations a and b, which are based on the same
+// document, into a' and b' so that apply(apply(d, a), b') == apply(apply(d, b), a').
+// Inserts of a at the same position as inserts of b are placed first.
+func transformOperations(a, b textOperation) (textOperation, textOperation, error) {
+	if a.baseLength != b.baseLength {
+		return textOperation{}, textOperation{}, errOperationMismatch
+	}
+
+	var aPrime, bPrime textOperation
+	i, j := 0, 0
+	var ca, cb *opComponent
+	next := func(components []opComponent, index *int) *opComponent {
+		if *index >= len(components) {
+			return nil
+		}
+		c := components[*index]
+		*index++
+		return &c
+	}
+	ca, cb = next(a.components, &i), next(b.components, &j)
+
+	for ca != nil || cb != nil {
+		if ca != nil && ca.insert != "" {
+			aPrime.insert(ca.insert)
+			bPrime.retain(utf8.RuneCountInString(ca.insert))
+			ca = next(a.components, &i)
+			continue
+		}
+
+		if cb != nil && cb.insert != "" {
+			aPrime.retain(utf8.RuneCountInString(cb.insert))
+			bPrime.insert(cb.insert)
+			cb = next(b.components, &j)
+			continue
+		}
+
+		if ca == nil || cb == nil {
+			return textOperation{}, textOperation{}, errOperationMismatch
+		}
+
+		lengthA, lengthB := ca.retain+ca.delete, cb.retain+cb.delete
+		length := lengthA
+		if lengthB < length {
+			length = lengthB
+		}
+
+		switch {
+		case ca.retain > 0 && cb.retain > 0:
+			aPrime.retain(length)
+			bPrime.retain(length)
+		case ca.delete > 0 && cb.retain > 0:
+			aPrime.delete(length)
+		case ca.retain > 0 && cb.delete > 0:
+			bPrime.delete(length)
+		}
+		// Both deleted the same characters, so there is nothing left to do for either of them.
+
+		ca = consume(ca, length, func() *opComponent { return next(a.components, &i) })
+		cb = consume(cb, length, func() *opComponent { return next(b.components, &j) })
+	}
+
+	return aPrime, bPrime, nil
+}
+
+// consume shortens a retain or delete component by n and returns the next component once
+// it has been used up.
+func consume(c *opComponent, n int, next func() *opComponent) *opComponent {
+	if c.retain > 0 {
+		c.retain -= n
+		if c.retain == 0 {
+			return next()
+		}
+		return c
+	}
+
+	c.delete -= n
+	if c.delete == 0 {
+		return next()
+	}
+	return c
+}
+
+func (o textOperation) MarshalJSON() ([]byte, error) {
+	encoded := make([]interface{}, len(o.components))
+	for i, c := range o.components {
+		switch {
+		case c.retain > 0:
+			encoded[i] = c.retain
+		case c.insert != "":
+			encoded[i] = c.insert
+		default:
+			encoded[i] = -c.delete
+		}
+	}
+	return json.Marshal(encoded)
+}
+
+func (o *textOperation) UnmarshalJSON(data []byte) error {
+	var raw []interface{}
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	*o = textOperation{}
+	for _, component := range raw {
+		switch c := component.(type) {
+		case string:
+			if c == "" {
+				return fmt.Errorf("empty insert")
+			}
+			o.insert(c)
+		case float64:
+			n := int(c)
+			if float64(n) != c || n == 0 {
+				return fmt.Errorf("invalid component %v", c)
+			}
+
+			if n > 0 {
+				o.retain(n)
+			} else {
+				o.delete(-n)
+			}
+		default:
+			return fmt.Errorf("invalid component %v", c)
+		}
+	}
+	return nil
+}
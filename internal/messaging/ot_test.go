@@ -0,0 +1,99 @@
+package messaging
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func parseOperation(t *testing.T, s string) textOperation {
+	var o textOperation
+	err := json.Unmarshal([]byte(s), &o)
+	if err != nil {
+		t.Fatalf("%s: %s", s, err)
+	}
+	return o
+}
+
+func TestOperationJSON(t *testing.T) {
+	o := parseOperation(t, `[2, -1, "ab", 1, 1]`)
+	if o.baseLength != 5 || o.targetLength != 6 {
+		t.Errorf("unexpected lengths %d %d", o.baseLength, o.targetLength)
+	}
+
+	encoded, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Inserts are moved in front of deletes and adjacent retains are merged.
+	if string(encoded) != `[2,"ab",-1,2]` {
+		t.Errorf("unexpected encoding %s", encoded)
+	}
+
+	var invalid textOperation
+	if json.Unmarshal([]byte(`[1.5]`), &invalid) == nil {
+		t.Error("expected an error for a fractional component")
+	}
+}
+
+func TestOperationApply(t *testing.T) {
+	o := parseOperation(t, `[1, "ö", -2, 1]`)
+	result, err := o.apply("äbcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result != "äöd" {
+		t.Errorf("unexpected result %q", result)
+	}
+
+	if _, err := o.apply("abc"); err == nil {
+		t.Error("expected an error for a document of wrong length")
+	}
+}
+
+func TestTransformOperations(t *testing.T) {
+	tests := []struct {
+		document string
+		a        string
+		b        string
+		expected string
+	}{
+		{"hello", `[5, " world"]`, `["oh, ", 5]`, "oh, hello world"},
+		{"hello", `[2, "X", 3]`, `[2, "Y", 3]`, "heXYllo"},
+		{"abcdef", `[1, -3, 2]`, `[2, -3, 1]`, "af"},
+		{"abcdef", `[-6]`, `[3, "x", 3]`, "x"},
+		{"abc", `[1, -1, "Z", 1]`, `[-1, 2]`, "Zc"},
+	}
+
+	for _, test := range tests {
+		a := parseOperation(t, test.a)
+		b := parseOperation(t, test.b)
+
+		aPrime, bPrime, err := transformOperations(a, b)
+		if err != nil {
+			t.Fatalf("%s %s: %s", test.a, test.b, err)
+		}
+
+		afterA, _ := a.apply(test.document)
+		resultA, err := bPrime.apply(afterA)
+		if err != nil {
+			t.Fatalf("%s %s: %s", test.a, test.b, err)
+		}
+
+		afterB, _ := b.apply(test.document)
+		resultB, err := aPrime.apply(afterB)
+		if err != nil {
+			t.Fatalf("%s %s: %s", test.a, test.b, err)
+		}
+
+		if resultA != resultB || resultA != test.expected {
+			t.Errorf("%s %s: expected %q, got %q and %q", test.a, test.b, test.expected, resultA, resultB)
+		}
+	}
+
+	_, _, err := transformOperations(parseOperation(t, `[1]`), parseOperation(t, `[2]`))
+	if err == nil {
+		t.Error("expected an error for operations with different base lengths")
+	}
+}
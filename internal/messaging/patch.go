@@ -0,0 +1,270 @@
+package messaging
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+const maxPatchSize = 1 << 20
+
+var (
+	hunkHeaderRegex    = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@ ?(.*)$`)
+	patchSubjectPrefix = regexp.MustCompile(`^\[[^\]]*\]\s*`)
+)
+
+// parsedPatch is the result of parsing a unified diff or one or more mails
+// created by git format-patch.
+type parsedPatch struct {
+	Subject string
+	Files   []core.DiffFile
+}
+
+func (p parsedPatch) lineCounts() (int, int) {
+	additions, deletions := 0, 0
+	for _, f := range p.Files {
+		additions += f.Additions
+		deletions += f.Deletions
+	}
+	return additions, deletions
+}
+
+// parsePatch splits a patch into files and hunks. Everything that is not part of a
+// file header or a hunk (mail headers, commit messages, diffstats) is skipped.
+func parsePatch(patch string) (parsedPatch, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+
+	var result parsedPatch
+	current := -1
+	inHeader := false
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			oldPath, newPath := parseGitDiffPaths(line[len("diff --git "):])
+			result.Files = append(result.Files, core.DiffFile{
+				OldPath: oldPath,
+				NewPath: newPath,
+				Status:  core.DiffFileModified,
+			})
+			current = len(result.Files) - 1
+			inHeader = true
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			if current < 0 || !inHeader {
+				result.Files = append(result.Files, core.DiffFile{Status: core.DiffFileModified})
+				current = len(result.Files) - 1
+				inHeader = true
+			}
+
+			file := &result.Files[current]
+			oldPath, newPath := parseDiffPath(line[4:]), parseDiffPath(lines[i+1][4:])
+			if oldPath == "/dev/null" {
+				file.Status = core.DiffFileAdded
+			} else if file.Status != core.DiffFileRenamed {
+				file.OldPath = oldPath
+			}
+
+			if newPath == "/dev/null" {
+				file.Status = core.DiffFileDeleted
+			} else if file.Status != core.DiffFileRenamed {
+				file.NewPath = newPath
+			}
+			i++
+		case strings.HasPrefix(line, "@@ "):
+			if current < 0 {
+				return parsedPatch{}, core.NewInvalidValueError("patch")
+			}
+
+			hunk, next, err := parseHunk(lines, i)
+			if err != nil {
+				return parsedPatch{}, err
+			}
+
+			file := &result.Files[current]
+			for _, l := range hunk.Lines {
+				switch l.Type {
+				case "add":
+					file.Additions++
+				case "delete":
+					file.Deletions++
+				}
+			}
+			file.Hunks = append(file.Hunks, hunk)
+			inHeader = false
+			i = next - 1
+		case current >= 0 && inHeader:
+			parseExtendedHeader(&result.Files[current], line)
+		case current < 0 && result.Subject == "" && strings.HasPrefix(line, "Subject: "):
+			subject := line[len("Subject: "):]
+			// Long subjects are folded onto indented continuation lines.
+			for i+1 < len(lines) && strings.HasPrefix(lines[i+1], " ") {
+				subject += lines[i+1]
+				i++
+			}
+			result.Subject = strings.TrimSpace(patchSubjectPrefix.ReplaceAllString(subject, ""))
+		}
+	}
+
+	if len(result.Files) == 0 {
+		return parsedPatch{}, core.NewInvalidValueError("patch")
+	}
+
+	for i := range result.Files {
+		switch result.Files[i].Status {
+		case core.DiffFileAdded:
+			result.Files[i].OldPath = ""
+		case core.DiffFileDeleted:
+			result.Files[i].NewPath = ""
+		}
+	}
+
+	return result, nil
+}
+
+func parseExtendedHeader(file *core.DiffFile, line string) {
+	switch {
+	case strings.HasPrefix(line, "new file mode"):
+		file.Status = core.DiffFileAdded
+	case strings.HasPrefix(line, "deleted file mode"):
+		file.Status = core.DiffFileDeleted
+	case strings.HasPrefix(line, "rename from "):
+		file.Status = core.DiffFileRenamed
+		file.OldPath = line[len("rename from "):]
+	case strings.HasPrefix(line, "rename to "):
+		file.Status = core.DiffFileRenamed
+		file.NewPath = line[len("rename to "):]
+	case strings.HasPrefix(line, "Binary files "), strings.HasPrefix(line, "GIT binary patch"):
+		file.IsBinary = true
+	}
+}
+
+func parseHunk(lines []string, start int) (core.DiffHunk, int, error) {
+	match := hunkHeaderRegex.FindStringSubmatch(lines[start])
+	if match == nil {
+		return core.DiffHunk{}, 0, core.NewInvalidValueError("patch")
+	}
+
+	hunk := core.DiffHunk{
+		OldStart: atoiOrDefault(match[1], 0),
+		OldLines: atoiOrDefault(match[2], 1),
+		NewStart: atoiOrDefault(match[3], 0),
+		NewLines: atoiOrDefault(match[4], 1),
+		Section:  match[5],
+		Lines:    make([]core.DiffLine, 0, 10),
+	}
+
+	oldNumber, newNumber := hunk.OldStart, hunk.NewStart
+	oldRemaining, newRemaining := hunk.OldLines, hunk.NewLines
+
+	i := start + 1
+	for ; i < len(lines) && (oldRemaining > 0 || newRemaining > 0); i++ {
+		line := lines[i]
+		// Some editors strip the trailing space of empty context lines. The empty
+		// string after the final newline of the patch is not a line though.
+		if line == "" && i < len(lines)-1 {
+			line = " "
+		}
+
+		if line == "" {
+			break
+		}
+
+		switch line[0] {
+		case ' ':
+			if oldRemaining == 0 || newRemaining == 0 {
+				return core.DiffHunk{}, 0, core.NewInvalidValueError("patch")
+			}
+			hunk.Lines = append(hunk.Lines, core.DiffLine{
+				Type:      "context",
+				Content:   line[1:],
+				OldNumber: oldNumber,
+				NewNumber: newNumber,
+			})
+			oldNumber++
+			newNumber++
+			oldRemaining--
+			newRemaining--
+		case '-':
+			if oldRemaining == 0 {
+				return core.DiffHunk{}, 0, core.NewInvalidValueError("patch")
+			}
+			hunk.Lines = append(hunk.Lines, core.DiffLine{Type: "delete", Content: line[1:], OldNumber: oldNumber})
+			oldNumber++
+			oldRemaining--
+		case '+':
+			if newRemaining == 0 {
+				return core.DiffHunk{}, 0, core.NewInvalidValueError("patch")
+			}
+			hunk.Lines = append(hunk.Lines, core.DiffLine{Type: "add", Content: line[1:], NewNumber: newNumber})
+			newNumber++
+			newRemaining--
+		case '\\':
+			markNoNewline(&hunk)
+		default:
+			return core.DiffHunk{}, 0, core.NewInvalidValueError("patch")
+		}
+	}
+
+	if oldRemaining > 0 || newRemaining > 0 {
+		return core.DiffHunk{}, 0, core.NewInvalidValueError("patch")
+	}
+
+	// The marker for a missing newline may follow the last line of a hunk.
+	if i < len(lines) && strings.HasPrefix(lines[i], "\\") {
+		markNoNewline(&hunk)
+		i++
+	}
+
+	return hunk, i, nil
+}
+
+func markNoNewline(hunk *core.DiffHunk) {
+	if len(hunk.Lines) > 0 {
+		hunk.Lines[len(hunk.Lines)-1].NoNewlineAtEOF = true
+	}
+}
+
+func parseGitDiffPaths(paths string) (string, string) {
+	if strings.HasPrefix(paths, "a/") {
+		if i := strings.Index(paths, " b/"); i >= 0 {
+			return paths[2:i], paths[i+3:]
+		}
+	}
+
+	fields := strings.Fields(paths)
+	if len(fields) == 2 {
+		return fields[0], fields[1]
+	}
+	return paths, paths
+}
+
+// parseDiffPath extracts the path of a ---/+++ line, which may be followed by a timestamp.
+func parseDiffPath(path string) string {
+	if i := strings.IndexByte(path, '\t'); i >= 0 {
+		path = path[:i]
+	}
+
+	path = strings.Trim(strings.TrimSpace(path), `"`)
+	if path == "/dev/null" {
+		return path
+	}
+
+	if strings.HasPrefix(path, "a/") || strings.HasPrefix(path, "b/") {
+		return path[2:]
+	}
+	return path
+}
+
+func atoiOrDefault(s string, defaultValue int) int {
+	if s == "" {
+		return defaultValue
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return defaultValue
+	}
+	return n
+}
@@ -0,0 +1,115 @@
+package messaging
+
+import (
+	"testing"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+const formatPatch = `From 1a2b3c Mon Sep 17 00:00:00 2001
+From: Jane Doe <jane@example.com>
+Date: Tue, 3 Mar 2020 10:00:00 +0100
+Subject: [PATCH 1/2] Fix the handling of empty
+ requests
+
+---
+ main.go   | 3 ++-
+ README.md | 1 +
+ old.txt => new.txt | 0
+ 3 files changed, 3 insertions(+), 1 deletion(-)
+
+diff --git a/main.go b/main.go
+index 83db48f..bf269f4 100644
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,5 @@ package main
+ package main
+
+-func main() {}
++func main() {
++}
+ // end
+\ No newline at end of file
+diff --git a/README.md b/README.md
+new file mode 100644
+index 0000000..e69de29
+--- /dev/null
++++ b/README.md
+@@ -0,0 +1 @@
++# Readme
+diff --git a/old.txt b/new.txt
+similarity index 100%
+rename from old.txt
+rename to new.txt
+--
+2.25.1
+`
+
+func TestParsePatch(t *testing.T) {
+	patch, err := parsePatch(formatPatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if patch.Subject != "Fix the handling of empty requests" {
+		t.Errorf("unexpected subject %q", patch.Subject)
+	}
+
+	expected := []core.DiffFile{
+		{OldPath: "main.go", NewPath: "main.go", Status: core.DiffFileModified, Additions: 2, Deletions: 1},
+		{OldPath: "", NewPath: "README.md", Status: core.DiffFileAdded, Additions: 1},
+		{OldPath: "old.txt", NewPath: "new.txt", Status: core.DiffFileRenamed},
+	}
+
+	if len(patch.Files) != len(expected) {
+		t.Fatalf("expected %d files, got %d", len(expected), len(patch.Files))
+	}
+
+	for i, e := range expected {
+		f := patch.Files[i]
+		if f.OldPath != e.OldPath || f.NewPath != e.NewPath || f.Status != e.Status ||
+			f.Additions != e.Additions || f.Deletions != e.Deletions {
+			t.Errorf("file %d: expected %+v, got %+v", i, e, f)
+		}
+	}
+
+	hunk := patch.Files[0].Hunks[0]
+	if hunk.Section != "package main" || len(hunk.Lines) != 6 {
+		t.Fatalf("unexpected hunk %+v", hunk)
+	}
+
+	last := hunk.Lines[5]
+	if last.Type != "context" || last.OldNumber != 4 || last.NewNumber != 5 || !last.NoNewlineAtEOF {
+		t.Errorf("unexpected last line %+v", last)
+	}
+
+	if additions, deletions := patch.lineCounts(); additions != 3 || deletions != 1 {
+		t.Errorf("unexpected line counts +%d -%d", additions, deletions)
+	}
+}
+
+func TestParsePlainUnifiedDiff(t *testing.T) {
+	diff := "--- a.c\t2020-03-03 10:00:00\n+++ a.c\t2020-03-03 10:05:00\n@@ -2 +2 @@\n-int x;\n+long x;\n"
+	patch, err := parsePatch(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(patch.Files) != 1 || patch.Files[0].NewPath != "a.c" || patch.Files[0].Additions != 1 {
+		t.Errorf("unexpected files %+v", patch.Files)
+	}
+}
+
+func TestParseInvalidPatch(t *testing.T) {
+	inputs := []string{
+		"",
+		"just some text",
+		"--- a\n+++ b\n@@ -1,2 +1,2 @@\n-x\n+y\n",
+	}
+
+	for _, input := range inputs {
+		if _, err := parsePatch(input); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package messaging
+
+import (
+	"context"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+// TogglePin pins or unpins a message. Only admins of the conversation are allowed to do so.
+func (s *service) TogglePin(
+	userCtx, conversationID, messageID int,
+	state bool,
+	pusher core.Pusher,
+	ctx context.Context) error {
+
+	isAdmin, err := s.conversationRepo.IsUserAdminOfConveration(userCtx, conversationID)
+	if err != nil {
+		return err
+	}
+
+	if !isAdmin {
+		return core.ErrAccessDenied
+	}
+
+	_, err = s.messageRepo.FindMessageStubForConversation(conversationID, messageID)
+	if err != nil {
+		return err
+	}
+
+	if state {
+		err = s.messageRepo.StorePin(conversationID, messageID, userCtx)
+	} else {
+		err = s.messageRepo.DeletePin(conversationID, messageID)
+	}
+	if err != nil {
+		return err
+	}
+
+	payload := struct {
+		MessageID int  `json:"messageId"`
+		IsPinned  bool `json:"isPinned"`
+		PinnedBy  int  `json:"pinnedBy"`
+	}{messageID, state, userCtx}
+	pusher.BroadcastToRoom(conversationID, payload, ctx)
+	return nil
+}
+
+func (s *service) ListPins(userCtx, conversationID int) ([]core.Pin, error) {
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.messageRepo.FindPinsForConversation(conversationID)
+}
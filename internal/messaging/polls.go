@@ -0,0 +1,121 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+const (
+	maxPollOptions      = 20
+	maxPollOptionLength = 200
+)
+
+// preparePoll validates a new poll and resets all fields that are controlled by the server.
+func preparePoll(poll *core.PollMessage) error {
+	poll.Question = strings.TrimSpace(poll.Question)
+	if poll.Question == "" {
+		return core.NewInvalidValueError("question")
+	}
+
+	if len(poll.Options) < 2 || len(poll.Options) > maxPollOptions {
+		return core.NewInvalidValueError("options")
+	}
+
+	for i := range poll.Options {
+		text := strings.TrimSpace(poll.Options[i].Text)
+		if text == "" || utf8.RuneCountInString(text) > maxPollOptionLength {
+			return core.NewInvalidValueError("options")
+		}
+
+		poll.Options[i] = core.PollOption{
+			ID:     i + 1,
+			Text:   text,
+			Voters: make([]int, 0),
+		}
+	}
+
+	if poll.CloseDate != nil {
+		if !poll.CloseDate.After(time.Now()) {
+			return core.NewInvalidValueError("closeDate")
+		}
+
+		closeDate := poll.CloseDate.UTC()
+		poll.CloseDate = &closeDate
+	}
+
+	poll.IsClosed = false
+	return nil
+}
+
+// TogglePollVote casts or retracts the vote of a user. Casting a vote in a single choice
+// poll replaces the previous vote of the user.
+func (s *service) TogglePollVote(
+	userCtx, conversationID int,
+	state bool,
+	message json.RawMessage,
+	pusher core.Pusher,
+	ctx context.Context) (int, error) {
+
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return 0, err
+	}
+
+	payload := struct {
+		MessageID int `json:"messageId"`
+		OptionID  int `json:"optionId"`
+	}{}
+	err = json.Unmarshal(message, &payload)
+	if err != nil {
+		return 0, core.NewJSONFormatError(err.Error())
+	}
+
+	poll, err := s.messageRepo.FindPollMessageForID(payload.MessageID, conversationID)
+	if err != nil {
+		return 0, err
+	}
+
+	if poll.IsClosed {
+		return 0, core.ErrExpired
+	}
+
+	if !hasPollOption(poll, payload.OptionID) {
+		return 0, core.NewInvalidValueError("optionId")
+	}
+
+	if state {
+		err = s.messageRepo.StorePollVote(payload.MessageID, payload.OptionID, userCtx, !poll.IsMultipleChoice)
+	} else {
+		err = s.messageRepo.DeletePollVote(payload.MessageID, payload.OptionID, userCtx)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	poll, err = s.messageRepo.FindPollMessageForID(payload.MessageID, conversationID)
+	if err != nil {
+		return 0, err
+	}
+
+	reply := struct {
+		MessageID int               `json:"messageId"`
+		Options   []core.PollOption `json:"options"`
+	}{payload.MessageID, poll.Options}
+	pusher.BroadcastToRoom(conversationID, reply, ctx)
+
+	return payload.MessageID, nil
+}
+
+func hasPollOption(poll core.PollMessage, optionID int) bool {
+	for _, o := range poll.Options {
+		if o.ID == optionID {
+			return true
+		}
+	}
+	return false
+}
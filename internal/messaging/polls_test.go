@@ -0,0 +1,61 @@
+package messaging
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+func pollOptions(texts ...string) []core.PollOption {
+	options := make([]core.PollOption, len(texts))
+	for i, text := range texts {
+		options[i] = core.PollOption{ID: 42, Text: text, Votes: 3, Voters: []int{1}}
+	}
+	return options
+}
+
+func TestPreparePoll(t *testing.T) {
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	manyOptions := make([]string, maxPollOptions+1)
+	for i := range manyOptions {
+		manyOptions[i] = "option"
+	}
+
+	tests := []struct {
+		name     string
+		poll     core.PollMessage
+		expected error
+	}{
+		{"valid", core.PollMessage{Question: " Lunch? ", Options: pollOptions(" Pizza ", "Pasta"), CloseDate: &future}, nil},
+		{"empty question", core.PollMessage{Question: "  ", Options: pollOptions("a", "b")}, core.NewInvalidValueError("question")},
+		{"single option", core.PollMessage{Question: "?", Options: pollOptions("a")}, core.NewInvalidValueError("options")},
+		{"too many options", core.PollMessage{Question: "?", Options: pollOptions(manyOptions...)}, core.NewInvalidValueError("options")},
+		{"empty option", core.PollMessage{Question: "?", Options: pollOptions("a", " ")}, core.NewInvalidValueError("options")},
+		{"long option", core.PollMessage{Question: "?", Options: pollOptions("a", strings.Repeat("ü", maxPollOptionLength+1))}, core.NewInvalidValueError("options")},
+		{"closed in the past", core.PollMessage{Question: "?", Options: pollOptions("a", "b"), CloseDate: &past}, core.NewInvalidValueError("closeDate")},
+	}
+
+	for _, tt := range tests {
+		if err := preparePoll(&tt.poll); err != tt.expected {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.expected)
+		}
+	}
+
+	poll := core.PollMessage{Question: " Lunch? ", Options: pollOptions(" Pizza ", "Pasta"), IsClosed: true}
+	err := preparePoll(&poll)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if poll.Question != "Lunch?" || poll.IsClosed {
+		t.Errorf("unexpected poll %#v", poll)
+	}
+
+	for i, option := range poll.Options {
+		if option.ID != i+1 || option.Votes != 0 || len(option.Voters) != 0 || strings.TrimSpace(option.Text) != option.Text {
+			t.Errorf("option %d was not reset: %#v", i, option)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"unicode"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+const maxEmojiLength = 32
+
+func (s *service) ToggleReaction(
+	userCtx, conversationID int,
+	state bool,
+	message json.RawMessage,
+	pusher core.Pusher,
+	ctx context.Context) (int, error) {
+
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return 0, err
+	}
+
+	payload := struct {
+		MessageID int    `json:"messageId"`
+		Emoji     string `json:"emoji"`
+	}{}
+	err = json.Unmarshal(message, &payload)
+	if err != nil {
+		return 0, core.NewJSONFormatError(err.Error())
+	}
+
+	if !isValidEmoji(payload.Emoji) {
+		return 0, core.NewInvalidValueError("emoji")
+	}
+
+	_, err = s.messageRepo.FindMessageStubForConversation(conversationID, payload.MessageID)
+	if err != nil {
+		return 0, err
+	}
+
+	if state {
+		err = s.messageRepo.StoreReaction(payload.MessageID, userCtx, payload.Emoji)
+	} else {
+		err = s.messageRepo.DeleteReaction(payload.MessageID, userCtx, payload.Emoji)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	reactions, err := s.messageRepo.FindReactionsForMessage(payload.MessageID)
+	if err != nil {
+		return 0, err
+	}
+
+	reply := struct {
+		MessageID int                  `json:"messageId"`
+		Reactions []core.ReactionCount `json:"reactions"`
+	}{payload.MessageID, reactions}
+	pusher.BroadcastToRoom(conversationID, reply, ctx)
+
+	return payload.MessageID, nil
+}
+
+// isValidEmoji performs a rough plausibility check. It does not try to verify that
+// the passed string actually is a single emoji, as new emojis are added regulary.
+func isValidEmoji(emoji string) bool {
+	if emoji == "" || len(emoji) > maxEmojiLength {
+		return false
+	}
+
+	return strings.IndexFunc(emoji, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}) == -1
+}
@@ -0,0 +1,72 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+// ReadMessages marks the messages of a conversation as read. If the payload contains a messageId,
+// only the messages up to this one are marked. The other members are notified
+// with a read receipt if any message has been marked.
+func (s *service) ReadMessages(userID int, message json.RawMessage, pusher core.Pusher, ctx context.Context) error {
+	payload := struct {
+		ConversationID int `json:"conversationId"`
+		MessageID      int `json:"messageId"`
+	}{}
+	err := json.Unmarshal(message, &payload)
+	if err != nil {
+		return core.NewJSONFormatError(err.Error())
+	}
+
+	err = s.errorIFIsNotInConversation(userID, payload.ConversationID)
+	if err != nil {
+		return err
+	}
+
+	if payload.MessageID < 0 {
+		return core.NewInvalidValueError("messageId")
+	}
+
+	if payload.MessageID > 0 {
+		_, err = s.messageRepo.FindMessageStubForConversation(payload.ConversationID, payload.MessageID)
+		if err != nil {
+			return err
+		}
+	}
+
+	readDate := time.Now().UTC()
+	lastReadID, err := s.messageRepo.SetReadFlags(userID, payload.ConversationID, payload.MessageID, readDate)
+	if err != nil {
+		return err
+	}
+
+	if lastReadID == 0 {
+		return nil
+	}
+
+	pusher.BroadcastToRoom(payload.ConversationID, core.ReadReceipt{
+		UserID:         userID,
+		ConversationID: payload.ConversationID,
+		MessageID:      lastReadID,
+		ReadDate:       readDate,
+	}, ctx)
+	return nil
+}
+
+// ListReaders returns the members that have read the message.
+func (s *service) ListReaders(userCtx, conversationID, messageID int) ([]core.MessageReader, error) {
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.messageRepo.FindMessageStubForConversation(conversationID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.messageRepo.FindReadersOfMessage(messageID)
+}
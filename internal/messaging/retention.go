@@ -0,0 +1,84 @@
+package messaging
+
+import (
+	"context"
+	"time"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+const expiredMessagesBatchSize = 200
+
+// DeleteExpiredMessages deletes every message of a conversation that was sent before the passed date
+// including its media files and the replies to it. Connected members are notified of every removed message.
+func (s *service) DeleteExpiredMessages(
+	conversationID int,
+	sentBefore time.Time,
+	pathPrefix string,
+	pusher core.Pusher,
+	ctx context.Context) (int, error) {
+
+	nDeleted := 0
+	for {
+		messages, err := s.messageRepo.FindMessagesSentBefore(conversationID, sentBefore, expiredMessagesBatchSize)
+		if err != nil {
+			return nDeleted, err
+		}
+
+		if len(messages) == 0 {
+			return nDeleted, nil
+		}
+
+		ids := make([]int, len(messages))
+		isExpired := make(map[int]bool, len(messages))
+		for i, message := range messages {
+			ids[i] = message.ID
+			isExpired[message.ID] = true
+		}
+
+		// Replies are deleted together with their thread root, even if they have not expired yet.
+		replies, err := s.messageRepo.FindReplyStubs(ids)
+		if err != nil {
+			return nDeleted, err
+		}
+
+		deleted := make([]core.Message, len(messages), len(messages)+len(replies))
+		copy(deleted, messages)
+		repliesOfRoot := make(map[int][]int)
+		for _, reply := range replies {
+			if isExpired[reply.ID] {
+				continue
+			}
+			repliesOfRoot[reply.ParentID] = append(repliesOfRoot[reply.ParentID], reply.ID)
+			deleted = append(deleted, reply)
+		}
+
+		files, err := s.findMediaFiles(deleted)
+		if err != nil {
+			return nDeleted, err
+		}
+
+		err = s.messageRepo.DeleteMessages(ids)
+		if err != nil {
+			return nDeleted, err
+		}
+		nDeleted += len(deleted)
+
+		err = s.removeMediaFiles(pathPrefix, files)
+		if err != nil {
+			return nDeleted, err
+		}
+
+		for _, message := range messages {
+			pusher.BroadcastToRoom(conversationID, core.MessageTombstone{
+				MessageID: message.ID,
+				ParentID:  message.ParentID,
+				Replies:   repliesOfRoot[message.ID],
+			}, ctx)
+		}
+
+		if len(messages) < expiredMessagesBatchSize {
+			return nDeleted, nil
+		}
+	}
+}
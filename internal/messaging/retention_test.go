@@ -0,0 +1,90 @@
+package messaging
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+type fakeMessageRepo struct {
+	core.MessageRepo
+	messages []core.Message
+	deleted  []int
+}
+
+func (r *fakeMessageRepo) FindMessagesSentBefore(conversationID int, sentBefore time.Time, limit int) ([]core.Message, error) {
+	expired := make([]core.Message, 0)
+	for _, message := range r.messages {
+		if message.Sentdate.Before(sentBefore) && !containsInt(r.deleted, message.ID) && len(expired) < limit {
+			expired = append(expired, message)
+		}
+	}
+	return expired, nil
+}
+
+func (r *fakeMessageRepo) FindReplyStubs(parentIDs []int) ([]core.Message, error) {
+	replies := make([]core.Message, 0)
+	for _, message := range r.messages {
+		if containsInt(parentIDs, message.ParentID) && !containsInt(r.deleted, message.ID) {
+			replies = append(replies, message)
+		}
+	}
+	return replies, nil
+}
+
+func (r *fakeMessageRepo) DeleteMessages(ids []int) error {
+	for _, message := range r.messages {
+		if containsInt(ids, message.ID) || containsInt(ids, message.ParentID) {
+			r.deleted = append(r.deleted, message.ID)
+		}
+	}
+	return nil
+}
+
+func (r *fakeMessageRepo) FindUnreferencedFiles(fileIDs []int) ([]int, error) {
+	return fileIDs, nil
+}
+
+type fakePusher struct {
+	broadcasts []interface{}
+}
+
+func (p *fakePusher) BroadcastToRoom(roomNumber int, payload interface{}, ctx context.Context) {
+	p.broadcasts = append(p.broadcasts, payload)
+}
+
+func (p *fakePusher) Unicast(ctx context.Context, userID int, payload interface{}) {}
+
+func TestDeleteExpiredMessagesDeletesReplies(t *testing.T) {
+	now := time.Now()
+	old, recent := now.AddDate(0, 0, -10), now.AddDate(0, 0, -1)
+	repo := &fakeMessageRepo{messages: []core.Message{
+		{ID: 1, Sentdate: old},
+		{ID: 2, Sentdate: old, ParentID: 1},
+		{ID: 3, Sentdate: recent, ParentID: 1},
+		{ID: 4, Sentdate: recent},
+		{ID: 5, Sentdate: recent, ParentID: 4},
+	}}
+	pusher := &fakePusher{}
+	s := &service{messageRepo: repo}
+
+	nDeleted, err := s.DeleteExpiredMessages(1, now.AddDate(0, 0, -5), "", pusher, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if nDeleted != 3 || len(repo.deleted) != 3 {
+		t.Errorf("deleted %d messages %v, want 3", nDeleted, repo.deleted)
+	}
+
+	expected := []interface{}{
+		core.MessageTombstone{MessageID: 1, Replies: []int{3}},
+		core.MessageTombstone{MessageID: 2, ParentID: 1},
+	}
+	if !reflect.DeepEqual(pusher.broadcasts, expected) {
+		t.Errorf("unexpected tombstones %#v", pusher.broadcasts)
+	}
+}
@@ -0,0 +1,143 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+func (s *service) ListCodeRevisions(userCtx, conversationID, messageID int) ([]core.CodeRevision, error) {
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.messageRepo.FindCodeMessageForID(messageID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.messageRepo.FindRevisionsForCodeMessage(messageID)
+}
+
+// DiffCodeRevisions returns the changes between two revisions of a code message as unified diff.
+func (s *service) DiffCodeRevisions(userCtx, conversationID, messageID, from, to int) (string, error) {
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.messageRepo.FindCodeMessageForID(messageID, conversationID)
+	if err != nil {
+		return "", err
+	}
+
+	fromRevision, err := s.messageRepo.FindCodeRevision(messageID, from)
+	if err != nil {
+		return "", err
+	}
+
+	toRevision, err := s.messageRepo.FindCodeRevision(messageID, to)
+	if err != nil {
+		return "", err
+	}
+
+	// The main files are compared with each other, all further files by their names.
+	fromFiles, toFiles := revisionFiles(fromRevision), revisionFiles(toRevision)
+	pairs := [][2]core.CodeFile{{fromFiles[0], toFiles[0]}}
+	for _, f := range fromFiles[1:] {
+		pairs = append(pairs, [2]core.CodeFile{f, findCodeFile(toFiles[1:], f.Name)})
+	}
+	for _, f := range toFiles[1:] {
+		if findCodeFile(fromFiles[1:], f.Name).Name == "" {
+			pairs = append(pairs, [2]core.CodeFile{{}, f})
+		}
+	}
+
+	var b strings.Builder
+	for _, pair := range pairs {
+		fromName, toName := pair[0].Name, pair[1].Name
+		if fromName == "" {
+			fromName = toName
+		}
+		if toName == "" {
+			toName = fromName
+		}
+
+		b.WriteString(unifiedDiff(
+			fmt.Sprintf("a/%s\t(revision %d)", fromName, from),
+			fmt.Sprintf("b/%s\t(revision %d)", toName, to),
+			pair[0].Code,
+			pair[1].Code,
+		))
+	}
+	return b.String(), nil
+}
+
+func revisionFiles(revision core.CodeRevision) []core.CodeFile {
+	return CodeFiles(core.CodeMessage{
+		Code:     revision.Code,
+		Language: revision.Language,
+		Title:    revision.Title,
+		FileName: revision.FileName,
+		Files:    revision.Files,
+	})
+}
+
+// findCodeFile returns the file with the name or the zero value if there is none.
+func findCodeFile(files []core.CodeFile, name string) core.CodeFile {
+	for _, f := range files {
+		if f.Name == name {
+			return f
+		}
+	}
+	return core.CodeFile{}
+}
+
+// RestoreCodeRevision makes the state of an older revision the current one. The restored
+// state is stored as a new revision.
+func (s *service) RestoreCodeRevision(
+	userCtx, conversationID, messageID, revision int,
+	pusher core.Pusher,
+	ctx context.Context) (int, error) {
+
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return 0, err
+	}
+
+	codeMessage, err := s.messageRepo.FindCodeMessageForID(messageID, conversationID)
+	if err != nil {
+		return 0, err
+	}
+
+	if codeMessage.LockedBy > 0 && codeMessage.LockedBy != userCtx {
+		return 0, core.ErrAccessDenied
+	}
+
+	oldRevision, err := s.messageRepo.FindCodeRevision(messageID, revision)
+	if err != nil {
+		return 0, err
+	}
+
+	newRevision, err := s.messageRepo.UpdateCode(messageID, userCtx, core.CodeMessage{
+		Code:     oldRevision.Code,
+		Language: oldRevision.Language,
+		Title:    oldRevision.Title,
+		FileName: oldRevision.FileName,
+		Files:    oldRevision.Files,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	payload := struct {
+		MessageID int `json:"messageId"`
+		Revision  int `json:"revision"`
+	}{messageID, newRevision}
+	pusher.BroadcastToRoom(conversationID, payload, ctx)
+
+	return newRevision, nil
+}
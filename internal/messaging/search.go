@@ -0,0 +1,103 @@
+package messaging
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	snippetRadius      = 60
+)
+
+// Search looks up messages matching the query in all conversations the user is a member of.
+func (s *service) Search(userCtx int, query core.SearchQuery) ([]core.SearchResult, error) {
+	query.Term = strings.TrimSpace(query.Term)
+	if query.Term == "" {
+		return nil, core.NewInvalidValueError("q")
+	}
+
+	if query.ConversationID != 0 {
+		err := s.errorIFIsNotInConversation(userCtx, query.ConversationID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if query.Type < core.UndefinedMesssageType || query.Type > core.DiffMessageType {
+		return nil, core.ErrInvalidMessageType
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = defaultSearchLimit
+	} else if query.Limit > maxSearchLimit {
+		query.Limit = maxSearchLimit
+	}
+
+	if query.Offset < 0 {
+		return nil, core.NewInvalidValueError("offset")
+	}
+
+	if !query.From.IsZero() && !query.To.IsZero() && query.To.Before(query.From) {
+		return nil, core.NewInvalidValueError("to")
+	}
+
+	results, err := s.messageRepo.SearchMessages(userCtx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		results[i].Snippet = makeSnippet(results[i].Content, query.Term)
+	}
+	return results, nil
+}
+
+// makeSnippet cuts the part around the first occurence of one of the search words out of the content.
+func makeSnippet(content, term string) string {
+	lowerContent := strings.ToLower(content)
+	position := -1
+	for _, word := range strings.Fields(strings.ToLower(term)) {
+		word = strings.Trim(word, "\"-")
+		if word == "" || word == "or" {
+			continue
+		}
+
+		if p := strings.Index(lowerContent, word); p >= 0 && (position == -1 || p < position) {
+			position = p
+		}
+	}
+
+	// ToLower may change the byte length of some runes.
+	if position == -1 || len(lowerContent) != len(content) {
+		position = 0
+	}
+
+	start := position - snippetRadius
+	if start < 0 {
+		start = 0
+	}
+	end := position + snippetRadius
+	if end > len(content) {
+		end = len(content)
+	}
+
+	for start > 0 && !utf8.RuneStart(content[start]) {
+		start--
+	}
+	for end < len(content) && !utf8.RuneStart(content[end]) {
+		end++
+	}
+
+	snippet := strings.TrimSpace(content[start:end])
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(content) {
+		snippet += "…"
+	}
+	return snippet
+}
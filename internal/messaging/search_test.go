@@ -0,0 +1,43 @@
+package messaging
+
+import (
+	"testing"
+
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+type fakeSearchRepo struct {
+	core.MessageRepo
+	query core.SearchQuery
+}
+
+func (r *fakeSearchRepo) SearchMessages(userID int, query core.SearchQuery) ([]core.SearchResult, error) {
+	r.query = query
+	return []core.SearchResult{}, nil
+}
+
+func TestSearchMessageTypes(t *testing.T) {
+	tests := []struct {
+		messageType core.MessageType
+		expected    error
+	}{
+		{core.UndefinedMesssageType, nil},
+		{core.TextMessageType, nil},
+		{core.PollMessageType, nil},
+		{core.DiffMessageType, nil},
+		{core.DiffMessageType + 1, core.ErrInvalidMessageType},
+	}
+
+	for _, tt := range tests {
+		repo := &fakeSearchRepo{}
+		s := &service{messageRepo: repo}
+		_, err := s.Search(1, core.SearchQuery{Term: "lunch", Type: tt.messageType})
+		if err != tt.expected {
+			t.Errorf("type %d: got %v, want %v", tt.messageType, err, tt.expected)
+		}
+
+		if err == nil && repo.query.Type != tt.messageType {
+			t.Errorf("type %d: searched for type %d", tt.messageType, repo.query.Type)
+		}
+	}
+}
@@ -7,25 +7,64 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	core "github.com/miphilipp/devchat-server/internal"
 )
 
 type Service interface {
-	ListAllMessages(userID, conversationID, beforeInSequence, limit int, mType core.MessageType) ([]interface{}, error)
+	ListAllMessages(userID, conversationID int, query core.HistoryQuery) (core.HistoryPage, error)
 	ListProgrammingLanguages() ([]core.ProgrammingLanguage, error)
+	DetectLanguage(fileName, code string) (core.LanguageGuess, error)
 	GetMediaObject(userCtx, conversationID int, fileName, pathPrefix string) (core.MediaObject, *os.File, error)
 	GetMessage(userCtx, conversationID, messageID int) (interface{}, error)
 	GetCodeOfMessage(userCtx, conversationID, messageID int) (string, error)
+	GetCodeFile(userCtx, conversationID, messageID, index int) (core.CodeFile, error)
+	GetCodeArchive(userCtx, conversationID, messageID int) ([]byte, string, error)
+	GetPatchOfMessage(userCtx, conversationID, messageID int) (string, string, error)
+	GetDiffOfMessage(userCtx, conversationID, messageID int) ([]core.DiffFile, error)
+	ListThread(userCtx, conversationID, messageID int) (core.Thread, error)
+	GetThreadSummary(userCtx, conversationID, messageID int) (core.ThreadSummary, error)
+	ListEditHistory(userCtx, conversationID, messageID int) ([]core.MessageEdit, error)
+	ListReaders(userCtx, conversationID, messageID int) ([]core.MessageReader, error)
+	Search(userCtx int, query core.SearchQuery) ([]core.SearchResult, error)
+	ListMentions(userCtx, beforeInSequence, limit int) ([]core.Mention, error)
+	ListPins(userCtx, conversationID int) ([]core.Pin, error)
+	ListCodeRevisions(userCtx, conversationID, messageID int) ([]core.CodeRevision, error)
+	DiffCodeRevisions(userCtx, conversationID, messageID, from, to int) (string, error)
 	BroadcastUserIsTyping(userCtx, conversationID int, pusher core.Pusher, ctx context.Context) error
+	ListDrafts(userCtx int) ([]core.Draft, error)
+	GetDraft(userCtx, conversationID int) (core.Draft, error)
+	ListBookmarks(userCtx, beforeID, limit int, tag string) ([]core.Bookmark, error)
 
 	// Mutations
 	SendMessage(target, userID int, message json.RawMessage, pusher core.Pusher, ctx context.Context) (interface{}, error)
-	ReadMessages(userID int, message json.RawMessage) error
+	ReadMessages(userID int, message json.RawMessage, pusher core.Pusher, ctx context.Context) error
 	EditMessage(userCtx, conversationID int, message json.RawMessage, pusher core.Pusher, ctx context.Context) (int, error)
 	LiveEditMessage(userCtx, conversationID int, message json.RawMessage, pusher core.Pusher, ctx context.Context) (int, error)
 	ToggleLiveSession(userCtx, conversationID int, state bool, message json.RawMessage, pusher core.Pusher, ctx context.Context) (int, error)
 	CompleteMessage(id int, err error) error
+	DeleteMessage(userCtx, conversationID, messageID int, pathPrefix string, pusher core.Pusher, ctx context.Context) (core.MessageTombstone, error)
+	ToggleReaction(userCtx, conversationID int, state bool, message json.RawMessage, pusher core.Pusher, ctx context.Context) (int, error)
+	TogglePollVote(userCtx, conversationID int, state bool, message json.RawMessage, pusher core.Pusher, ctx context.Context) (int, error)
+	TogglePin(userCtx, conversationID, messageID int, state bool, pusher core.Pusher, ctx context.Context) error
+	RestoreCodeRevision(userCtx, conversationID, messageID, revision int, pusher core.Pusher, ctx context.Context) (int, error)
+	ForceReleaseLock(userCtx, conversationID, messageID int, pusher core.Pusher, ctx context.Context) error
+	RequestLockHandOver(userCtx, conversationID int, message json.RawMessage, pusher core.Pusher, ctx context.Context) (int, error)
+	AnswerLockHandOver(userCtx, conversationID int, message json.RawMessage, pusher core.Pusher, ctx context.Context) (int, error)
+	ForwardMessage(userCtx, conversationID, messageID, targetConversationID int, pusher core.Pusher, ctx context.Context) (interface{}, error)
+	SaveDraft(userCtx int, draft core.Draft, pusher core.Pusher, ctx context.Context) (core.Draft, error)
+	ClearDraft(userCtx, conversationID int, pusher core.Pusher, ctx context.Context) error
+	SaveBookmark(userCtx int, bookmark core.Bookmark) (core.Bookmark, error)
+	DeleteBookmark(userCtx, conversationID, messageID int) error
+
+	// Internal
+	ReleaseLocksOfUser(userID int, pusher core.Pusher, ctx context.Context) error
+	ReleaseIdleLocks(idleTimeout time.Duration, pusher core.Pusher, ctx context.Context) error
+	DeleteExpiredMessages(conversationID int, sentBefore time.Time, pathPrefix string, pusher core.Pusher, ctx context.Context) (int, error)
+
+	// ValidateMessage checks a message that is sent later on behalf of the user.
+	ValidateMessage(target int, message json.RawMessage) error
 
 	// AddFileToMessage adds a media object to a media message.
 	AddFileToMessage(userCtx, conversationID, messageID int, fileBuffer []byte, pathPrefix, fileName, fileType string) error
@@ -34,17 +73,23 @@ type Service interface {
 type service struct {
 	messageRepo      core.MessageRepo
 	conversationRepo core.ConversationRepo
+	documents        liveDocuments
+	handOvers        handOvers
 }
 
 type messageStub struct {
-	Type int `json:"type"`
-	ID   int `json:"id"`
+	Type     int `json:"type"`
+	ID       int `json:"id"`
+	ParentID int `json:"parentId"`
+	QuotedID int `json:"quotedId"`
 }
 
 func NewService(messageRepo core.MessageRepo, conversationRepo core.ConversationRepo) Service {
 	return &service{
 		messageRepo:      messageRepo,
 		conversationRepo: conversationRepo,
+		documents:        liveDocuments{m: make(map[int]*liveDocument)},
+		handOvers:        handOvers{m: make(map[int]handOver)},
 	}
 }
 
@@ -61,40 +106,21 @@ func (s *service) BroadcastUserIsTyping(userCtx, conversationID int, pusher core
 	return nil
 }
 
-func (s *service) ListAllMessages(
-	userID int,
-	conversationID int,
-	beforeInSequence int,
-	limit int,
-	mType core.MessageType) ([]interface{}, error) {
-	err := s.errorIFIsNotInConversation(userID, conversationID)
-	if err != nil {
-		return nil, err
-	}
-
-	switch mType {
-	case core.CodeMessageType:
-		return s.messageRepo.FindCodeMessagesForConversation(conversationID, beforeInSequence, limit)
-	case core.TextMessageType:
-		return s.messageRepo.FindTextMessagesForConversation(conversationID, beforeInSequence, limit)
-	case core.MediaMessageType:
-		return s.messageRepo.FindMediaMessagesForConversation(conversationID, beforeInSequence, limit)
-	case core.UndefinedMesssageType:
-		return s.messageRepo.FindForConversation(conversationID, beforeInSequence, limit)
-	default:
-		return nil, core.ErrInvalidMessageType
-	}
-}
-
 func (s *service) ListProgrammingLanguages() ([]core.ProgrammingLanguage, error) {
 	return s.messageRepo.FindAllProgrammingLanguages()
 }
 
+// patchData changes one file of a code message. File is the index of the file within
+// CodeFiles, the index after the last file adds a new file. Name renames the file and
+// Delete removes it, the main file cannot be removed.
 type patchData struct {
 	MessageID int    `json:"messageId"`
 	Patch     string `json:"patch"`
 	Title     string `json:"title"`
 	Language  string `json:"language"`
+	File      int    `json:"file"`
+	Name      string `json:"name"`
+	Delete    bool   `json:"delete"`
 }
 
 func (s *service) GetCodeOfMessage(userCtx, conversationID, messageID int) (string, error) {
@@ -117,6 +143,10 @@ func (s *service) GetMessage(userCtx, conversationID, messageID int) (interface{
 		return nil, err
 	}
 
+	return s.findMessage(conversationID, messageID)
+}
+
+func (s *service) findMessage(conversationID, messageID int) (interface{}, error) {
 	messageFromDB, err := s.messageRepo.FindMessageStubForConversation(conversationID, messageID)
 	if err != nil {
 		return 0, err
@@ -129,11 +159,29 @@ func (s *service) GetMessage(userCtx, conversationID, messageID int) (interface{
 		return s.messageRepo.FindTextMessageForID(messageID, conversationID)
 	case core.MediaMessageType:
 		return s.messageRepo.FindMediaMessageForID(messageID, conversationID)
+	case core.PollMessageType:
+		return s.messageRepo.FindPollMessageForID(messageID, conversationID)
+	case core.DiffMessageType:
+		return s.messageRepo.FindDiffMessageForID(messageID, conversationID)
 	default:
 		return nil, core.ErrMessageTypeNotImplemented
 	}
 }
 
+func (s *service) ListEditHistory(userCtx, conversationID, messageID int) ([]core.MessageEdit, error) {
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.messageRepo.FindMessageStubForConversation(conversationID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.messageRepo.FindEditHistory(messageID)
+}
+
 func (s *service) errorIFIsNotInConversation(userCtx, conversationID int) error {
 	isMember, err := s.conversationRepo.IsUserInConversation(userCtx, conversationID)
 	if err != nil {
@@ -167,7 +215,8 @@ func (s *service) GetMediaObject(userCtx, conversationID int, fileName, pathPref
 		return core.MediaObject{}, nil, err
 	}
 
-	file, err := os.Open(path.Join(pathPrefix, fileName))
+	// Forwarded media objects share the file of the original media object.
+	file, err := os.Open(path.Join(pathPrefix, strconv.Itoa(obj.FileID)+"-"+components[1]))
 	if err != nil {
 		return core.MediaObject{}, nil, err
 	}
@@ -0,0 +1,73 @@
+package messaging
+
+import (
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+func (s *service) ListThread(userCtx, conversationID, messageID int) (core.Thread, error) {
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return core.Thread{}, err
+	}
+
+	rootID, err := s.resolveThreadRoot(conversationID, messageID)
+	if err != nil {
+		return core.Thread{}, err
+	}
+
+	root, err := s.findMessage(conversationID, rootID)
+	if err != nil {
+		return core.Thread{}, err
+	}
+
+	replies, err := s.messageRepo.FindRepliesForMessage(conversationID, rootID)
+	if err != nil {
+		return core.Thread{}, err
+	}
+
+	return core.Thread{
+		Root:    root,
+		Replies: replies,
+	}, nil
+}
+
+func (s *service) GetThreadSummary(userCtx, conversationID, messageID int) (core.ThreadSummary, error) {
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return core.ThreadSummary{}, err
+	}
+
+	rootID, err := s.resolveThreadRoot(conversationID, messageID)
+	if err != nil {
+		return core.ThreadSummary{}, err
+	}
+
+	count, err := s.messageRepo.CountReplies(rootID)
+	if err != nil {
+		return core.ThreadSummary{}, err
+	}
+
+	return core.ThreadSummary{
+		MessageID:  rootID,
+		ReplyCount: count,
+	}, nil
+}
+
+// resolveThreadRoot returns the id of the root message of the thread the passed message
+// belongs to. Replies to replies are therefore attached to the root of the thread.
+// If messageID is 0, 0 is returned.
+func (s *service) resolveThreadRoot(conversationID, messageID int) (int, error) {
+	if messageID == 0 {
+		return 0, nil
+	}
+
+	message, err := s.messageRepo.FindMessageStubForConversation(conversationID, messageID)
+	if err != nil {
+		return 0, err
+	}
+
+	if message.ParentID != 0 {
+		return message.ParentID, nil
+	}
+	return message.ID, nil
+}
@@ -16,6 +16,7 @@ type ConversationRepo interface {
 	MarkAsJoined(userID, conversationID int) (int, error)
 	RemoveGroupAssociation(userID, conversationID int) error
 	SetMetaDataOfConversation(conversation Conversation) error
+	SetRetentionPeriod(conversationID int, days *int) error
 	SetAsLeft(userID, conversationID int) error
 	SetAdminState(userID, conversationID int, state bool) error
 
@@ -49,6 +50,7 @@ type UserRepo interface {
 	CompareCredentials(userID int, password string) (int, error)
 	GetUserForID(userID int) (User, error)
 	GetUserForName(name string) (User, error)
+	GetUserForEmail(email string) (User, error)
 	GetUsersForPrefix(prefix string, limit int) ([]User, error)
 	SelectRecoveryTokenIssueDate(recoveryUUID uuid.UUID) (time.Time, error)
 
@@ -63,23 +65,91 @@ type MessageRepo interface {
 	StoreTextMessage(conversation, user int, m TextMessage) (int, error)
 	StoreCodeMessage(conversation, user int, m CodeMessage) (int, error)
 	StoreMediaMessage(conversation, user int, m MediaMessage) (int, error)
-	SetReadFlags(userid, conversationID int) error
-	UpdateCode(messageID int, newCode, title, language string) error
+	StorePollMessage(conversation, user int, m PollMessage) (int, error)
+	StoreDiffMessage(conversation, user int, m DiffMessage) (int, error)
+	StorePollVote(messageID, optionID, userID int, exclusive bool) error
+	DeletePollVote(messageID, optionID, userID int) error
+	SetReadFlags(userID, conversationID, upToMessageID int, readDate time.Time) (int, error)
+	UpdateCode(messageID, userID int, m CodeMessage) (int, error)
+	FindRevisionsForCodeMessage(messageID int) ([]CodeRevision, error)
+	FindCodeRevision(messageID, revision int) (CodeRevision, error)
+	StoreExecutionResult(result ExecutionResult) error
+	FindExecutionResult(messageID int) (ExecutionResult, error)
 	SetLockedSateForCodeMessage(messageID int, lockingUserID int) error
+	ReleaseLock(messageID, holderID int) error
+	TransferLock(messageID, holderID, newHolderID int) error
+	FindLocks(userID int, idleSince time.Time) ([]CodeLock, error)
 	CreateMediaObject(messageID int, name, fileType string) (int, error)
+	CopyMediaObject(messageID int, obj MediaObject) (int, error)
 	SetMetaOfMediaMessage(id int, meta interface{}) error
 	DeleteMessage(id int) error
+	DeleteMessages(ids []int) error
 	UpdateCompleteFlag(id int) error
+	StoreReaction(messageID, userID int, emoji string) error
+	DeleteReaction(messageID, userID int, emoji string) error
+	UpdateMessageText(messageID, userID int, text, html, plainText string) error
+	StoreDraft(userID int, draft Draft) error
+	DeleteDraft(userID, conversationID int) error
+	StoreBookmark(userID int, bookmark Bookmark) error
+	DeleteBookmark(userID, messageID int) error
 
 	// Queries
-	FindForConversation(conversationID, beforeInSequence, limit int) ([]interface{}, error)
-	FindCodeMessagesForConversation(conversationID, beforeInSequence, limit int) ([]interface{}, error)
-	FindTextMessagesForConversation(conversationID, beforeInSequence, limit int) ([]interface{}, error)
-	FindMediaMessagesForConversation(conversationID, beforeInSequence, limit int) ([]interface{}, error)
+	// FindForConversation also returns the number of messages within the range up to its limit.
+	// It can be larger than the number of returned messages, as media messages without files are left out.
+	FindForConversation(conversationID int, window MessageRange) ([]interface{}, int, error)
+	FindCodeMessagesForConversation(conversationID int, window MessageRange) ([]interface{}, error)
+	FindTextMessagesForConversation(conversationID int, window MessageRange) ([]interface{}, error)
+	FindMediaMessagesForConversation(conversationID int, window MessageRange) ([]interface{}, error)
+	FindPollMessagesForConversation(conversationID int, window MessageRange) ([]interface{}, error)
+	FindDiffMessagesForConversation(conversationID int, window MessageRange) ([]interface{}, error)
+	HasMessagesInRange(conversationID int, window MessageRange, mType MessageType) (bool, error)
 	FindCodeMessageForID(messageID, conversationID int) (CodeMessage, error)
 	FindTextMessageForID(messageID, conversationID int) (TextMessage, error)
 	FindMediaMessageForID(messageID, conversationID int) (MediaMessage, error)
+	FindPollMessageForID(messageID, conversationID int) (PollMessage, error)
+	FindDiffMessageForID(messageID, conversationID int) (DiffMessage, error)
+	FindPatchOfDiffMessage(messageID, conversationID int) (string, error)
 	FindMessageStubForConversation(conversationID, messageID int) (Message, error)
+	FindMessagesSentBefore(conversationID int, sentBefore time.Time, limit int) ([]Message, error)
+	FindReplyStubs(parentIDs []int) ([]Message, error)
 	FindAllProgrammingLanguages() ([]ProgrammingLanguage, error)
 	FindMediaObjectForID(id, conversationID int) (MediaObject, error)
+	FindRepliesForMessage(conversationID, messageID int) ([]interface{}, error)
+	FindMessagesAfter(conversationID, afterID, limit int) ([]interface{}, error)
+	CountReplies(messageID int) (int, error)
+	FindReactionsForMessage(messageID int) ([]ReactionCount, error)
+	FindReadersOfMessage(messageID int) ([]MessageReader, error)
+	FindEditHistory(messageID int) ([]MessageEdit, error)
+	FindMediaObjectsForMessage(messageID int) ([]MediaObject, error)
+	FindUnreferencedFiles(fileIDs []int) ([]int, error)
+	FindMessagePreview(conversationID, messageID int) (MessagePreview, error)
+	SearchMessages(userID int, query SearchQuery) ([]SearchResult, error)
+	StoreMentions(messageID int, userIDs []int) error
+	StorePin(conversationID, messageID, userID int) error
+	DeletePin(conversationID, messageID int) error
+	FindPinsForConversation(conversationID int) ([]Pin, error)
+	FindMentionsForUser(userID, beforeInSequence, limit int) ([]Mention, error)
+	FindDraft(userID, conversationID int) (Draft, error)
+	FindDraftsForUser(userID int) ([]Draft, error)
+	FindBookmark(userID, messageID int) (Bookmark, error)
+	FindBookmarksForUser(userID, beforeID, limit int, tag string) ([]Bookmark, error)
+}
+
+// SchedulingRepo contains all queries and mutations to work with scheduled messages and reminders.
+type SchedulingRepo interface {
+
+	// Mutations
+	StoreScheduledItem(item ScheduledItem) (int, error)
+	UpdateScheduledItem(item ScheduledItem) error
+	DeleteScheduledItem(userID, itemID int) error
+	ClaimDueItems(dueBefore, claimedBefore time.Time, limit int) ([]ScheduledItem, error)
+	CompleteScheduledItem(itemID int) error
+	ReleaseScheduledItem(itemID int, dueDate time.Time) error
+	DeferScheduledItem(itemID int) error
+	ClaimDeferredItems(userID int, claimDate time.Time) ([]ScheduledItem, error)
+
+	// Queries
+	FindScheduledItem(userID, itemID int) (ScheduledItem, error)
+	FindScheduledItemsForUser(userID int) ([]ScheduledItem, error)
+	FindNextDueDate(claimTimeout time.Duration) (time.Time, error)
 }
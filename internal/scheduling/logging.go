@@ -0,0 +1,189 @@
+package scheduling
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	core "github.com/miphilipp/devchat-server/internal"
+)
+
+type loggingService struct {
+	logger  log.Logger
+	next    Service
+	verbose bool
+}
+
+func NewLoggingService(logger log.Logger, s Service, verbose bool) Service {
+	return &loggingService{logger, s, verbose}
+}
+
+func (s *loggingService) ListScheduledItems(userCtx int) (items []core.ScheduledItem, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "ListScheduledItems",
+				"userCtx", userCtx,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.ListScheduledItems(userCtx)
+}
+
+func (s *loggingService) ScheduleMessage(
+	userCtx, conversationID int,
+	dueDate time.Time,
+	message json.RawMessage) (item core.ScheduledItem, err error) {
+
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "ScheduleMessage",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"dueDate", dueDate,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.ScheduleMessage(userCtx, conversationID, dueDate, message)
+}
+
+func (s *loggingService) CreateReminder(
+	userCtx, conversationID, messageID int,
+	dueDate time.Time,
+	note string) (item core.ScheduledItem, err error) {
+
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "CreateReminder",
+				"userCtx", userCtx,
+				"conversationID", conversationID,
+				"messageID", messageID,
+				"dueDate", dueDate,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.CreateReminder(userCtx, conversationID, messageID, dueDate, note)
+}
+
+func (s *loggingService) EditScheduledItem(
+	userCtx, itemID int,
+	dueDate *time.Time,
+	message json.RawMessage,
+	note *string) (item core.ScheduledItem, err error) {
+
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "EditScheduledItem",
+				"userCtx", userCtx,
+				"itemID", itemID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.EditScheduledItem(userCtx, itemID, dueDate, message, note)
+}
+
+func (s *loggingService) CancelScheduledItem(userCtx, itemID int) (err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "CancelScheduledItem",
+				"userCtx", userCtx,
+				"itemID", itemID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.CancelScheduledItem(userCtx, itemID)
+}
+
+func (s *loggingService) ClaimDueItems() (items []core.ScheduledItem, err error) {
+	defer func(begin time.Time) {
+		if err != nil || (s.verbose && len(items) > 0) {
+			s.logger.Log(
+				"Use-Case", "ClaimDueItems",
+				"nItems", len(items),
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.ClaimDueItems()
+}
+
+func (s *loggingService) CompleteItem(itemID int) (err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "CompleteItem",
+				"itemID", itemID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.CompleteItem(itemID)
+}
+
+func (s *loggingService) DeferItem(itemID int) (err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "DeferItem",
+				"itemID", itemID,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.DeferItem(itemID)
+}
+
+func (s *loggingService) ClaimDeferredItems(userID int) (items []core.ScheduledItem, err error) {
+	defer func(begin time.Time) {
+		if err != nil || (s.verbose && len(items) > 0) {
+			s.logger.Log(
+				"Use-Case", "ClaimDeferredItems",
+				"userID", userID,
+				"nItems", len(items),
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.ClaimDeferredItems(userID)
+}
+
+func (s *loggingService) RetryItem(item core.ScheduledItem, dispatchErr error) (retried bool, err error) {
+	defer func(begin time.Time) {
+		if err != nil || s.verbose {
+			s.logger.Log(
+				"Use-Case", "RetryItem",
+				"itemID", item.ID,
+				"attempts", item.Attempts,
+				"dispatchErr", dispatchErr,
+				"retried", retried,
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.RetryItem(item, dispatchErr)
+}
+
+func (s *loggingService) NextDueDate() (next time.Time, err error) {
+	defer func(begin time.Time) {
+		if err != nil {
+			s.logger.Log(
+				"Use-Case", "NextDueDate",
+				"took", time.Since(begin),
+				"err", err)
+		}
+	}(time.Now())
+	return s.next.NextDueDate()
+}
+
+func (s *loggingService) Changes() <-chan struct{} {
+	return s.next.Changes()
+}
@@ -0,0 +1,371 @@
+package scheduling
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	core "github.com/miphilipp/devchat-server/internal"
+	"github.com/miphilipp/devchat-server/internal/messaging"
+)
+
+const (
+	maxNoteLength      = 500
+	maxClaimedItems    = 50
+	maxPendingPerUser  = 100
+	minimumLeadingTime = 5 * time.Second
+
+	// An item that is neither completed nor released within claimTimeout is handed
+	// out again, e.g. because the server crashed while dispatching it.
+	claimTimeout = 5 * time.Minute
+
+	maxDispatchAttempts = 5
+	retryDelay          = time.Minute
+)
+
+// Service defines all use cases related to scheduled messages and reminders.
+type Service interface {
+	ListScheduledItems(userCtx int) ([]core.ScheduledItem, error)
+	ScheduleMessage(userCtx, conversationID int, dueDate time.Time, message json.RawMessage) (core.ScheduledItem, error)
+	CreateReminder(userCtx, conversationID, messageID int, dueDate time.Time, note string) (core.ScheduledItem, error)
+	EditScheduledItem(userCtx, itemID int, dueDate *time.Time, message json.RawMessage, note *string) (core.ScheduledItem, error)
+	CancelScheduledItem(userCtx, itemID int) error
+
+	// Internal
+
+	// ClaimDueItems returns all items that are due. Each item is returned only once,
+	// unless it is neither completed nor retried within the claim timeout.
+	ClaimDueItems() ([]core.ScheduledItem, error)
+
+	// CompleteItem removes an item after it has been dispatched.
+	CompleteItem(itemID int) error
+
+	// DeferItem keeps an item that could not be delivered, because its owner is offline.
+	// It is handed out again by ClaimDeferredItems.
+	DeferItem(itemID int) error
+
+	// ClaimDeferredItems returns the deferred items of a user who has come online.
+	ClaimDeferredItems(userID int) ([]core.ScheduledItem, error)
+
+	// RetryItem reschedules an item whose dispatch failed with err. Items are only
+	// retried after temporary errors and up to a maximum number of attempts.
+	// Returns false if the item has been dropped instead.
+	RetryItem(item core.ScheduledItem, err error) (bool, error)
+
+	// NextDueDate returns the due date of the next pending item or the zero time.
+	NextDueDate() (time.Time, error)
+
+	// Changes signals that an item has been scheduled or rescheduled.
+	Changes() <-chan struct{}
+}
+
+type service struct {
+	schedulingRepo   core.SchedulingRepo
+	messageRepo      core.MessageRepo
+	conversationRepo core.ConversationRepo
+	messagingService messaging.Service
+	changes          chan struct{}
+}
+
+// NewService creates and returns new Service
+func NewService(
+	schedulingRepo core.SchedulingRepo,
+	messageRepo core.MessageRepo,
+	conversationRepo core.ConversationRepo,
+	messagingService messaging.Service) Service {
+
+	return &service{
+		schedulingRepo:   schedulingRepo,
+		messageRepo:      messageRepo,
+		conversationRepo: conversationRepo,
+		messagingService: messagingService,
+		changes:          make(chan struct{}, 1),
+	}
+}
+
+func (s *service) ListScheduledItems(userCtx int) ([]core.ScheduledItem, error) {
+	return s.schedulingRepo.FindScheduledItemsForUser(userCtx)
+}
+
+func (s *service) ScheduleMessage(
+	userCtx, conversationID int,
+	dueDate time.Time,
+	message json.RawMessage) (core.ScheduledItem, error) {
+
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return core.ScheduledItem{}, err
+	}
+
+	err = validateDueDate(dueDate)
+	if err != nil {
+		return core.ScheduledItem{}, err
+	}
+
+	err = s.validateMessage(conversationID, message)
+	if err != nil {
+		return core.ScheduledItem{}, err
+	}
+
+	item := core.ScheduledItem{
+		Kind:           core.ScheduledMessage,
+		UserID:         userCtx,
+		ConversationID: conversationID,
+		DueDate:        dueDate.UTC(),
+		Message:        message,
+	}
+	return s.store(item)
+}
+
+func (s *service) CreateReminder(
+	userCtx, conversationID, messageID int,
+	dueDate time.Time,
+	note string) (core.ScheduledItem, error) {
+
+	err := s.errorIFIsNotInConversation(userCtx, conversationID)
+	if err != nil {
+		return core.ScheduledItem{}, err
+	}
+
+	_, err = s.messageRepo.FindMessageStubForConversation(conversationID, messageID)
+	if err != nil {
+		return core.ScheduledItem{}, err
+	}
+
+	err = validateDueDate(dueDate)
+	if err != nil {
+		return core.ScheduledItem{}, err
+	}
+
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > maxNoteLength {
+		return core.ScheduledItem{}, core.NewInvalidValueError("note")
+	}
+
+	item := core.ScheduledItem{
+		Kind:           core.ScheduledReminder,
+		UserID:         userCtx,
+		ConversationID: conversationID,
+		MessageID:      messageID,
+		DueDate:        dueDate.UTC(),
+		Note:           note,
+	}
+	return s.store(item)
+}
+
+func (s *service) store(item core.ScheduledItem) (core.ScheduledItem, error) {
+	pending, err := s.schedulingRepo.FindScheduledItemsForUser(item.UserID)
+	if err != nil {
+		return core.ScheduledItem{}, err
+	}
+
+	if len(pending) >= maxPendingPerUser {
+		return core.ScheduledItem{}, core.ErrRequestLimitExceeded
+	}
+
+	item.ID, err = s.schedulingRepo.StoreScheduledItem(item)
+	if err != nil {
+		return core.ScheduledItem{}, err
+	}
+
+	item.CreationDate = time.Now().UTC()
+	s.notifyChange()
+	return item, nil
+}
+
+// EditScheduledItem changes the pending item. Fields passed as nil are left untouched.
+func (s *service) EditScheduledItem(
+	userCtx, itemID int,
+	dueDate *time.Time,
+	message json.RawMessage,
+	note *string) (core.ScheduledItem, error) {
+
+	item, err := s.schedulingRepo.FindScheduledItem(userCtx, itemID)
+	if err != nil {
+		return core.ScheduledItem{}, err
+	}
+
+	if dueDate != nil {
+		err = validateDueDate(*dueDate)
+		if err != nil {
+			return core.ScheduledItem{}, err
+		}
+		item.DueDate = dueDate.UTC()
+	}
+
+	if message != nil {
+		if item.Kind != core.ScheduledMessage {
+			return core.ScheduledItem{}, core.NewInvalidValueError("message")
+		}
+
+		err = s.validateMessage(item.ConversationID, message)
+		if err != nil {
+			return core.ScheduledItem{}, err
+		}
+		item.Message = message
+	}
+
+	if note != nil {
+		trimmedNote := strings.TrimSpace(*note)
+		if item.Kind != core.ScheduledReminder || utf8.RuneCountInString(trimmedNote) > maxNoteLength {
+			return core.ScheduledItem{}, core.NewInvalidValueError("note")
+		}
+		item.Note = trimmedNote
+	}
+
+	err = s.schedulingRepo.UpdateScheduledItem(item)
+	if err != nil {
+		return core.ScheduledItem{}, err
+	}
+
+	s.notifyChange()
+	return item, nil
+}
+
+func (s *service) CancelScheduledItem(userCtx, itemID int) error {
+	return s.schedulingRepo.DeleteScheduledItem(userCtx, itemID)
+}
+
+func (s *service) ClaimDueItems() ([]core.ScheduledItem, error) {
+	now := time.Now().UTC()
+	items, err := s.schedulingRepo.ClaimDueItems(now, now.Add(-claimTimeout), maxClaimedItems)
+	if err != nil {
+		return nil, err
+	}
+
+	// Scheduled messages are sent with the time they are actually sent at.
+	for i := range items {
+		if items[i].Kind != core.ScheduledMessage {
+			continue
+		}
+
+		items[i].Message, err = withSentDate(items[i].Message, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return items, nil
+}
+
+func (s *service) CompleteItem(itemID int) error {
+	return s.schedulingRepo.CompleteScheduledItem(itemID)
+}
+
+func (s *service) DeferItem(itemID int) error {
+	return s.schedulingRepo.DeferScheduledItem(itemID)
+}
+
+func (s *service) ClaimDeferredItems(userID int) ([]core.ScheduledItem, error) {
+	return s.schedulingRepo.ClaimDeferredItems(userID, time.Now().UTC())
+}
+
+func (s *service) RetryItem(item core.ScheduledItem, err error) (bool, error) {
+	if !isTemporary(err) || item.Attempts >= maxDispatchAttempts {
+		return false, s.schedulingRepo.CompleteScheduledItem(item.ID)
+	}
+
+	dueDate := time.Now().Add(time.Duration(item.Attempts) * retryDelay)
+	err = s.schedulingRepo.ReleaseScheduledItem(item.ID, dueDate)
+	if err != nil {
+		return false, err
+	}
+
+	s.notifyChange()
+	return true, nil
+}
+
+func (s *service) NextDueDate() (time.Time, error) {
+	return s.schedulingRepo.FindNextDueDate(claimTimeout)
+}
+
+func (s *service) Changes() <-chan struct{} {
+	return s.changes
+}
+
+func (s *service) notifyChange() {
+	select {
+	case s.changes <- struct{}{}:
+	default:
+	}
+}
+
+func (s *service) errorIFIsNotInConversation(userCtx, conversationID int) error {
+	isMember, err := s.conversationRepo.IsUserInConversation(userCtx, conversationID)
+	if err != nil {
+		return err
+	}
+
+	if !isMember {
+		return core.ErrAccessDenied
+	}
+	return nil
+}
+
+func validateDueDate(dueDate time.Time) error {
+	if dueDate.Before(time.Now().Add(minimumLeadingTime)) {
+		return core.NewInvalidValueError("dueDate")
+	}
+	return nil
+}
+
+// validateMessage makes sure that the message can be sent without further interaction.
+// Besides the checks done when sending, it requires the type to be set explicitly.
+func (s *service) validateMessage(conversationID int, message json.RawMessage) error {
+	err := validateMessageType(message)
+	if err != nil {
+		return err
+	}
+	return s.messagingService.ValidateMessage(conversationID, message)
+}
+
+// validateMessageType rejects media messages, as their files are uploaded after the
+// message has been sent.
+func validateMessageType(message json.RawMessage) error {
+	var stub struct {
+		Type *core.MessageType `json:"type"`
+	}
+	err := json.Unmarshal(message, &stub)
+	if err != nil {
+		return core.NewJSONFormatError(err.Error())
+	}
+
+	if stub.Type == nil {
+		return core.NewJSONFormatError("type missing")
+	}
+
+	switch *stub.Type {
+	case core.TextMessageType, core.CodeMessageType, core.PollMessageType, core.DiffMessageType:
+		return nil
+	default:
+		return core.ErrInvalidMessageType
+	}
+}
+
+// isTemporary reports whether err might not occur again, e.g. a lost database connection.
+// Errors caused by the message itself or the rights of its author are final.
+func isTemporary(err error) bool {
+	if errors.Is(err, core.ErrDataBase) {
+		return true
+	}
+
+	_, isAPIError := err.(core.ApiError)
+	return !isAPIError
+}
+
+func withSentDate(message json.RawMessage, sentDate time.Time) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(message, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	fields["sentdate"], err = json.Marshal(sentDate)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(fields)
+}
@@ -0,0 +1,103 @@
+package scheduling
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	core "github.com/miphilipp/devchat-server/internal"
+	"github.com/miphilipp/devchat-server/internal/messaging"
+)
+
+type fakeMessagingService struct {
+	messaging.Service
+	err       error
+	validated int
+}
+
+func (s *fakeMessagingService) ValidateMessage(target int, message json.RawMessage) error {
+	s.validated++
+	return s.err
+}
+
+func TestValidateMessage(t *testing.T) {
+	invalidPoll := core.NewInvalidValueError("options")
+	tests := []struct {
+		message    string
+		messageErr error
+		expected   error
+		validated  bool
+	}{
+		{`{"type": 0, "text": "hello"}`, nil, nil, true},
+		{`{"type": 1, "code": "x"}`, nil, nil, true},
+		{`{"type": 3, "question": "?"}`, invalidPoll, invalidPoll, true},
+		{`{"type": 4, "patch": ""}`, core.ErrRessourceDoesNotExist, core.ErrRessourceDoesNotExist, true},
+		{`{"type": 2}`, nil, core.ErrInvalidMessageType, false},
+		{`{"text": "hello"}`, nil, core.NewJSONFormatError("type missing"), false},
+	}
+
+	for _, test := range tests {
+		messagingService := &fakeMessagingService{err: test.messageErr}
+		s := &service{messagingService: messagingService}
+
+		err := s.validateMessage(1, json.RawMessage(test.message))
+		if err != test.expected {
+			t.Errorf("validateMessage(%s) = %v, want %v", test.message, err, test.expected)
+		}
+
+		if (messagingService.validated > 0) != test.validated {
+			t.Errorf("validateMessage(%s) validated %d times", test.message, messagingService.validated)
+		}
+	}
+
+	s := &service{messagingService: &fakeMessagingService{}}
+	if _, ok := s.validateMessage(1, json.RawMessage(`{"type":`)).(core.ApiError); !ok {
+		t.Error("invalid json was accepted")
+	}
+}
+
+func TestWithSentDate(t *testing.T) {
+	sentDate := time.Date(2020, 5, 17, 12, 30, 0, 0, time.UTC)
+	message, err := withSentDate(json.RawMessage(`{"type": 0, "text": "hello", "sentdate": "2000-01-01T00:00:00Z"}`), sentDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fields struct {
+		Type     int       `json:"type"`
+		Text     string    `json:"text"`
+		SentDate time.Time `json:"sentdate"`
+	}
+	err = json.Unmarshal(message, &fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fields.Type != 0 || fields.Text != "hello" || !fields.SentDate.Equal(sentDate) {
+		t.Errorf("unexpected message %s", message)
+	}
+
+	_, err = withSentDate(json.RawMessage(`[1, 2]`), sentDate)
+	if err == nil {
+		t.Error("a message which is not an object was accepted")
+	}
+}
+
+func TestIsTemporary(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{core.NewDataBaseError(errors.New("connection refused")), true},
+		{errors.New("connection reset"), true},
+		{core.ErrAccessDenied, false},
+		{core.NewInvalidValueError("options"), false},
+	}
+
+	for _, test := range tests {
+		if got := isTemporary(test.err); got != test.expected {
+			t.Errorf("isTemporary(%v) = %v, want %v", test.err, got, test.expected)
+		}
+	}
+}
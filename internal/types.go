@@ -29,6 +29,12 @@ const (
 	// MediaMessageType represents a type of message that can hold various types of files.
 	MediaMessageType MessageType = 2
 
+	// PollMessageType represents the type of a poll.
+	PollMessageType MessageType = 3
+
+	// DiffMessageType represents the type of a message holding a patch.
+	DiffMessageType MessageType = 4
+
 	// UndefinedMesssageType represents a message of any kind.
 	UndefinedMesssageType MessageType = -1
 )
@@ -47,12 +53,26 @@ type Sequencable interface {
 	GetSequenceNumber() int
 }
 
+// Threadable is implemented by every message that can be posted as a reply.
+type Threadable interface {
+	GetParentID() int
+}
+
+// Mentionable is implemented by every message that can mention members of a conversation.
+type Mentionable interface {
+	GetMentions() []int
+}
+
 // Conversation
 type Conversation struct {
 	Title           string `json:"title"`
 	ID              int    `json:"id"`
 	Repourl         string `json:"repoUrl"`
 	NUnreadMessages int    `json:"nUnreadMessages" pg:"unreadmessagescount"`
+
+	// RetentionDays is the number of days after which messages are deleted.
+	// nil stands for the server default and 0 keeps messages forever.
+	RetentionDays *int `json:"retentionDays" pg:"retentiondays"`
 }
 
 // MailingService provides an simple interface to send emails.
@@ -69,26 +89,170 @@ type Invitation struct {
 
 // Message is the abstract base type of any message.
 type Message struct {
-	ID             int         `json:"id"`
-	Type           MessageType `json:"type"`
-	Sentdate       time.Time   `json:"sentdate"`
-	ProvisionaryID int         `json:"provisionaryId,omitempty"`
-	Author         string      `json:"author"`
+	ID             int             `json:"id"`
+	Type           MessageType     `json:"type"`
+	Sentdate       time.Time       `json:"sentdate"`
+	ProvisionaryID int             `json:"provisionaryId,omitempty"`
+	Author         string          `json:"author"`
+	ParentID       int             `json:"parentId,omitempty" pg:"parentid"`
+	ReplyCount     int             `json:"replyCount" pg:"replycount"`
+	Reactions      []ReactionCount `json:"reactions" pg:"reactions"`
+	IsEdited       bool            `json:"isEdited" pg:"isedited"`
+	IsPinned       bool            `json:"isPinned" pg:"ispinned"`
+	Mentions       []int           `json:"mentions,omitempty" pg:"-"`
+	AuthorID       int             `json:"-" pg:"userid"`
+	Origin         *MessageOrigin  `json:"forwardedFrom,omitempty" pg:"origin"`
+	QuotedID       int             `json:"quotedId,omitempty" pg:"quotedid"`
+	Quote          *MessagePreview `json:"quote,omitempty" pg:"quote"`
+}
+
+// MessageOrigin describes the message a forwarded message has been copied from.
+type MessageOrigin struct {
+	MessageID      int       `json:"messageId"`
+	ConversationID int       `json:"conversationId"`
+	Author         string    `json:"author"`
+	Sentdate       time.Time `json:"sentdate"`
+}
+
+// MessagePreview is a short summary of a message that is quoted by another one.
+type MessagePreview struct {
+	MessageID int         `json:"messageId"`
+	Type      MessageType `json:"type"`
+	Author    string      `json:"author"`
+	Sentdate  time.Time   `json:"sentdate"`
+	Excerpt   string      `json:"excerpt"`
+}
+
+// MessageEdit is a prior revision of the text of a message.
+type MessageEdit struct {
+	ID       int       `json:"id"`
+	Editor   string    `json:"editor"`
+	EditDate time.Time `json:"editDate" pg:"editdate"`
+	Text     string    `json:"text"`
+}
+
+// MessageTombstone is sent to the members of a conversation in place of a deleted message.
+// Deleting the root of a thread deletes all of its replies as well. Their ids are listed
+// in Replies and no separate tombstones are sent for them.
+type MessageTombstone struct {
+	MessageID int   `json:"messageId"`
+	ParentID  int   `json:"parentId,omitempty"`
+	Replies   []int `json:"replies,omitempty"`
+}
+
+// Draft is an unsent message of a user that is shared between all devices of the user.
+type Draft struct {
+	ConversationID int       `json:"conversationId" pg:"conversationid"`
+	ParentID       int       `json:"parentId,omitempty" pg:"parentid"`
+	Text           string    `json:"text"`
+	UpdateDate     time.Time `json:"updateDate" pg:"updatedate"`
+}
+
+// Bookmark is a message a user has saved for later together with a private note and tags.
+type Bookmark struct {
+	ID             int             `json:"id"`
+	ConversationID int             `json:"conversationId" pg:"conversationid"`
+	MessageID      int             `json:"messageId" pg:"messageid"`
+	Note           string          `json:"note"`
+	Tags           []string        `json:"tags" pg:",array"`
+	CreationDate   time.Time       `json:"creationDate" pg:"creationdate"`
+	Message        *MessagePreview `json:"message,omitempty" pg:"preview"`
+}
+
+// ReadReceipt announces that a member has read every message of a conversation up to MessageID.
+type ReadReceipt struct {
+	UserID         int       `json:"userId"`
+	ConversationID int       `json:"conversationId"`
+	MessageID      int       `json:"messageId"`
+	ReadDate       time.Time `json:"readDate"`
+}
+
+// MessageReader is a member that has read a message.
+// ReadDate is missing for messages that were read before read receipts were recorded.
+type MessageReader struct {
+	UserID   int        `json:"userId" pg:"userid"`
+	Name     string     `json:"name"`
+	ReadDate *time.Time `json:"readDate,omitempty" pg:"readdate"`
+}
+
+// ReactionCount aggregates all reactions with the same emoji to a message.
+// Users contains the ids of the reacting users in the order they reacted.
+type ReactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+	Users []int  `json:"users" pg:",array"`
+}
+
+// Thread is a root message together with all of its replies in chronological order.
+type Thread struct {
+	Root    interface{}   `json:"root"`
+	Replies []interface{} `json:"replies"`
+}
+
+// ThreadSummary is sent to the members of a conversation whenever a thread changes.
+type ThreadSummary struct {
+	MessageID  int `json:"messageId"`
+	ReplyCount int `json:"replyCount"`
 }
 
 // TextMessage is derived from Message.
 type TextMessage struct {
 	Message
 	Text string `json:"text"`
+
+	// HTML and PlainText are rendered from the Markdown source in Text.
+	HTML      string `json:"html" pg:"html"`
+	PlainText string `json:"plainText" pg:"plaintext"`
 }
 
-// CodeMessage is derived from Message.
+// CodeMessage is derived from Message. Code and Language belong to the main file,
+// which is named FileName. Snippets spanning several files carry the others in Files.
 type CodeMessage struct {
 	Message
-	Code     string `json:"code"`
+	Code     string     `json:"code"`
+	Language string     `json:"language"`
+	Title    string     `json:"title"`
+	FileName string     `json:"fileName" pg:"filename"`
+	Files    []CodeFile `json:"files"`
+	LockedBy int        `json:"lockedBy" pg:"lockedby"`
+	Revision int        `json:"revision"`
+
+	// DetectedLanguage is set when a message is sent without a language.
+	DetectedLanguage *LanguageGuess `json:"detectedLanguage,omitempty" pg:"-"`
+}
+
+// CodeFile is a further file of a code message.
+type CodeFile struct {
+	Name     string `json:"name"`
 	Language string `json:"language"`
-	Title    string `json:"title"`
-	LockedBy int    `json:"lockedBy" pg:"lockedby"`
+	Code     string `json:"code"`
+}
+
+// LanguageGuess is the result of the language detection. Confidence ranges from 0 to 1.
+type LanguageGuess struct {
+	Language   string  `json:"language"`
+	Confidence float64 `json:"confidence"`
+}
+
+// CodeLock describes which user holds the live session of a code message. LockDate is
+// the time of the last activity within the live session.
+type CodeLock struct {
+	MessageID      int       `json:"messageId" pg:"id"`
+	ConversationID int       `json:"conversationId" pg:"conversationid"`
+	LockedBy       int       `json:"lockedBy" pg:"lockedby"`
+	LockDate       time.Time `json:"lockDate" pg:"lockdate"`
+}
+
+// CodeRevision is a state of a code message. The first revision is created together with the message.
+type CodeRevision struct {
+	Revision     int        `json:"revision"`
+	Author       string     `json:"author"`
+	RevisionDate time.Time  `json:"revisionDate" pg:"revisiondate"`
+	Title        string     `json:"title"`
+	Language     string     `json:"language"`
+	FileName     string     `json:"fileName" pg:"filename"`
+	Code         string     `json:"code,omitempty"`
+	Files        []CodeFile `json:"files,omitempty"`
 }
 
 // MediaObject represents a file.
@@ -97,6 +261,10 @@ type MediaObject struct {
 	MIMEType string          `json:"mimeType" pg:"filetype"`
 	Name     string          `json:"name"`
 	Meta     json.RawMessage `json:"meta"`
+
+	// FileID is the id of the media object whose file is stored on disk.
+	// Forwarded media objects share the file of the original one.
+	FileID int `json:"-" pg:"fileid"`
 }
 
 // MediaMessage is derived from Message.
@@ -106,6 +274,77 @@ type MediaMessage struct {
 	Files []MediaObject `json:"files"`
 }
 
+// PollMessage is derived from Message. A poll accepts votes until its CloseDate.
+type PollMessage struct {
+	Message
+	Question         string       `json:"question"`
+	Options          []PollOption `json:"options"`
+	IsMultipleChoice bool         `json:"isMultipleChoice" pg:"ismultiplechoice"`
+	IsAnonymous      bool         `json:"isAnonymous" pg:"isanonymous"`
+	CloseDate        *time.Time   `json:"closeDate,omitempty" pg:"closedate"`
+	IsClosed         bool         `json:"isClosed" pg:"isclosed"`
+}
+
+// DiffMessage is derived from Message. It holds a unified diff or the output of git format-patch.
+// Patch is only set when the message is sent; it can be downloaded separately afterwards.
+type DiffMessage struct {
+	Message
+	Title     string     `json:"title"`
+	Patch     string     `json:"patch,omitempty" pg:"-"`
+	Files     []DiffFile `json:"files"`
+	Additions int        `json:"additions"`
+	Deletions int        `json:"deletions"`
+}
+
+// DiffFile describes the changes to a single file within a patch.
+// Hunks are only included in the structured representation of a patch.
+type DiffFile struct {
+	OldPath   string     `json:"oldPath"`
+	NewPath   string     `json:"newPath"`
+	Status    string     `json:"status"`
+	IsBinary  bool       `json:"isBinary"`
+	Additions int        `json:"additions"`
+	Deletions int        `json:"deletions"`
+	Hunks     []DiffHunk `json:"hunks,omitempty"`
+}
+
+// The possible values of DiffFile.Status.
+const (
+	DiffFileAdded    = "added"
+	DiffFileDeleted  = "deleted"
+	DiffFileModified = "modified"
+	DiffFileRenamed  = "renamed"
+)
+
+// DiffHunk is a contiguous block of changes.
+type DiffHunk struct {
+	OldStart int        `json:"oldStart"`
+	OldLines int        `json:"oldLines"`
+	NewStart int        `json:"newStart"`
+	NewLines int        `json:"newLines"`
+	Section  string     `json:"section,omitempty"`
+	Lines    []DiffLine `json:"lines"`
+}
+
+// DiffLine is a single line of a hunk. Type is one of "context", "add" and "delete".
+// The line numbers are 0 if the line does not exist on the respective side.
+type DiffLine struct {
+	Type           string `json:"type"`
+	Content        string `json:"content"`
+	OldNumber      int    `json:"oldNumber,omitempty"`
+	NewNumber      int    `json:"newNumber,omitempty"`
+	NoNewlineAtEOF bool   `json:"noNewlineAtEOF,omitempty"`
+}
+
+// PollOption is a possible answer of a poll together with its votes.
+// Voters is always empty for anonymous polls.
+type PollOption struct {
+	ID     int    `json:"id"`
+	Text   string `json:"text"`
+	Votes  int    `json:"votes"`
+	Voters []int  `json:"voters"`
+}
+
 // GetDate makes Message implement the Cronological interface.
 func (m Message) GetDate() time.Time {
 	return m.Sentdate
@@ -121,6 +360,21 @@ func (m TextMessage) GetDate() time.Time {
 	return m.Sentdate
 }
 
+// GetParentID makes Message and all derived types implement the Threadable interface.
+func (m Message) GetParentID() int {
+	return m.ParentID
+}
+
+// GetMentions makes Message and all derived types implement the Mentionable interface.
+func (m Message) GetMentions() []int {
+	return m.Mentions
+}
+
+// GetParentID makes MessageTombstone implement the Threadable interface.
+func (t MessageTombstone) GetParentID() int {
+	return t.ParentID
+}
+
 // GetSequenceNumber makes Message implement the Sequencable interface.
 func (m Message) GetSequenceNumber() int {
 	return m.ID
@@ -141,6 +395,174 @@ func (m MediaMessage) GetSequenceNumber() int {
 	return m.ID
 }
 
+// ExportState is the state of an ExportJob.
+type ExportState string
+
+// The possible values of ExportJob.State.
+const (
+	ExportPending  ExportState = "pending"
+	ExportRunning  ExportState = "running"
+	ExportFinished ExportState = "finished"
+	ExportFailed   ExportState = "failed"
+)
+
+// ExportJob is the asynchronous export of a conversation into a ZIP archive.
+// DownloadURL is only set after the archive has been written.
+type ExportJob struct {
+	ID             int         `json:"id"`
+	ConversationID int         `json:"conversationId"`
+	UserID         int         `json:"-"`
+	State          ExportState `json:"state"`
+	CreationDate   time.Time   `json:"creationDate"`
+	CompletionDate *time.Time  `json:"completionDate,omitempty"`
+	Size           int64       `json:"size,omitempty"`
+	DownloadURL    string      `json:"downloadUrl,omitempty"`
+}
+
+// ImportResult summarizes the import of an export of another chat system.
+// CreatedUsers contains the names of the placeholder accounts that have been
+// created for users without an account on this server. Error is set if the
+// import has been aborted, the conversations imported up to then remain.
+type ImportResult struct {
+	Conversations      []Conversation `json:"conversations"`
+	CreatedUsers       []string       `json:"createdUsers"`
+	MappedUsers        int            `json:"mappedUsers"`
+	Messages           int            `json:"messages"`
+	Attachments        int            `json:"attachments"`
+	SkippedAttachments int            `json:"skippedAttachments"`
+	Error              string         `json:"error,omitempty"`
+}
+
+// ExecutionResult is the outcome of the last execution of a code message.
+// Duration is measured in milliseconds.
+type ExecutionResult struct {
+	MessageID     int       `json:"messageId" pg:"messageid"`
+	Revision      int       `json:"revision"`
+	Language      string    `json:"language"`
+	ExitCode      int       `json:"exitCode" pg:"exitcode"`
+	Stdout        string    `json:"stdout"`
+	Stderr        string    `json:"stderr"`
+	TimedOut      bool      `json:"timedOut" pg:"timedout"`
+	Truncated     bool      `json:"truncated"`
+	Duration      int       `json:"duration"`
+	ExecutionDate time.Time `json:"executionDate" pg:"executiondate"`
+	ExecutedBy    string    `json:"executedBy" pg:"executedby"`
+	UserID        int       `json:"-" pg:"userid"`
+}
+
+// The possible values of ScheduledItem.Kind.
+const (
+	ScheduledMessage  = "message"
+	ScheduledReminder = "reminder"
+)
+
+// ScheduledItem is either a message which is sent on behalf of its owner at DueDate or
+// a personal reminder to an existing message. Message holds the payload of a scheduled
+// message in the same format as it is sent over the websocket. Error is only set when
+// the owner is notified that the message could not be sent.
+type ScheduledItem struct {
+	ID             int             `json:"id"`
+	Kind           string          `json:"kind"`
+	UserID         int             `json:"-" pg:"userid"`
+	ConversationID int             `json:"conversationId" pg:"conversationid"`
+	MessageID      int             `json:"messageId,omitempty" pg:"messageid"`
+	DueDate        time.Time       `json:"dueDate" pg:"duedate"`
+	CreationDate   time.Time       `json:"creationDate" pg:"creationdate"`
+	Message        json.RawMessage `json:"message,omitempty" pg:"payload"`
+	Note           string          `json:"note,omitempty"`
+	Attempts       int             `json:"-"`
+	Error          *ApiError       `json:"error,omitempty" pg:"-"`
+}
+
+// Pin marks a message as important for a conversation.
+type Pin struct {
+	MessageID int         `json:"messageId" pg:"messageid"`
+	PinnedBy  string      `json:"pinnedBy" pg:"pinnedby"`
+	PinDate   time.Time   `json:"pinDate" pg:"pindate"`
+	Message   interface{} `json:"message" pg:"-"`
+}
+
+// Mention is a message in which a user was mentioned.
+type Mention struct {
+	MessageID      int         `json:"messageId" pg:"messageid"`
+	ConversationID int         `json:"conversationId" pg:"conversationid"`
+	Type           MessageType `json:"type"`
+	Author         string      `json:"author"`
+	Sentdate       time.Time   `json:"sentdate"`
+	Preview        string      `json:"preview"`
+	HasRead        bool        `json:"hasRead" pg:"hasread"`
+}
+
+// HistoryDirection determines which messages around the anchor of a HistoryQuery are loaded.
+type HistoryDirection int
+
+const (
+	// HistoryBefore loads the messages preceding the anchor.
+	HistoryBefore HistoryDirection = iota
+	// HistoryAfter loads the messages following the anchor.
+	HistoryAfter
+	// HistoryAround loads a window centred on the anchor including the anchor itself.
+	HistoryAround
+)
+
+// HistoryQuery selects a page of the message history of a conversation. If Cursor is
+// set, Direction and AnchorID are taken from it.
+type HistoryQuery struct {
+	Direction HistoryDirection
+	AnchorID  int
+	Cursor    string
+	Limit     int
+	Type      MessageType
+}
+
+// HistoryPage is a page of the message history in chronological order. The cursors
+// continue the history in the respective direction.
+type HistoryPage struct {
+	Messages      []interface{} `json:"messages"`
+	HasMoreBefore bool          `json:"hasMoreBefore"`
+	HasMoreAfter  bool          `json:"hasMoreAfter"`
+	BeforeCursor  string        `json:"beforeCursor,omitempty"`
+	AfterCursor   string        `json:"afterCursor,omitempty"`
+}
+
+// MessageRange restricts a query to the messages with ids between AfterID and BeforeID,
+// both exclusive. If Forward is set, the oldest messages of the range are returned first,
+// otherwise the newest.
+type MessageRange struct {
+	AfterID  int
+	BeforeID int
+	Limit    int
+	Forward  bool
+}
+
+// SearchQuery describes a full text search over all messages of the conversations
+// a user is a member of. Zero values disable the respective filter.
+type SearchQuery struct {
+	Term           string
+	ConversationID int
+	Author         string
+	Type           MessageType
+	Language       string
+	From           time.Time
+	To             time.Time
+	Limit          int
+	Offset         int
+}
+
+// SearchResult is a message that matches a SearchQuery.
+type SearchResult struct {
+	MessageID      int         `json:"messageId" pg:"id"`
+	ConversationID int         `json:"conversationId" pg:"conversationid"`
+	Type           MessageType `json:"type"`
+	Author         string      `json:"author"`
+	Sentdate       time.Time   `json:"sentdate"`
+	Title          string      `json:"title,omitempty"`
+	Language       string      `json:"language,omitempty"`
+	Snippet        string      `json:"snippet"`
+	Rank           float64     `json:"rank"`
+	Content        string      `json:"-"`
+}
+
 // User contains the information of actual users that can sign in to the app.
 type User struct {
 	ID                  int       `pg:"id" json:"id"`